@@ -0,0 +1,231 @@
+// Package client 是文件管理服务器 API 的 Go 客户端
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"fileSystem/internal/models"
+)
+
+// FileInfo 文件列表中的一项，与服务器返回的结构一致
+type FileInfo = models.FileInfo
+
+// Checksums 服务器计算的文件摘要（十六进制）
+type Checksums = models.Checksums
+
+// FileList 分页列出目录时的一页结果
+type FileList = models.FileList
+
+// ManifestEntry 目录清单中的一项
+type ManifestEntry = models.ManifestEntry
+
+// ProgressFunc 传输进度回调，total 未知时为 -1
+type ProgressFunc func(transferred, total int64)
+
+// Client 文件管理服务器客户端
+type Client struct {
+	baseURL string
+
+	// HTTPClient 发送请求使用的 HTTP 客户端，默认为 http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// New 创建客户端，baseURL 为服务器地址加根路由，如 http://localhost:8080 或 http://host/files
+func New(baseURL string) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// List 在一次请求中列出目录的全部内容，dir 为空时列出根目录（配置了挂载点时为挂载点列表）。
+// 目录较大时使用 ListPage 分页读取
+func (c *Client) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	query := url.Values{}
+	if dir != "" {
+		query.Set("path", cleanPath(dir))
+	}
+	var files []FileInfo
+	if err := c.call(ctx, http.MethodGet, "/api/files", query, nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ListOptions 分页列出目录的参数，零值表示使用服务器的默认值
+type ListOptions struct {
+	Sort     string   // 排序字段：name（默认）、size、time
+	Desc     bool     // 降序排列
+	Mixed    bool     // 目录与文件混合排序，默认目录在前
+	Name     string   // 只返回名称包含该字符串的项（不区分大小写）
+	Ext      []string // 只返回这些扩展名的文件
+	Type     string   // 只返回 dir 或 file
+	DirStats bool     // 目录的大小为递归总大小
+	Limit    int      // 每页条数，0 时为 DefaultPageSize，服务器最多返回 1000 条
+	Cursor   string   // 上一页返回的 NextCursor，为空时从第一页开始
+}
+
+// DefaultPageSize ListPage 未指定 Limit 时的每页条数
+const DefaultPageSize = 200
+
+// ListPage 列出目录的一页，返回结果的 NextCursor 为空时表示没有下一页。
+// 翻页时除 Cursor 外的参数需保持不变
+func (c *Client) ListPage(ctx context.Context, dir string, opts *ListOptions) (*FileList, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	query := url.Values{}
+	if dir != "" {
+		query.Set("path", cleanPath(dir))
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Desc {
+		query.Set("order", "desc")
+	}
+	if opts.Mixed {
+		query.Set("dirsFirst", "false")
+	}
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	if len(opts.Ext) > 0 {
+		query.Set("ext", strings.Join(opts.Ext, ","))
+	}
+	if opts.Type != "" {
+		query.Set("type", opts.Type)
+	}
+	if opts.DirStats {
+		query.Set("dirStats", "true")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	query.Set("limit", strconv.Itoa(limit))
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+
+	var list FileList
+	if err := c.call(ctx, http.MethodGet, "/api/files", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Manifest 递归列出目录下的所有文件和子目录，文件带有 SHA-256
+func (c *Client) Manifest(ctx context.Context, dir string) ([]ManifestEntry, error) {
+	query := url.Values{}
+	query.Set("path", cleanPath(dir))
+	var entries []ManifestEntry
+	if err := c.call(ctx, http.MethodGet, "/api/manifest", query, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Delete 删除文件或目录（目录连同其内容一起删除）
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.call(ctx, http.MethodDelete, "/api/delete/"+escapePath(path), nil, nil, nil)
+}
+
+// Mkdir 创建目录（含中间目录），目录已存在时不返回错误
+func (c *Client) Mkdir(ctx context.Context, path string) error {
+	return c.call(ctx, http.MethodPost, "/api/mkdir", nil, map[string]string{"path": cleanPath(path)}, nil)
+}
+
+// Move 在同一存储位置内移动或重命名文件/目录，目标已存在时返回 ErrConflict
+func (c *Client) Move(ctx context.Context, from, to string) error {
+	body := map[string]string{"from": cleanPath(from), "to": cleanPath(to)}
+	return c.call(ctx, http.MethodPost, "/api/move", nil, body, nil)
+}
+
+// call 发送 JSON 请求并将响应中的 data 解析到 out
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, out)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return http.NewRequestWithContext(ctx, method, u, body)
+}
+
+// do 发送请求，非 2xx 响应转换为 *Error
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+	return resp, nil
+}
+
+// decodeResponse 解析 models.Response 并将 data 字段解析到 out
+func decodeResponse(resp *http.Response, out interface{}) error {
+	var result struct {
+		Success bool            `json:"success"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("无法解析服务器响应: %w", err)
+	}
+	if !result.Success {
+		return &Error{StatusCode: resp.StatusCode, Message: result.Message}
+	}
+	if out == nil || len(result.Data) == 0 || string(result.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// cleanPath 规范化远程路径：统一使用 / 并去掉首尾的 /
+func cleanPath(p string) string {
+	return strings.Trim(strings.ReplaceAll(p, "\\", "/"), "/")
+}
+
+// escapePath 逐段转义远程路径，用于拼接到 URL 路径中
+func escapePath(p string) string {
+	segments := strings.Split(cleanPath(p), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
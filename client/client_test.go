@@ -0,0 +1,233 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"fileSystem/internal/config"
+	"fileSystem/internal/handlers"
+)
+
+// newTestServer 使用临时存储目录启动服务器，返回连接到它的客户端和存储目录
+func newTestServer(t *testing.T) (*Client, string) {
+	t.Helper()
+	dir, mounts, cfg := config.UploadDir, config.Mounts, config.Cfg
+	t.Cleanup(func() {
+		config.UploadDir, config.Mounts, config.Cfg = dir, mounts, cfg
+	})
+	config.UploadDir = t.TempDir()
+	config.Mounts = nil
+	config.Cfg = config.Config{}
+
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/files", handlers.ListFiles).Methods("GET")
+	api.HandleFunc("/manifest", handlers.Manifest).Methods("GET")
+	api.HandleFunc("/upload", handlers.UploadFile).Methods("POST")
+	api.HandleFunc("/download/{filename:.*}", handlers.DownloadFile).Methods("GET")
+	api.HandleFunc("/delete/{filename:.*}", handlers.DeleteFile).Methods("DELETE")
+	api.HandleFunc("/mkdir", handlers.Mkdir).Methods("POST")
+	api.HandleFunc("/move", handlers.MoveFile).Methods("POST")
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return New(srv.URL + "/"), config.UploadDir
+}
+
+// names 文件列表中的名称
+func names(files []FileInfo) []string {
+	list := []string{}
+	for _, f := range files {
+		list = append(list, f.Name)
+	}
+	return list
+}
+
+func TestClientFileOperations(t *testing.T) {
+	c, root := newTestServer(t)
+	ctx := context.Background()
+
+	if err := c.Mkdir(ctx, "docs/sub"); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := c.Upload(ctx, "docs", "a.txt", strings.NewReader("hello world"), 11, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sums.SHA256 != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+		t.Errorf("SHA256 = %s", sums.SHA256)
+	}
+	_, err = c.Upload(ctx, "docs", "b.txt", strings.NewReader("x"), 1, &UploadOptions{SHA256: strings.Repeat("0", 64)})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Upload() with wrong SHA256 error = %v, want %v", err, ErrChecksumMismatch)
+	}
+
+	files, err := c.List(ctx, "/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(files); !reflect.DeepEqual(got, []string{"sub", "a.txt"}) {
+		t.Errorf("List() = %v", got)
+	}
+
+	var buf bytes.Buffer
+	if n, err := c.Download(ctx, "docs/a.txt", &buf, nil); err != nil || n != 11 || buf.String() != "hello world" {
+		t.Errorf("Download() = %d, %q, %v", n, buf.String(), err)
+	}
+	local := filepath.Join(t.TempDir(), "a.txt")
+	if err := c.DownloadFile(ctx, "docs/a.txt", local, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != "hello world" {
+		t.Errorf("downloaded file = %q", data)
+	}
+
+	if err := c.Move(ctx, "docs/a.txt", "docs/sub/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "docs", "sub", "a.txt")); err != nil {
+		t.Errorf("moved file missing: %v", err)
+	}
+	if err := c.Mkdir(ctx, "docs/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Move(ctx, "docs/sub/a.txt", "docs/a.txt"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Move() onto existing error = %v, want %v", err, ErrConflict)
+	}
+
+	if err := c.Delete(ctx, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.List(ctx, "docs"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("List() of deleted directory error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.Download(ctx, "docs/a.txt", &buf, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Download() of deleted file error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestClientListPage(t *testing.T) {
+	c, root := newTestServer(t)
+	ctx := context.Background()
+	var want []string
+	for i := 0; i < 7; i++ {
+		name := fmt.Sprintf("f%d.txt", i)
+		if err := os.WriteFile(filepath.Join(root, name), []byte(strings.Repeat("x", 7-i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		want = append([]string{name}, want...)
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ListOptions{Sort: "size", Type: "file", Limit: 3}
+	var got []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("too many pages")
+		}
+		list, err := c.ListPage(ctx, "", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list.Total != 7 {
+			t.Errorf("Total = %d, want 7", list.Total)
+		}
+		got = append(got, names(list.Files)...)
+		if list.NextCursor == "" {
+			break
+		}
+		opts.Cursor = list.NextCursor
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+
+	// 排序参数变化后旧游标无效
+	opts.Desc = true
+	if _, err := c.ListPage(ctx, "", opts); !errors.Is(err, ErrBadRequest) {
+		t.Errorf("ListPage() with stale cursor error = %v, want %v", err, ErrBadRequest)
+	}
+
+	list, err := c.ListPage(ctx, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 8 || len(list.Files) != 8 || list.NextCursor != "" {
+		t.Errorf("default page = total %d, %d files, cursor %q", list.Total, len(list.Files), list.NextCursor)
+	}
+}
+
+func TestClientListPageQuery(t *testing.T) {
+	var got url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		fmt.Fprint(w, `{"success":true,"data":{"files":[],"total":0}}`)
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	tests := []struct {
+		opts *ListOptions
+		want url.Values
+	}{
+		{nil, url.Values{"path": {"a/b"}, "limit": {"200"}}},
+		{
+			&ListOptions{Sort: "time", Desc: true, Mixed: true, Name: "rep", Ext: []string{"pdf", "doc"}, Type: "file", DirStats: true, Limit: 50, Cursor: "abc"},
+			url.Values{"path": {"a/b"}, "sort": {"time"}, "order": {"desc"}, "dirsFirst": {"false"}, "name": {"rep"},
+				"ext": {"pdf,doc"}, "type": {"file"}, "dirStats": {"true"}, "limit": {"50"}, "cursor": {"abc"}},
+		},
+	}
+	for _, tt := range tests {
+		if _, err := c.ListPage(context.Background(), `\a\b\`, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("query = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusNotFound, `{"success":false,"message":"文件不存在"}`, ErrNotFound},
+		{http.StatusForbidden, `{"success":false,"message":"只读"}`, ErrForbidden},
+		{http.StatusInsufficientStorage, `{"success":false,"message":"超出存储配额"}`, ErrQuotaExceeded},
+		{http.StatusRequestEntityTooLarge, `{"success":false}`, ErrQuotaExceeded},
+		{http.StatusBadGateway, `<html>bad gateway</html>`, ErrServer},
+		{http.StatusOK, `{"success":false,"message":"失败"}`, nil},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		err := New(srv.URL).Mkdir(context.Background(), "a")
+		srv.Close()
+
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != tt.status {
+			t.Errorf("status %d: error = %v", tt.status, err)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+	}
+}
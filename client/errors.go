@@ -0,0 +1,64 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 按服务器状态码分类的错误，可用 errors.Is 判断
+var (
+	ErrBadRequest       = errors.New("请求无效")
+	ErrNotFound         = errors.New("文件或目录不存在")
+	ErrForbidden        = errors.New("存储位置为只读")
+	ErrConflict         = errors.New("目标已存在")
+	ErrChecksumMismatch = errors.New("校验和不匹配")
+	ErrQuotaExceeded    = errors.New("超出存储配额")
+	ErrServer           = errors.New("服务器内部错误")
+)
+
+// Error 服务器返回的错误响应
+type Error struct {
+	StatusCode int
+	Message    string // 服务器返回的错误信息
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("服务器返回 %d", e.StatusCode)
+	}
+	return fmt.Sprintf("服务器返回 %d: %s", e.StatusCode, e.Message)
+}
+
+// Is 将状态码映射到对应的分类错误
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrBadRequest
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusUnprocessableEntity:
+		return target == ErrChecksumMismatch
+	case http.StatusInsufficientStorage, http.StatusRequestEntityTooLarge:
+		return target == ErrQuotaExceeded
+	}
+	return e.StatusCode >= 500 && target == ErrServer
+}
+
+// newError 从错误响应中读取服务器返回的 message
+func newError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &body) != nil {
+		body.Message = http.StatusText(resp.StatusCode)
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: body.Message}
+}
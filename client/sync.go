@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CompareMode 判断文件是否变化的方式
+type CompareMode string
+
+const (
+	CompareSize    CompareMode = "size"  // 只比较大小
+	CompareModTime CompareMode = "mtime" // 比较大小和修改时间（默认）
+	CompareHash    CompareMode = "hash"  // 比较大小和 SHA-256
+)
+
+// SyncOp 同步操作类型
+type SyncOp string
+
+const (
+	SyncMkdir  SyncOp = "mkdir"
+	SyncUpload SyncOp = "upload"
+	SyncDelete SyncOp = "delete"
+)
+
+// SyncOptions 同步选项
+type SyncOptions struct {
+	Compare CompareMode // 为空时使用 CompareModTime
+	Delete  bool        // 删除远程目录中本地不存在的文件和目录
+}
+
+// SyncAction 同步计划中的一个操作
+type SyncAction struct {
+	Op     SyncOp
+	Path   string // 相对于同步目录的路径，使用 / 分隔，空字符串表示同步目录本身
+	Local  string // 本地路径（上传时）
+	Remote string // 远程完整路径
+	Size   int64  // 上传的文件大小
+	Reason string // 操作原因
+}
+
+func (a SyncAction) String() string {
+	p := a.Path
+	if p == "" {
+		p = "."
+	}
+	if a.Op == SyncUpload {
+		return fmt.Sprintf("%-6s %s (%s)", a.Op, p, a.Reason)
+	}
+	return fmt.Sprintf("%-6s %s", a.Op, p)
+}
+
+// localEntry 本地目录中的一项
+type localEntry struct {
+	path     string // 相对路径，使用 / 分隔
+	fullPath string
+	info     fs.FileInfo
+	isDir    bool
+}
+
+// PlanSync 比较本地目录和远程目录，返回使远程与本地一致所需的操作，不做任何修改
+//
+// 操作按执行顺序排列：先删除与本地类型不同的远程项，再创建目录、上传文件，
+// 启用 Delete 时最后删除远程多余的文件和目录。
+func (c *Client) PlanSync(ctx context.Context, localDir, remoteDir string, opts *SyncOptions) ([]SyncAction, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	switch opts.Compare {
+	case "":
+		opts = &SyncOptions{Compare: CompareModTime, Delete: opts.Delete}
+	case CompareSize, CompareModTime, CompareHash:
+	default:
+		return nil, fmt.Errorf("未知的比较方式 %q", opts.Compare)
+	}
+	remoteDir = cleanPath(remoteDir)
+
+	locals, err := walkLocal(localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := make(map[string]ManifestEntry)
+	manifest, err := c.Manifest(ctx, remoteDir)
+	remoteMissing := errors.Is(err, ErrNotFound)
+	if err != nil && !remoteMissing {
+		return nil, err
+	}
+	for _, e := range manifest {
+		remotes[e.Path] = e
+	}
+
+	var replaced, mkdirs, uploads, extraneous []SyncAction
+	var deletedDirs []string // 将被整体删除的远程目录
+	action := func(op SyncOp, rel string) SyncAction {
+		return SyncAction{Op: op, Path: rel, Remote: path.Join(remoteDir, rel)}
+	}
+	if remoteMissing {
+		mkdirs = append(mkdirs, action(SyncMkdir, ""))
+	}
+
+	for _, l := range locals {
+		r, exists := remotes[l.path]
+		if exists && r.IsDir != l.isDir {
+			replaced = append(replaced, action(SyncDelete, l.path))
+			if r.IsDir {
+				deletedDirs = append(deletedDirs, l.path)
+			}
+			exists = false
+		}
+		if l.isDir {
+			if !exists {
+				mkdirs = append(mkdirs, action(SyncMkdir, l.path))
+			}
+			continue
+		}
+
+		reason := "new"
+		if exists {
+			changed, err := fileChanged(l, r, opts.Compare)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+			reason = "changed"
+		}
+		a := action(SyncUpload, l.path)
+		a.Local = l.fullPath
+		a.Size = l.info.Size()
+		a.Reason = reason
+		uploads = append(uploads, a)
+	}
+
+	if opts.Delete {
+		localSet := make(map[string]bool, len(locals))
+		for _, l := range locals {
+			localSet[l.path] = true
+		}
+		// 清单中父目录在其子项之前；父目录已删除时跳过其子项
+		for _, e := range manifest {
+			if localSet[e.Path] || underAny(e.Path, deletedDirs) {
+				continue
+			}
+			extraneous = append(extraneous, action(SyncDelete, e.Path))
+			if e.IsDir {
+				deletedDirs = append(deletedDirs, e.Path)
+			}
+		}
+	}
+
+	plan := append(replaced, mkdirs...)
+	plan = append(plan, uploads...)
+	return append(plan, extraneous...), nil
+}
+
+// ApplySync 执行同步计划中的一个操作，progress 用于上传进度（可为 nil）
+func (c *Client) ApplySync(ctx context.Context, a SyncAction, progress ProgressFunc) error {
+	switch a.Op {
+	case SyncMkdir:
+		return c.Mkdir(ctx, a.Remote)
+	case SyncDelete:
+		return c.Delete(ctx, a.Remote)
+	case SyncUpload:
+		_, err := c.UploadFile(ctx, a.Local, path.Dir(a.Remote), &UploadOptions{Progress: progress})
+		return err
+	}
+	return fmt.Errorf("未知的同步操作 %q", a.Op)
+}
+
+// Sync 将本地目录同步到远程目录，返回已执行的操作
+func (c *Client) Sync(ctx context.Context, localDir, remoteDir string, opts *SyncOptions) ([]SyncAction, error) {
+	plan, err := c.PlanSync(ctx, localDir, remoteDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range plan {
+		if err := c.ApplySync(ctx, a, nil); err != nil {
+			return plan[:i], fmt.Errorf("%s: %w", a.Path, err)
+		}
+	}
+	return plan, nil
+}
+
+// walkLocal 列出本地目录下的文件和子目录（父目录在子项之前），忽略符号链接等特殊文件
+func walkLocal(dir string) ([]localEntry, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s 不是目录", dir)
+	}
+
+	var entries []localEntry
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, localEntry{path: filepath.ToSlash(rel), fullPath: p, info: info, isDir: d.IsDir()})
+		return nil
+	})
+	return entries, err
+}
+
+// fileChanged 按比较方式判断本地文件与远程文件是否不同
+func fileChanged(l localEntry, r ManifestEntry, mode CompareMode) (bool, error) {
+	if l.info.Size() != r.Size {
+		return true, nil
+	}
+	switch mode {
+	case CompareModTime:
+		// 只比较到秒，兼容不同文件系统的时间精度
+		return l.info.ModTime().Unix() != r.ModTime.Unix(), nil
+	case CompareHash:
+		sum, err := fileSHA256(l.fullPath)
+		if err != nil {
+			return false, err
+		}
+		return sum != r.SHA256, nil
+	}
+	return false, nil
+}
+
+func underAny(p string, dirs []string) bool {
+	for _, d := range dirs {
+		if strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSHA256 计算本地文件的 SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var syncBase = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// writeTree 在 dir 下创建文件，以 / 结尾的路径为目录；offset 为修改时间相对 syncBase 的秒数
+func writeTree(t *testing.T, dir string, files map[string]struct {
+	content string
+	offset  int
+}) {
+	t.Helper()
+	for name, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := syncBase.Add(time.Duration(f.offset) * time.Second)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree 读取目录下所有文件的内容，目录记为 "/"
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if rel == ".filesystem" {
+				return filepath.SkipDir
+			}
+			tree[rel] = "/"
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		tree[rel] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// setupSync 创建本地目录和服务器上的 dst 目录：
+// same 内容和时间都相同，touched 只有时间不同，edited 大小和时间相同而内容不同，grown 大小不同，
+// swap 本地是文件而远程是目录，extra.txt 和 olddir 只在远程存在
+func setupSync(t *testing.T) (c *Client, local, remote string) {
+	t.Helper()
+	c, root := newTestServer(t)
+	local, remote = t.TempDir(), filepath.Join(root, "dst")
+	writeTree(t, local, map[string]struct {
+		content string
+		offset  int
+	}{
+		"same.txt":     {"aaa", 0},
+		"touched.txt":  {"bbb", 3600},
+		"edited.txt":   {"ccc", 0},
+		"grown.txt":    {"eeee", 0},
+		"new.txt":      {"new", 0},
+		"newdir/x.txt": {"x", 0},
+		"swap":         {"file", 0},
+		"empty/":       {},
+	})
+	writeTree(t, remote, map[string]struct {
+		content string
+		offset  int
+	}{
+		"same.txt":       {"aaa", 0},
+		"touched.txt":    {"bbb", 0},
+		"edited.txt":     {"ddd", 0},
+		"grown.txt":      {"ee", 0},
+		"swap/inner.txt": {"inner", 0},
+		"empty/":         {},
+		"extra.txt":      {"extra", 0},
+		"olddir/y.txt":   {"y", 0},
+	})
+	return c, local, remote
+}
+
+// planStrings 同步计划的文字形式，便于比较
+func planStrings(plan []SyncAction) []string {
+	list := []string{}
+	for _, a := range plan {
+		list = append(list, a.String())
+	}
+	return list
+}
+
+func TestPlanSync(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *SyncOptions
+		want []string
+	}{
+		{"default mtime", nil, []string{
+			"delete swap",
+			"mkdir  newdir",
+			"upload grown.txt (changed)",
+			"upload new.txt (new)",
+			"upload newdir/x.txt (new)",
+			"upload swap (new)",
+			"upload touched.txt (changed)",
+		}},
+		{"size", &SyncOptions{Compare: CompareSize}, []string{
+			"delete swap",
+			"mkdir  newdir",
+			"upload grown.txt (changed)",
+			"upload new.txt (new)",
+			"upload newdir/x.txt (new)",
+			"upload swap (new)",
+		}},
+		{"hash", &SyncOptions{Compare: CompareHash}, []string{
+			"delete swap",
+			"mkdir  newdir",
+			"upload edited.txt (changed)",
+			"upload grown.txt (changed)",
+			"upload new.txt (new)",
+			"upload newdir/x.txt (new)",
+			"upload swap (new)",
+		}},
+		// 远程多余的目录整体删除，不再单独删除其中的文件
+		{"mtime with delete", &SyncOptions{Compare: CompareModTime, Delete: true}, []string{
+			"delete swap",
+			"mkdir  newdir",
+			"upload grown.txt (changed)",
+			"upload new.txt (new)",
+			"upload newdir/x.txt (new)",
+			"upload swap (new)",
+			"upload touched.txt (changed)",
+			"delete extra.txt",
+			"delete olddir",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, local, remote := setupSync(t)
+			before := readTree(t, remote)
+
+			plan, err := c.PlanSync(context.Background(), local, "/dst/", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := planStrings(plan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plan =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, a := range plan {
+				if a.Remote != "dst/"+a.Path {
+					t.Errorf("%s: Remote = %q", a, a.Remote)
+				}
+				if a.Op == SyncUpload && (a.Local != filepath.Join(local, filepath.FromSlash(a.Path)) || a.Size == 0) {
+					t.Errorf("%s: Local = %q, Size = %d", a, a.Local, a.Size)
+				}
+			}
+			// 只生成计划（fsctl sync -n）不修改远程目录
+			if after := readTree(t, remote); !reflect.DeepEqual(after, before) {
+				t.Errorf("remote changed by PlanSync: %v, want %v", after, before)
+			}
+		})
+	}
+}
+
+func TestPlanSyncErrors(t *testing.T) {
+	c, local, _ := setupSync(t)
+	ctx := context.Background()
+	if _, err := c.PlanSync(ctx, local, "dst", &SyncOptions{Compare: "crc"}); err == nil {
+		t.Error("PlanSync() with unknown compare mode succeeded")
+	}
+	if _, err := c.PlanSync(ctx, filepath.Join(local, "same.txt"), "dst", nil); err == nil {
+		t.Error("PlanSync() from a file succeeded")
+	}
+	if _, err := c.PlanSync(ctx, filepath.Join(local, "missing"), "dst", nil); !os.IsNotExist(err) {
+		t.Errorf("PlanSync() from a missing directory error = %v", err)
+	}
+	if err := c.ApplySync(ctx, SyncAction{Op: "chmod", Path: "a"}, nil); err == nil {
+		t.Error("ApplySync() with unknown op succeeded")
+	}
+}
+
+func TestSyncApply(t *testing.T) {
+	for _, mode := range []CompareMode{CompareModTime, CompareHash} {
+		t.Run(string(mode), func(t *testing.T) {
+			c, local, remote := setupSync(t)
+			ctx := context.Background()
+			opts := &SyncOptions{Compare: mode, Delete: true}
+
+			done, err := c.Sync(ctx, local, "dst", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(done) == 0 {
+				t.Fatal("nothing synced")
+			}
+			want := readTree(t, local)
+			if mode == CompareModTime {
+				// 大小和修改时间相同的文件按 mtime 比较时视为未变化
+				want["edited.txt"] = "ddd"
+			}
+			if got := readTree(t, remote); !reflect.DeepEqual(got, want) {
+				t.Errorf("remote = %v, want %v", got, want)
+			}
+			// 上传保留本地修改时间，再次同步没有操作
+			plan, err := c.PlanSync(ctx, local, "dst", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan) != 0 {
+				t.Errorf("second plan = %v, want empty", planStrings(plan))
+			}
+			// 按 hash 比较时只有修改时间不同的文件不会上传
+			wantTime := syncBase.Add(time.Hour)
+			if mode == CompareHash {
+				wantTime = syncBase
+			}
+			info, err := os.Stat(filepath.Join(remote, "touched.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(wantTime) {
+				t.Errorf("touched.txt modTime = %v, want %v", info.ModTime(), wantTime)
+			}
+		})
+	}
+}
+
+func TestSyncToMissingDirectory(t *testing.T) {
+	c, local, _ := setupSync(t)
+	ctx := context.Background()
+	plan, err := c.PlanSync(ctx, local, "a/b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) == 0 || plan[0].Op != SyncMkdir || plan[0].Path != "" || plan[0].Remote != "a/b" {
+		t.Fatalf("plan = %v, want mkdir of the target first", planStrings(plan))
+	}
+	var uploads []string
+	for _, a := range plan {
+		if a.Op == SyncDelete {
+			t.Errorf("unexpected %s", a)
+		}
+		if a.Op == SyncUpload {
+			uploads = append(uploads, a.Path)
+		}
+	}
+	sort.Strings(uploads)
+	want := []string{"edited.txt", "grown.txt", "new.txt", "newdir/x.txt", "same.txt", "swap", "touched.txt"}
+	if !reflect.DeepEqual(uploads, want) {
+		t.Errorf("uploads = %v, want %v", uploads, want)
+	}
+
+	if _, err := c.Sync(ctx, local, "a/b", nil); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err := c.PlanSync(ctx, local, "a/b", nil); err != nil || len(plan) != 0 {
+		t.Errorf("plan after sync = %v, %v", planStrings(plan), err)
+	}
+}
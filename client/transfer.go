@@ -0,0 +1,201 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// UploadOptions 上传选项
+type UploadOptions struct {
+	// SHA256 期望的内容 SHA-256（十六进制），服务器校验不匹配时返回 ErrChecksumMismatch
+	SHA256 string
+	// ModTime 服务器上文件的修改时间，为零时使用上传时间（UploadFile 默认使用本地文件的修改时间）
+	ModTime time.Time
+	// Progress 上传进度回调
+	Progress ProgressFunc
+}
+
+// DownloadOptions 下载选项
+type DownloadOptions struct {
+	// Progress 下载进度回调，断点续传时 transferred 包含已下载的部分
+	Progress ProgressFunc
+}
+
+// Upload 将 body 上传为 dir 目录下的 name 文件，size 未知时传 -1
+func (c *Client) Upload(ctx context.Context, dir, name string, body io.Reader, size int64, opts *UploadOptions) (*Checksums, error) {
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
+	// 预先生成 multipart 的头尾，使请求体可以流式发送且长度已知
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if _, err := mw.CreateFormFile("file", name); err != nil {
+		return nil, err
+	}
+	head := append([]byte(nil), buf.Bytes()...)
+	buf.Reset()
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	tail := append([]byte(nil), buf.Bytes()...)
+
+	query := url.Values{}
+	if dir = cleanPath(dir); dir != "" {
+		query.Set("path", dir)
+	}
+	if opts.SHA256 != "" {
+		query.Set("sha256", opts.SHA256)
+	}
+	if !opts.ModTime.IsZero() {
+		query.Set("modTime", opts.ModTime.UTC().Format(time.RFC3339Nano))
+	}
+
+	content := &progressReader{r: body, total: size, progress: opts.Progress}
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/upload", query,
+		io.MultiReader(bytes.NewReader(head), content, bytes.NewReader(tail)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if size >= 0 {
+		req.ContentLength = int64(len(head)) + size + int64(len(tail))
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var sums Checksums
+	if err := decodeResponse(resp, &sums); err != nil {
+		return nil, err
+	}
+	return &sums, nil
+}
+
+// UploadFile 将本地文件上传到 dir 目录，文件名和修改时间保持不变
+func (c *Client) UploadFile(ctx context.Context, localPath, dir string, opts *UploadOptions) (*Checksums, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	o := UploadOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.ModTime.IsZero() {
+		o.ModTime = info.ModTime()
+	}
+	return c.Upload(ctx, dir, filepath.Base(localPath), f, info.Size(), &o)
+}
+
+// Download 下载文件内容写入 w，返回写入的字节数
+func (c *Client) Download(ctx context.Context, remotePath string, w io.Writer, opts *DownloadOptions) (int64, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/download/"+escapePath(remotePath), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, &progressReader{r: resp.Body, total: resp.ContentLength, progress: opts.Progress})
+}
+
+// DownloadFile 下载文件保存到 localPath，支持断点续传
+//
+// 下载过程中内容写入 localPath + ".part"，其修改时间与服务器文件保持一致；
+// 再次调用时通过 Range/If-Range 从断点继续，服务器文件已变化时重新下载。
+// 完成后重命名为 localPath。
+func (c *Client) DownloadFile(ctx context.Context, remotePath, localPath string, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	partPath := localPath + ".part"
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/download/"+escapePath(remotePath), nil, nil)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil && info.Size() > 0 {
+		offset = info.Size()
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 断点已位于文件末尾，说明上次已下载完整
+		return os.Rename(partPath, localPath)
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	default:
+		offset = 0
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	_, err = io.Copy(f, &progressReader{r: resp.Body, done: offset, total: total, progress: opts.Progress})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	// 无论是否完成都同步修改时间，下次续传时作为 If-Range 的依据
+	if !modTime.IsZero() {
+		os.Chtimes(partPath, time.Now(), modTime)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(partPath, localPath)
+}
+
+// progressReader 读取时回调传输进度
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	if p.progress != nil && (n > 0 || err == io.EOF) {
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"fileSystem/client"
+	"fileSystem/internal/utils"
+)
+
+// newFlags 创建子命令的参数解析器
+func newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+// cleanRemote 规范化远程路径
+func cleanRemote(p string) string {
+	return strings.Trim(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+}
+
+func joinRemote(elem ...string) string {
+	return cleanRemote(path.Join(elem...))
+}
+
+// stat 通过列出父目录获取远程文件信息，根目录返回 nil
+func (a *app) stat(ctx context.Context, remote string) (*client.FileInfo, error) {
+	remote = cleanRemote(remote)
+	if remote == "" {
+		return nil, nil
+	}
+	parent := path.Dir(remote)
+	if parent == "." {
+		parent = ""
+	}
+	files, err := a.client.List(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(remote)
+	for i := range files {
+		if files[i].Name == name {
+			return &files[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", remote, client.ErrNotFound)
+}
+
+// walkRemote 递归遍历远程目录，fn 收到相对于 dir 的路径
+func (a *app) walkRemote(ctx context.Context, dir string, fn func(rel string, f *client.FileInfo) error) error {
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		files, err := a.client.List(ctx, joinRemote(dir, rel))
+		if err != nil {
+			return err
+		}
+		for i := range files {
+			f := &files[i]
+			child := path.Join(rel, f.Name)
+			if err := fn(child, f); err != nil {
+				return err
+			}
+			if f.IsDir {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk("")
+}
+
+// cmdList 列出目录
+func cmdList(ctx context.Context, a *app, args []string) error {
+	flags := newFlags("ls")
+	long := flags.Bool("l", false, "显示大小和修改时间")
+	recursive := flags.Bool("r", false, "递归列出子目录")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return errUsage
+	}
+	dir := cleanRemote(flags.Arg(0))
+
+	print := func(name string, f *client.FileInfo) {
+		if f.IsDir {
+			name += "/"
+		}
+		if *long {
+			kind := "-"
+			if f.IsDir {
+				kind = "d"
+			}
+			fmt.Printf("%s %10s  %s  %s\n", kind, utils.FormatSize(f.Size), f.ModTime.Local().Format("2006-01-02 15:04"), name)
+		} else {
+			fmt.Println(name)
+		}
+	}
+
+	info, err := a.stat(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if info != nil && !info.IsDir {
+		print(dir, info)
+		return nil
+	}
+	if *recursive {
+		return a.walkRemote(ctx, dir, func(rel string, f *client.FileInfo) error {
+			print(rel, f)
+			return nil
+		})
+	}
+	files, err := a.client.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for i := range files {
+		print(files[i].Name, &files[i])
+	}
+	return nil
+}
+
+// cmdPut 上传文件或目录
+func cmdPut(ctx context.Context, a *app, args []string) error {
+	flags := newFlags("put")
+	recursive := flags.Bool("r", false, "递归上传目录")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 2 {
+		return errUsage
+	}
+	locals := flags.Args()[:flags.NArg()-1]
+	remoteDir := cleanRemote(flags.Arg(flags.NArg() - 1))
+
+	for _, local := range locals {
+		info, err := os.Stat(local)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if err := a.upload(ctx, local, remoteDir); err != nil {
+				return err
+			}
+			continue
+		}
+		if !*recursive {
+			return fmt.Errorf("%s 是目录，请使用 -r: %w", local, errUsage)
+		}
+		if err := a.uploadDir(ctx, local, joinRemote(remoteDir, filepath.Base(filepath.Clean(local)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upload 上传单个本地文件到远程目录
+func (a *app) upload(ctx context.Context, local, remoteDir string) error {
+	remoteDir = cleanRemote(remoteDir)
+	t := a.newTransfer("上传 " + joinRemote(remoteDir, filepath.Base(local)))
+	_, err := a.client.UploadFile(ctx, local, remoteDir, &client.UploadOptions{Progress: t.callback()})
+	t.finish(err)
+	return err
+}
+
+// uploadDir 递归上传本地目录，保留空目录
+func (a *app) uploadDir(ctx context.Context, localDir, remoteDir string) error {
+	return filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		remote := joinRemote(remoteDir, filepath.ToSlash(rel))
+		if d.IsDir() {
+			return a.client.Mkdir(ctx, remote)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return a.upload(ctx, p, path.Dir(remote))
+	})
+}
+
+// cmdGet 下载文件或目录
+func cmdGet(ctx context.Context, a *app, args []string) error {
+	flags := newFlags("get")
+	recursive := flags.Bool("r", false, "递归下载目录")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		return errUsage
+	}
+	remote := cleanRemote(flags.Arg(0))
+	info, err := a.stat(ctx, remote)
+	if err != nil {
+		return err
+	}
+	isDir := info == nil || info.IsDir
+
+	local := flags.Arg(1)
+	if local == "" {
+		local = "."
+	}
+	if st, err := os.Stat(local); err == nil && st.IsDir() && remote != "" {
+		local = filepath.Join(local, path.Base(remote))
+	}
+
+	if !isDir {
+		return a.download(ctx, remote, local)
+	}
+	if !*recursive {
+		return fmt.Errorf("%s 是目录，请使用 -r: %w", remote, errUsage)
+	}
+	if err := os.MkdirAll(local, 0755); err != nil {
+		return err
+	}
+	return a.walkRemote(ctx, remote, func(rel string, f *client.FileInfo) error {
+		target := filepath.Join(local, filepath.FromSlash(rel))
+		if f.IsDir {
+			return os.MkdirAll(target, 0755)
+		}
+		return a.download(ctx, joinRemote(remote, rel), target)
+	})
+}
+
+// download 下载单个文件，支持断点续传
+func (a *app) download(ctx context.Context, remote, local string) error {
+	t := a.newTransfer("下载 " + remote)
+	err := a.client.DownloadFile(ctx, remote, local, &client.DownloadOptions{Progress: t.callback()})
+	t.finish(err)
+	return err
+}
+
+// cmdRemove 删除文件或目录
+func cmdRemove(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	for _, remote := range args {
+		if cleanRemote(remote) == "" {
+			return fmt.Errorf("不能删除根目录: %w", errUsage)
+		}
+		if err := a.client.Delete(ctx, remote); err != nil {
+			return fmt.Errorf("%s: %w", remote, err)
+		}
+	}
+	return nil
+}
+
+// cmdMkdir 创建目录
+func cmdMkdir(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	for _, remote := range args {
+		if err := a.client.Mkdir(ctx, remote); err != nil {
+			return fmt.Errorf("%s: %w", remote, err)
+		}
+	}
+	return nil
+}
+
+// cmdMove 移动或重命名
+func cmdMove(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	return a.client.Move(ctx, args[0], args[1])
+}
+
+// cmdSync 将本地目录同步到远程目录，只上传新增或变化的文件
+func cmdSync(ctx context.Context, a *app, args []string) error {
+	flags := newFlags("sync")
+	dryRun := flags.Bool("n", false, "只显示将要执行的操作，不做修改")
+	del := flags.Bool("delete", false, "删除远程目录中本地不存在的文件和目录")
+	compare := flags.String("compare", string(client.CompareModTime), "判断文件变化的方式: size、mtime 或 hash")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+	opts := &client.SyncOptions{Compare: client.CompareMode(*compare), Delete: *del}
+	switch opts.Compare {
+	case client.CompareSize, client.CompareModTime, client.CompareHash:
+	default:
+		return fmt.Errorf("未知的比较方式 %q: %w", *compare, errUsage)
+	}
+
+	plan, err := a.client.PlanSync(ctx, flags.Arg(0), flags.Arg(1), opts)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		for _, action := range plan {
+			fmt.Println(action)
+		}
+		return nil
+	}
+
+	var uploaded, deleted int
+	var total int64
+	for _, action := range plan {
+		if action.Op != client.SyncUpload {
+			if err := a.client.ApplySync(ctx, action, nil); err != nil {
+				return fmt.Errorf("%s: %w", action.Remote, err)
+			}
+			if action.Op == client.SyncDelete {
+				deleted++
+				if !a.quiet {
+					fmt.Fprintf(os.Stderr, "删除 %s\n", action.Remote)
+				}
+			}
+			continue
+		}
+		t := a.newTransfer("上传 " + action.Remote)
+		err := a.client.ApplySync(ctx, action, t.callback())
+		t.finish(err)
+		if err != nil {
+			return err
+		}
+		uploaded++
+		total += action.Size
+	}
+	if !a.quiet {
+		fmt.Fprintf(os.Stderr, "同步完成: 上传 %d 个文件（%s），删除 %d 项\n", uploaded, utils.FormatSize(total), deleted)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"fileSystem/client"
+)
+
+// 退出码，便于脚本判断失败原因
+const (
+	exitOK          = 0
+	exitError       = 1 // 其他错误
+	exitUsage       = 2 // 命令行参数错误
+	exitNotFound    = 3 // 文件或目录不存在
+	exitConflict    = 4 // 目标已存在
+	exitForbidden   = 5 // 存储位置只读
+	exitQuota       = 6 // 超出配额
+	exitChecksum    = 7 // 校验和不匹配
+	exitUnreachable = 8 // 无法连接服务器
+	exitInterrupted = 130
+)
+
+// errUsage 命令行参数错误
+var errUsage = errors.New("参数错误")
+
+const usage = `用法: fsctl [-server 地址] [-q] <命令> [参数]
+
+命令:
+  ls [-l] [-r] [远程路径]              列出目录
+  put [-r] <本地路径>... <远程目录>    上传文件（-r 上传目录）
+  get [-r] <远程路径> [本地路径]       下载文件（-r 下载目录），中断后重新执行可断点续传
+  rm <远程路径>...                     删除文件或目录
+  mkdir <远程路径>...                  创建目录（含中间目录）
+  mv <源路径> <目标路径>               移动或重命名
+  sync [-n] [-delete] [-compare 方式] <本地目录> <远程目录>
+                                       同步本地目录到远程，只上传新增或变化的文件
+
+选项:
+`
+
+// command 子命令
+type command func(ctx context.Context, app *app, args []string) error
+
+var commands = map[string]command{
+	"ls":    cmdList,
+	"put":   cmdPut,
+	"get":   cmdGet,
+	"rm":    cmdRemove,
+	"mkdir": cmdMkdir,
+	"mv":    cmdMove,
+	"sync":  cmdSync,
+}
+
+// app 命令共享的客户端和输出选项
+type app struct {
+	client *client.Client
+	quiet  bool
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	server := os.Getenv("FS_SERVER")
+	if server == "" {
+		server = "http://localhost:8080"
+	}
+	flags := flag.NewFlagSet("fsctl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&server, "server", server, "服务器地址（含根路由），默认读取环境变量 FS_SERVER")
+	quiet := flags.Bool("q", false, "不显示传输进度")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	name := flags.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "fsctl: 未知命令 %q\n", name)
+		flags.Usage()
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := cmd(ctx, &app{client: client.New(server), quiet: *quiet}, flags.Args()[1:])
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "fsctl %s: %v\n", name, err)
+	return exitCode(ctx, err)
+}
+
+// exitCode 将错误映射为退出码
+func exitCode(ctx context.Context, err error) int {
+	var netErr net.Error
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, client.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return exitNotFound
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	case errors.Is(err, client.ErrForbidden):
+		return exitForbidden
+	case errors.Is(err, client.ErrQuotaExceeded):
+		return exitQuota
+	case errors.Is(err, client.ErrChecksumMismatch):
+		return exitChecksum
+	case errors.As(err, &netErr):
+		return exitUnreachable
+	default:
+		return exitError
+	}
+}
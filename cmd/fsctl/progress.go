@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"fileSystem/client"
+	"fileSystem/internal/utils"
+)
+
+// progressInterval 进度刷新间隔
+const progressInterval = 200 * time.Millisecond
+
+// transfer 单个文件的传输进度显示，输出到标准错误
+type transfer struct {
+	label    string
+	start    time.Time
+	last     time.Time
+	done     int64
+	total    int64
+	quiet    bool
+	terminal bool
+}
+
+// newTransfer 开始显示一个文件的传输进度
+func (a *app) newTransfer(label string) *transfer {
+	t := &transfer{label: label, start: time.Now(), total: -1, quiet: a.quiet}
+	if info, err := os.Stderr.Stat(); err == nil {
+		t.terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+	return t
+}
+
+// callback 返回传给 client 的进度回调
+func (t *transfer) callback() client.ProgressFunc {
+	return func(done, total int64) {
+		t.done, t.total = done, total
+		if t.quiet || !t.terminal || time.Since(t.last) < progressInterval {
+			return
+		}
+		t.last = time.Now()
+		if total >= 0 {
+			fmt.Fprintf(os.Stderr, "\r\033[K%s  %s / %s  %s", t.label,
+				utils.FormatSize(done), utils.FormatSize(total), utils.FormatSpeed(t.speed()))
+		} else {
+			fmt.Fprintf(os.Stderr, "\r\033[K%s  %s  %s", t.label, utils.FormatSize(done), utils.FormatSpeed(t.speed()))
+		}
+	}
+}
+
+// finish 输出最终结果
+func (t *transfer) finish(err error) {
+	if t.quiet {
+		return
+	}
+	if t.terminal {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s  失败\n", t.label)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s  %s  %s\n", t.label, utils.FormatSize(t.done), utils.FormatSpeed(t.speed()))
+}
+
+func (t *transfer) speed() float64 {
+	elapsed := time.Since(t.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(t.done) / elapsed
+}
@@ -0,0 +1,301 @@
+// Package audit 审计日志：以 JSON Lines 格式追加记录所有文件操作，按大小轮转
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry 一条审计记录
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor"`              // 操作者：SFTP 用户名、S3 访问密钥，未登录时为客户端 IP
+	IP         string    `json:"ip,omitempty"`       // 客户端 IP
+	Via        string    `json:"via"`                // 访问方式：web、share、drop、webdav、sftp、s3
+	Action     string    `json:"action"`             // 操作，如 list、upload、download、delete、move
+	Path       string    `json:"path,omitempty"`     // 操作的路径
+	Target     string    `json:"target,omitempty"`   // 移动、复制的目标路径
+	BytesIn    int64     `json:"bytesIn,omitempty"`  // 接收的字节数
+	BytesOut   int64     `json:"bytesOut,omitempty"` // 发送的字节数
+	Result     string    `json:"result"`             // ok 或 error
+	Status     int       `json:"status,omitempty"`   // HTTP 状态码
+	Error      string    `json:"error,omitempty"`    // 失败原因
+	DurationMs int64     `json:"durationMs"`         // 耗时（毫秒）
+}
+
+// 结果
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+const (
+	currentName = "audit.log"
+	// 轮转后的文件名包含轮转时间，按名称排序即为时间顺序
+	rotatedPrefix = "audit-"
+	rotatedLayout = "20060102-150405.000000"
+)
+
+var (
+	mu       sync.Mutex
+	dir      string
+	file     *os.File
+	size     int64
+	maxSize  int64
+	maxFiles int
+)
+
+// Init 打开日志目录中的当前日志文件，maxSize 为单个文件的大小上限，maxFiles 为保留的轮转文件数。
+// 未调用 Init 时 Record 不做任何事。
+func Init(logDir string, maxSizeBytes int64, keepFiles int) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(logDir, currentName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	dir, file, size = logDir, f, info.Size()
+	maxSize, maxFiles = maxSizeBytes, keepFiles
+	return nil
+}
+
+// Enabled 是否已启用审计日志
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return file != nil
+}
+
+// Record 追加一条记录，写入失败时只打印日志，不影响操作本身
+func Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Result == "" {
+		e.Result = ResultOK
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return
+	}
+	if size > 0 && size+int64(len(data)) > maxSize {
+		if err := rotate(); err != nil {
+			log.Printf("[AUDIT] 警告: 无法轮转审计日志 - %v", err)
+		}
+	}
+	n, err := file.Write(data)
+	size += int64(n)
+	if err != nil {
+		log.Printf("[AUDIT] 警告: 无法写入审计日志 - %v", err)
+	}
+}
+
+// rotate 将当前文件改名为带时间的文件并重新打开，删除超出数量的旧文件，调用方需持有锁
+func rotate() error {
+	current := filepath.Join(dir, currentName)
+	if err := file.Close(); err != nil {
+		return err
+	}
+	rotated := filepath.Join(dir, rotatedPrefix+time.Now().UTC().Format(rotatedLayout)+".log")
+	renameErr := os.Rename(current, rotated)
+
+	f, err := os.OpenFile(current, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		file = nil
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		file = nil
+		return err
+	}
+	file, size = f, info.Size()
+	if renameErr != nil {
+		return renameErr
+	}
+
+	old, err := rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for len(old) > maxFiles {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+	return nil
+}
+
+// rotatedFiles 轮转后的文件，按时间从旧到新排列
+func rotatedFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, rotatedPrefix+"*.log"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Filter 查询条件，为零值的条件不限制
+type Filter struct {
+	From   time.Time // 不早于该时间
+	To     time.Time // 早于该时间
+	Actor  string    // 操作者或客户端 IP
+	Path   string    // 路径本身或其下的文件（匹配 Path 或 Target）
+	Action string    // 操作
+	Limit  int       // 最多返回的条数（最新的），0 表示不限制
+}
+
+func (f *Filter) match(e *Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor && e.IP != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Path != "" && !underPath(e.Path, f.Path) && !underPath(e.Target, f.Path) {
+		return false
+	}
+	return true
+}
+
+func underPath(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// Query 按条件查询记录，按时间从新到旧返回
+func Query(f Filter) ([]Entry, error) {
+	files, err := openLogs(f.From)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, lf := range files {
+			lf.Close()
+		}
+	}()
+
+	f.Path = strings.Trim(f.Path, "/")
+	var entries []Entry
+	for _, lf := range files {
+		err := scan(lf, func(e *Entry) {
+			if f.match(e) {
+				entries = append(entries, *e)
+				// 只保留最新的 Limit 条
+				if f.Limit > 0 && len(entries) > 2*f.Limit {
+					entries = append(entries[:0], entries[len(entries)-f.Limit:]...)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// logFile 查询时打开的日志文件，只读取打开时已写入的部分
+type logFile struct {
+	*os.File
+	size int64
+}
+
+// openLogs 在持有锁时打开需要查询的日志文件（从旧到新），轮转只会改名或删除已打开的文件，
+// 不会使查询漏掉记录；from 之前轮转的文件不打开
+func openLogs(from time.Time) ([]logFile, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil, fmt.Errorf("审计日志未启用")
+	}
+	names, err := rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	names = append(names, filepath.Join(dir, currentName))
+
+	var files []logFile
+	for _, name := range names {
+		// 轮转文件中的记录都早于文件名中的轮转时间
+		if ts, ok := rotatedTime(name); ok && !from.IsZero() && ts.Before(from) {
+			continue
+		}
+		f, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			var info os.FileInfo
+			if info, err = f.Stat(); err == nil {
+				files = append(files, logFile{f, info.Size()})
+				continue
+			}
+			f.Close()
+		}
+		for _, lf := range files {
+			lf.Close()
+		}
+		return nil, err
+	}
+	return files, nil
+}
+
+func rotatedTime(name string) (time.Time, bool) {
+	base := filepath.Base(name)
+	if !strings.HasPrefix(base, rotatedPrefix) {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(rotatedLayout, strings.TrimSuffix(strings.TrimPrefix(base, rotatedPrefix), ".log"))
+	return ts, err == nil
+}
+
+// scan 逐行读取日志文件，跳过无法解析的行
+func scan(f logFile, fn func(e *Entry)) error {
+	scanner := bufio.NewScanner(io.NewSectionReader(f, 0, f.size))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			fn(&e)
+		}
+	}
+	return scanner.Err()
+}
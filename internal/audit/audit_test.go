@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useTempLog 在临时目录中启用审计日志，测试结束后关闭
+func useTempLog(t *testing.T, maxSizeBytes int64, keepFiles int) {
+	t.Helper()
+	if err := Init(t.TempDir(), maxSizeBytes, keepFiles); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		mu.Lock()
+		file.Close()
+		file = nil
+		mu.Unlock()
+	})
+}
+
+func TestQueryFilterAndLimit(t *testing.T) {
+	useTempLog(t, 1<<20, 3)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		Record(Entry{Time: base.Add(time.Duration(i) * time.Minute), Actor: "u", Action: "upload", Path: fmt.Sprintf("docs/%d.txt", i)})
+	}
+	Record(Entry{Time: base, Actor: "other", Action: "delete", Path: "docs2/a.txt"})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"limit", Filter{Path: "/docs/", Limit: 2}, []string{"docs/5.txt", "docs/4.txt"}},
+		{"time range", Filter{From: base.Add(time.Minute), To: base.Add(3 * time.Minute), Action: "upload"}, []string{"docs/2.txt", "docs/1.txt"}},
+		{"actor", Filter{Actor: "other"}, []string{"docs2/a.txt"}},
+	}
+	for _, tt := range tests {
+		entries, err := Query(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Path)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: paths = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueryDuringRotation(t *testing.T) {
+	// 每条记录都会触发轮转，写入的记录数不超过保留的文件数
+	const total = 200
+	useTempLog(t, 1, total)
+	var recorded atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < total; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			Record(Entry{Actor: "u", Action: "upload", Path: fmt.Sprint(i)})
+			recorded.Add(1)
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	for recorded.Load() < total {
+		before := recorded.Load()
+		entries, err := Query(Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(entries)) < before {
+			t.Fatalf("query returned %d entries, want at least %d", len(entries), before)
+		}
+		// 结果从新到旧，序号必须连续：轮转时被改名的文件不能被漏掉
+		for j, e := range entries {
+			if want := fmt.Sprint(len(entries) - 1 - j); e.Path != want {
+				t.Fatalf("entry %d = %s, want %s (entries dropped during rotation)", j, e.Path, want)
+			}
+		}
+	}
+}
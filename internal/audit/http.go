@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type entryKey struct{}
+
+// maxErrorBody 失败时从响应中读取错误信息的最大字节数
+const maxErrorBody = 1024
+
+// Wrap 记录 HTTP 请求的审计日志：访问方式、操作、状态码、收发字节数和耗时。
+// 路径默认取路由中的 filename 或查询参数 path，处理器可通过 SetPath 等函数补充；操作为空时不记录。
+func Wrap(via, action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled() {
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+		ip := utils.ClientIP(r)
+		e := &Entry{Actor: ip, IP: ip, Via: via, Action: action}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+		rw := &responseWriter{ResponseWriter: w}
+		next(rw, r.WithContext(context.WithValue(r.Context(), entryKey{}, e)))
+
+		if e.Action == "" {
+			return
+		}
+		if e.Path == "" {
+			e.Path = mux.Vars(r)["filename"]
+		}
+		if e.Path == "" {
+			e.Path = r.URL.Query().Get("path")
+		}
+		e.Path = strings.Trim(e.Path, "/")
+		e.Time = start
+		e.BytesIn = body.n
+		e.BytesOut = rw.n
+		e.Status = rw.status
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		e.Result = ResultOK
+		if e.Status >= http.StatusBadRequest {
+			e.Result = ResultError
+			if e.Error == "" {
+				e.Error = errorMessage(rw.errBody)
+			}
+		}
+		e.DurationMs = time.Since(start).Milliseconds()
+		Record(*e)
+	}
+}
+
+func entry(r *http.Request) *Entry {
+	e, _ := r.Context().Value(entryKey{}).(*Entry)
+	if e == nil {
+		// 未经 Wrap 的请求，修改不会被记录
+		return &Entry{}
+	}
+	return e
+}
+
+// SetAction 设置操作，为空时不记录该请求
+func SetAction(r *http.Request, action string) {
+	entry(r).Action = action
+}
+
+// SetPath 设置操作的路径和目标路径（移动、复制时）
+func SetPath(r *http.Request, path, target string) {
+	e := entry(r)
+	e.Path = path
+	e.Target = strings.Trim(target, "/")
+}
+
+// SetActor 设置已认证的操作者
+func SetActor(r *http.Request, actor string) {
+	entry(r).Actor = actor
+}
+
+// errorMessage 从 JSON 响应的 message 字段或纯文本响应中取出错误信息
+func errorMessage(body []byte) string {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Message != "" {
+		return resp.Message
+	}
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "<") {
+		// XML（S3）或 HTML 响应只保留状态码
+		return ""
+	}
+	return text
+}
+
+// countingReader 统计读取的请求体字节数
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// responseWriter 记录状态码和发送的字节数，失败时保留响应开头用于提取错误信息
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	n       int64
+	errBody []byte
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest && len(w.errBody) < maxErrorBody {
+		w.errBody = append(w.errBody, p[:min(len(p), maxErrorBody-len(w.errBody))]...)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// ReadFrom 转发给原始的 ResponseWriter，使 http.ServeContent 下载文件时仍可使用 sendfile
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest {
+		// 失败时需要保留响应开头，逐块写入
+		return io.Copy(writerOnly{w}, src)
+	}
+	n, err := io.Copy(w.ResponseWriter, src)
+	w.n += n
+	return n, err
+}
+
+// writerOnly 隐藏 ReadFrom，避免 io.Copy 递归调用
+type writerOnly struct {
+	io.Writer
+}
+
+// Unwrap 供 http.ResponseController 访问原始的 ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
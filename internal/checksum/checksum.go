@@ -0,0 +1,296 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fileSystem/internal/config"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+)
+
+// 支持的校验算法
+const (
+	SHA256 = "sha256"
+	MD5    = "md5"
+	CRC32C = "crc32c"
+)
+
+var (
+	ErrMismatch       = errors.New("校验和不匹配，文件可能在传输中损坏")
+	ErrInvalidDigest  = errors.New("无效的校验和")
+	crc32cTable       = crc32.MakeTable(crc32.Castagnoli)
+	digestHeaderNames = map[string]string{
+		SHA256: "sha-256",
+		MD5:    "md5",
+		CRC32C: "crc32c",
+	}
+)
+
+// Hasher 在写入时同时计算多个摘要
+type Hasher struct {
+	hashes map[string]hash.Hash
+}
+
+// NewHasher 创建摘要计算器，始终计算 SHA-256，另外计算配置启用的和 extra 中的算法
+func NewHasher(extra ...string) *Hasher {
+	h := &Hasher{hashes: map[string]hash.Hash{SHA256: sha256.New()}}
+	for _, algo := range append(config.Cfg.Checksums, extra...) {
+		if _, ok := h.hashes[algo]; ok {
+			continue
+		}
+		switch algo {
+		case MD5:
+			h.hashes[algo] = md5.New()
+		case CRC32C:
+			h.hashes[algo] = crc32.New(crc32cTable)
+		}
+	}
+	return h
+}
+
+// Algos 返回正在计算的摘要算法
+func (h *Hasher) Algos() []string {
+	algos := make([]string, 0, len(h.hashes))
+	for algo := range h.hashes {
+		algos = append(algos, algo)
+	}
+	return algos
+}
+
+// Write 实现 io.Writer 接口
+func (h *Hasher) Write(p []byte) (int, error) {
+	for _, hh := range h.hashes {
+		hh.Write(p)
+	}
+	return len(p), nil
+}
+
+// Sum 返回已写入内容的摘要（十六进制）
+func (h *Hasher) Sum() models.Checksums {
+	var c models.Checksums
+	for algo, hh := range h.hashes {
+		sum := hex.EncodeToString(hh.Sum(nil))
+		switch algo {
+		case SHA256:
+			c.SHA256 = sum
+		case MD5:
+			c.MD5 = sum
+		case CRC32C:
+			c.CRC32C = sum
+		}
+	}
+	return c
+}
+
+// Expected 客户端提供的期望摘要（算法 -> 十六进制）
+type Expected map[string]string
+
+// Algos 返回期望摘要涉及的算法
+func (e Expected) Algos() []string {
+	algos := make([]string, 0, len(e))
+	for algo := range e {
+		algos = append(algos, algo)
+	}
+	return algos
+}
+
+// ParseExpected 从请求中读取期望摘要
+//
+// 支持查询参数 sha256/md5/crc32c（十六进制），以及 RFC 3230 的 Digest 请求头
+// （如 "sha-256=<base64>, md5=<base64>"）。
+func ParseExpected(r *http.Request) (Expected, error) {
+	expected := make(Expected)
+	query := r.URL.Query()
+	for algo := range digestHeaderNames {
+		if v := query.Get(algo); v != "" {
+			v = strings.ToLower(v)
+			if _, err := hex.DecodeString(v); err != nil {
+				return nil, ErrInvalidDigest
+			}
+			expected[algo] = v
+		}
+	}
+
+	if header := r.Header.Get("Digest"); header != "" {
+		for _, item := range strings.Split(header, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok {
+				return nil, ErrInvalidDigest
+			}
+			algo := ""
+			for a, headerName := range digestHeaderNames {
+				if strings.EqualFold(name, headerName) {
+					algo = a
+				}
+			}
+			if algo == "" {
+				// 忽略不认识的算法
+				continue
+			}
+			raw, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, ErrInvalidDigest
+			}
+			expected[algo] = hex.EncodeToString(raw)
+		}
+	}
+	return expected, nil
+}
+
+// Verify 比对计算结果与期望摘要
+func Verify(actual models.Checksums, expected Expected) error {
+	for algo, want := range expected {
+		if get(actual, algo) != want {
+			return fmt.Errorf("%w (%s)", ErrMismatch, algo)
+		}
+	}
+	return nil
+}
+
+func get(c models.Checksums, algo string) string {
+	switch algo {
+	case SHA256:
+		return c.SHA256
+	case MD5:
+		return c.MD5
+	case CRC32C:
+		return c.CRC32C
+	}
+	return ""
+}
+
+// DigestHeader 生成 RFC 3230 Digest 响应头
+func DigestHeader(c models.Checksums) string {
+	var parts []string
+	for _, algo := range []string{SHA256, MD5, CRC32C} {
+		sum := get(c, algo)
+		if sum == "" {
+			continue
+		}
+		raw, err := hex.DecodeString(sum)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, digestHeaderNames[algo]+"="+base64.StdEncoding.EncodeToString(raw))
+	}
+	return strings.Join(parts, ",")
+}
+
+// record 保存在存储根目录 .filesystem/checksums 下的摘要记录
+type record struct {
+	models.Checksums
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+func recordPath(root, fullPath string) (string, error) {
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || !storage.Within(root, fullPath) || rel == "." {
+		return "", storage.ErrInvalidPath
+	}
+	return filepath.Join(root, storage.MetaDirName, "checksums", rel+".json"), nil
+}
+
+// Save 保存文件摘要，记录当前的大小和修改时间用于判断是否过期
+func Save(root, fullPath string, c models.Checksums) error {
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+	path, err := recordPath(root, fullPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(record{
+		Checksums: c,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load 读取文件摘要，文件在记录之后被修改过时返回 false
+func Load(root, fullPath string, info os.FileInfo) (models.Checksums, bool) {
+	path, err := recordPath(root, fullPath)
+	if err != nil {
+		return models.Checksums{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.Checksums{}, false
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return models.Checksums{}, false
+	}
+	if rec.Size != info.Size() || !rec.ModTime.Equal(info.ModTime()) {
+		return models.Checksums{}, false
+	}
+	return rec.Checksums, true
+}
+
+// Remove 删除文件或目录对应的摘要记录
+func Remove(root, fullPath string, isDir bool) error {
+	path, err := recordPath(root, fullPath)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		path = strings.TrimSuffix(path, ".json")
+		return os.RemoveAll(path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Rename 文件或目录移动后同步移动对应的摘要记录
+func Rename(root, oldPath, newPath string, isDir bool) error {
+	from, err := recordPath(root, oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := recordPath(root, newPath)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		from = strings.TrimSuffix(from, ".json")
+		to = strings.TrimSuffix(to, ".json")
+	}
+	if _, err := os.Stat(from); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// ETag 生成 ETag，有摘要时使用 SHA-256，否则使用大小和修改时间生成弱 ETag
+func ETag(c models.Checksums, info os.FileInfo) string {
+	if c.SHA256 != "" {
+		return `"` + c.SHA256 + `"`
+	}
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
@@ -0,0 +1,152 @@
+package checksum
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"fileSystem/internal/models"
+)
+
+// "hello world" 的摘要
+const (
+	helloSHA256    = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	helloSHA256B64 = "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="
+	helloMD5       = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+	helloMD5B64    = "XrY7u+Ae7tCTyyK7j1rNww=="
+)
+
+func TestParseExpected(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		digest  string
+		want    Expected
+		wantErr error
+	}{
+		{name: "none", want: Expected{}},
+		{name: "hex query", query: "sha256=" + helloSHA256, want: Expected{SHA256: helloSHA256}},
+		{name: "uppercase hex query", query: "md5=5EB63BBBE01EEED093CB22BB8F5ACDC3", want: Expected{MD5: helloMD5}},
+		{name: "several hex queries", query: "sha256=" + helloSHA256 + "&md5=" + helloMD5, want: Expected{SHA256: helloSHA256, MD5: helloMD5}},
+		{name: "invalid hex query", query: "sha256=xyz", wantErr: ErrInvalidDigest},
+		{name: "base64 digest", digest: "sha-256=" + helloSHA256B64, want: Expected{SHA256: helloSHA256}},
+		{name: "digest name is case insensitive", digest: "SHA-256=" + helloSHA256B64, want: Expected{SHA256: helloSHA256}},
+		{name: "several digests", digest: "sha-256=" + helloSHA256B64 + ", md5=" + helloMD5B64, want: Expected{SHA256: helloSHA256, MD5: helloMD5}},
+		{name: "unknown digest ignored", digest: "sha-512=AAAA, md5=" + helloMD5B64, want: Expected{MD5: helloMD5}},
+		{name: "digest without value", digest: "sha-256", wantErr: ErrInvalidDigest},
+		{name: "invalid base64 digest", digest: "sha-256=not base64!", wantErr: ErrInvalidDigest},
+		{name: "query and digest", query: "md5=" + helloMD5, digest: "sha-256=" + helloSHA256B64, want: Expected{SHA256: helloSHA256, MD5: helloMD5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest("PUT", "/upload?"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.digest != "" {
+				r.Header.Set("Digest", tt.digest)
+			}
+			got, err := ParseExpected(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseExpected() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExpected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	actual := models.Checksums{SHA256: helloSHA256, MD5: helloMD5}
+	tests := []struct {
+		name     string
+		expected Expected
+		want     error
+	}{
+		{"nothing expected", Expected{}, nil},
+		{"match", Expected{SHA256: helloSHA256, MD5: helloMD5}, nil},
+		{"sha256 mismatch", Expected{SHA256: helloMD5 + helloMD5}, ErrMismatch},
+		{"md5 mismatch", Expected{SHA256: helloSHA256, MD5: "00000000000000000000000000000000"}, ErrMismatch},
+		{"algorithm not computed", Expected{CRC32C: "0d4a1185"}, ErrMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(actual, tt.expected); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasherMatchesParsedDigest(t *testing.T) {
+	h := NewHasher(MD5)
+	h.Write([]byte("hello world"))
+	r, _ := http.NewRequest("PUT", "/upload", nil)
+	r.Header.Set("Digest", DigestHeader(h.Sum()))
+	expected, err := ParseExpected(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(h.Sum(), expected); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestLoadInvalidatedOnChange(t *testing.T) {
+	root := t.TempDir()
+	fullPath := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(fullPath, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sums := models.Checksums{SHA256: helloSHA256}
+	if err := Save(root, fullPath, sums); err != nil {
+		t.Fatal(err)
+	}
+	stat := func() os.FileInfo {
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+
+	if got, ok := Load(root, fullPath, stat()); !ok || got != sums {
+		t.Fatalf("Load() = %+v, %v, want %+v, true", got, ok, sums)
+	}
+
+	// 修改时间变化
+	later := stat().ModTime().Add(time.Second)
+	if err := os.Chtimes(fullPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Load(root, fullPath, stat()); ok {
+		t.Error("Load() after mtime change = true, want false")
+	}
+
+	// 大小变化，修改时间保持记录时的值
+	if err := Save(root, fullPath, sums); err != nil {
+		t.Fatal(err)
+	}
+	modTime := stat().ModTime()
+	if err := os.WriteFile(fullPath, []byte("hello world!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(fullPath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Load(root, fullPath, stat()); ok {
+		t.Error("Load() after size change = true, want false")
+	}
+}
+
+func TestRecordOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := Save(root, filepath.Join(filepath.Dir(root), "other.txt"), models.Checksums{}); err == nil {
+		t.Error("Save() outside root succeeded")
+	}
+}
@@ -6,18 +6,88 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 type Config struct {
-	StorageDir string `json:"storage_dir"`
-	Port       string `json:"port"`
-	RootPath   string `json:"root_path"`
+	StorageDir string       `json:"storage_dir"`
+	Port       string       `json:"port"`
+	RootPath   string       `json:"root_path"`
+	Mounts     []Mount      `json:"mounts,omitempty"`    // 命名存储位置，配置后替代 storage_dir 作为顶层目录
+	Dedup      bool         `json:"dedup,omitempty"`     // 启用内容寻址去重存储
+	Checksums  []string     `json:"checksums,omitempty"` // 上传时额外计算的摘要算法（md5、crc32c），SHA-256 始终计算
+	SFTP       *SFTPConfig  `json:"sftp,omitempty"`      // 内置 SFTP 服务，未配置时不启动
+	S3         *S3Config    `json:"s3,omitempty"`        // S3 兼容接口，未配置时不启用
+	Audit      *AuditConfig `json:"audit,omitempty"`     // 审计日志，未配置时使用默认设置
+
+	UploadConcurrency int `json:"upload_concurrency,omitempty"` // 建议浏览器同时上传的文件数，默认 3
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Disabled   bool   `json:"disabled,omitempty"`    // 关闭审计日志
+	MaxSizeMB  int64  `json:"max_size_mb,omitempty"` // 单个日志文件的大小上限（MB），默认 10
+	MaxFiles   int    `json:"max_files,omitempty"`   // 保留的历史日志文件数，默认 10
+	AdminToken string `json:"admin_token,omitempty"` // 查询和导出接口的访问令牌，为空时不开放这两个接口
 }
 
+// 审计日志默认设置
+const (
+	DefaultAuditMaxSizeMB = 10
+	DefaultAuditMaxFiles  = 10
+)
+
+// AuditSettings 返回审计日志配置，未配置的项使用默认值
+func AuditSettings() AuditConfig {
+	var c AuditConfig
+	if Cfg.Audit != nil {
+		c = *Cfg.Audit
+	}
+	if c.MaxSizeMB <= 0 {
+		c.MaxSizeMB = DefaultAuditMaxSizeMB
+	}
+	if c.MaxFiles <= 0 {
+		c.MaxFiles = DefaultAuditMaxFiles
+	}
+	return c
+}
+
+// S3Config S3 兼容接口的访问密钥
+type S3Config struct {
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+// SFTPConfig 内置 SFTP 服务配置
+type SFTPConfig struct {
+	Port    string     `json:"port"`     // 监听地址，如 ":2022"
+	HostKey string     `json:"host_key"` // 主机私钥文件，不存在时自动生成 ed25519 密钥
+	Users   []SFTPUser `json:"users"`
+}
+
+// SFTPUser SFTP 登录用户，可使用密码或公钥认证
+type SFTPUser struct {
+	Username       string `json:"username"`
+	Password       string `json:"password,omitempty"`
+	AuthorizedKeys string `json:"authorized_keys,omitempty"` // OpenSSH authorized_keys 格式的公钥文件
+}
+
+// Mount 命名存储位置（挂载点）
+type Mount struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"read_only"`
+	Quota    int64  `json:"quota"` // 配额（字节），0 表示不限制
+}
+
+// DefaultUploadConcurrency 默认建议的浏览器并发上传数
+const DefaultUploadConcurrency = 3
+
 var (
 	UploadDir string
 	Port      = ":8080"
 	Cfg       Config
+	Mounts    []Mount
 )
 
 // 获取默认下载目录
@@ -65,6 +135,7 @@ func LoadConfig() {
 		}
 
 		Cfg = defaultConfig
+		Cfg.UploadConcurrency = DefaultUploadConcurrency
 		log.Printf("已创建默认配置文件: %s", configFile)
 		return
 	}
@@ -96,6 +167,10 @@ func LoadConfig() {
 		Cfg.RootPath = "/"
 	}
 
+	if Cfg.UploadConcurrency <= 0 {
+		Cfg.UploadConcurrency = DefaultUploadConcurrency
+	}
+
 	UploadDir = Cfg.StorageDir
 	Port = Cfg.Port
 
@@ -106,8 +181,82 @@ func LoadConfig() {
 
 	absPath, _ := filepath.Abs(UploadDir)
 	log.Printf("存储目录已设置为: %s", absPath)
+
+	loadMounts()
 	log.Printf("服务器端口: %s", Port)
 	log.Printf("根路由已设置为: %s", Cfg.RootPath)
+	log.Printf("去重存储: %v", Cfg.Dedup)
+	log.Printf("建议并发上传数: %d", Cfg.UploadConcurrency)
+
+	for _, algo := range Cfg.Checksums {
+		if algo != "md5" && algo != "crc32c" {
+			log.Fatalf("不支持的摘要算法: %s（可选: md5, crc32c）", algo)
+		}
+	}
+
+	loadSFTP()
+
+	if Cfg.S3 != nil {
+		if Cfg.S3.AccessKey == "" || Cfg.S3.SecretKey == "" {
+			log.Fatalf("S3 配置无效: access_key 和 secret_key 均不能为空")
+		}
+		log.Printf("S3 兼容接口: 已启用")
+	}
+}
+
+// 校验 SFTP 配置并填充默认值
+func loadSFTP() {
+	if Cfg.SFTP == nil {
+		return
+	}
+	if Cfg.SFTP.Port == "" {
+		Cfg.SFTP.Port = ":2022"
+	}
+	if len(Cfg.SFTP.Users) == 0 {
+		log.Fatalf("SFTP 配置无效: 至少需要配置一个用户")
+	}
+	for _, u := range Cfg.SFTP.Users {
+		if u.Username == "" || u.Password == "" && u.AuthorizedKeys == "" {
+			log.Fatalf("SFTP 用户配置无效: 需要用户名以及密码或公钥文件 (username=%q)", u.Username)
+		}
+	}
+	log.Printf("SFTP 服务端口: %s, 用户数: %d", Cfg.SFTP.Port, len(Cfg.SFTP.Users))
+}
+
+// 校验并加载挂载点配置
+func loadMounts() {
+	Mounts = nil
+	seen := make(map[string]bool)
+	for _, m := range Cfg.Mounts {
+		if m.Name == "" || m.Path == "" {
+			log.Fatalf("挂载点配置无效: 名称和路径均不能为空 (name=%q, path=%q)", m.Name, m.Path)
+		}
+		if strings.ContainsAny(m.Name, `/\`) || strings.Contains(m.Name, "..") {
+			log.Fatalf("挂载点名称无效: %s", m.Name)
+		}
+		if seen[m.Name] {
+			log.Fatalf("挂载点名称重复: %s", m.Name)
+		}
+		seen[m.Name] = true
+
+		if err := os.MkdirAll(m.Path, 0755); err != nil {
+			log.Fatalf("无法创建挂载点目录 %s: %v", m.Path, err)
+		}
+
+		absPath, _ := filepath.Abs(m.Path)
+		log.Printf("挂载点: %s -> %s (只读: %v, 配额: %d 字节)", m.Name, absPath, m.ReadOnly, m.Quota)
+		Mounts = append(Mounts, m)
+	}
+}
+
+// FindMount 按名称查找挂载点
+func FindMount(name string) *Mount {
+	for i := range Mounts {
+		if Mounts[i].Name == name {
+			return &Mounts[i]
+		}
+	}
+	return nil
 }
 
 // SaveConfig 保存配置文件
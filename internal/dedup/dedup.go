@@ -0,0 +1,395 @@
+package dedup
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"fileSystem/internal/config"
+	"fileSystem/internal/storage"
+)
+
+// ErrUnknownBlob 内容尚未入库
+var ErrUnknownBlob = errors.New("内容不存在，请完整上传")
+
+// Store 内容寻址的去重存储，每个存储根目录一个实例
+//
+// 文件内容按 SHA-256 存放在 <root>/.filesystem/blobs 下，目录中的文件是指向
+// 对应 blob 的硬链接（不支持硬链接时退化为复制），索引记录每个文件引用的 blob
+// 以及每个 blob 的引用计数，引用计数归零时删除 blob。
+type Store struct {
+	root  string
+	dir   string
+	mu    sync.Mutex
+	index indexData
+}
+
+type indexData struct {
+	Entries map[string]string `json:"entries"` // 相对路径 -> SHA-256
+	Refs    map[string]int    `json:"refs"`    // SHA-256 -> 引用计数
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// Enabled 是否启用去重存储
+func Enabled() bool {
+	return config.Cfg.Dedup
+}
+
+// For 获取存储根目录对应的去重存储
+func For(root string) (*Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[root]; ok {
+		return s, nil
+	}
+
+	s := &Store{
+		root: root,
+		dir:  filepath.Join(root, storage.MetaDirName, "blobs"),
+		index: indexData{
+			Entries: make(map[string]string),
+			Refs:    make(map[string]int),
+		},
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建 blob 目录: %w", err)
+	}
+
+	data, err := os.ReadFile(s.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("无法读取去重索引: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.index); err != nil {
+			return nil, fmt.Errorf("无法解析去重索引: %w", err)
+		}
+		if s.index.Entries == nil {
+			s.index.Entries = make(map[string]string)
+		}
+		if s.index.Refs == nil {
+			s.index.Refs = make(map[string]int)
+		}
+	}
+
+	log.Printf("[DEDUP] 已加载去重存储 - 根目录: %s, 文件数: %d, blob 数: %d",
+		root, len(s.index.Entries), len(s.index.Refs))
+	stores[root] = s
+	return s, nil
+}
+
+func (s *Store) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+func (s *Store) blobPath(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// 保存索引（先写临时文件再替换，避免写入中断导致索引损坏）
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.indexPath())
+}
+
+// ValidHash 校验 SHA-256 十六进制字符串
+func ValidHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Has 是否已存储该内容
+func (s *Store) Has(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Refs[hash] > 0
+}
+
+// Size 返回已入库内容的大小
+func (s *Store) Size(hash string) (int64, error) {
+	info, err := os.Stat(s.blobPath(hash))
+	if err != nil {
+		return 0, ErrUnknownBlob
+	}
+	return info.Size(), nil
+}
+
+// CreateTemp 在 blob 目录中创建上传临时文件
+func (s *Store) CreateTemp() (*os.File, error) {
+	f, err := os.CreateTemp(s.dir, "upload-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp 默认权限为 0600，与普通上传文件保持一致
+	if err := f.Chmod(0644); err != nil {
+		log.Printf("[DEDUP] 警告: 无法设置临时文件权限 %s - %v", f.Name(), err)
+	}
+	return f, nil
+}
+
+// Ingest 将已写完的临时文件按内容入库，并在 fullPath 创建指向 blob 的文件
+func (s *Store) Ingest(tmpPath, hash, fullPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob := s.blobPath(hash)
+	if _, err := os.Stat(blob); err == nil {
+		// 内容已存在，丢弃临时文件
+		os.Remove(tmpPath)
+		log.Printf("[DEDUP] 内容已存在，复用 blob: %s", hash)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if err := os.Rename(tmpPath, blob); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		log.Printf("[DEDUP] 新增 blob: %s", hash)
+	}
+
+	return s.link(hash, fullPath)
+}
+
+// LinkExisting 为已入库的内容创建新的文件引用（秒传）
+func (s *Store) LinkExisting(hash, fullPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index.Refs[hash] == 0 {
+		return ErrUnknownBlob
+	}
+	if _, err := os.Stat(s.blobPath(hash)); err != nil {
+		return ErrUnknownBlob
+	}
+	return s.link(hash, fullPath)
+}
+
+// 创建引用并更新索引，调用方需持有锁
+func (s *Store) link(hash, fullPath string) error {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return err
+	}
+
+	// 先占用新引用再释放旧引用，避免覆盖相同内容时 blob 被提前删除
+	s.index.Refs[hash]++
+	s.releaseLocked(rel)
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		s.unrefLocked(hash)
+		return err
+	}
+
+	blob := s.blobPath(hash)
+	if err := os.Link(blob, fullPath); err != nil {
+		log.Printf("[DEDUP] 无法创建硬链接，改为复制 - %s: %v", fullPath, err)
+		if err := copyFile(blob, fullPath); err != nil {
+			s.unrefLocked(hash)
+			return err
+		}
+	}
+
+	s.index.Entries[rel] = hash
+	return s.save()
+}
+
+// Shared 文件是否与其他文件共用同一个 blob 的硬链接，此时修改文件的属性（如修改时间）会同时影响其他文件
+func (s *Store) Shared(fullPath string) bool {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash, ok := s.index.Entries[rel]
+	if !ok || s.index.Refs[hash] <= 1 {
+		return false
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return false
+	}
+	blob, err := os.Stat(s.blobPath(hash))
+	return err == nil && os.SameFile(info, blob)
+}
+
+// Detach 将与其他文件共用 blob 的文件替换为独立的副本并释放其引用，之后可以单独修改文件的属性
+func (s *Store) Detach(fullPath string) error {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash, ok := s.index.Entries[rel]
+	if !ok {
+		return nil
+	}
+	tmp, err := s.CreateTemp()
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	if err := copyFile(s.blobPath(hash), tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.releaseLocked(rel)
+	log.Printf("[DEDUP] 已改为独立副本: %s", fullPath)
+	return s.save()
+}
+
+// Release 文件被删除后释放其引用
+func (s *Store) Release(fullPath string) error {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.releaseLocked(rel) {
+		return nil
+	}
+	return s.save()
+}
+
+// ReleaseTree 目录被删除后释放其中所有文件的引用
+func (s *Store) ReleaseTree(fullPath string) error {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for entry := range s.index.Entries {
+		if rel == "" || entry == rel || strings.HasPrefix(entry, rel+"/") {
+			if s.releaseLocked(entry) {
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// Rename 文件或目录移动后更新索引中的路径，内容和引用计数不变
+func (s *Store) Rename(oldPath, newPath string) error {
+	from, err := s.rel(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := s.rel(newPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for entry, hash := range s.index.Entries {
+		var moved string
+		switch {
+		case entry == from:
+			moved = to
+		case strings.HasPrefix(entry, from+"/"):
+			moved = to + strings.TrimPrefix(entry, from)
+		default:
+			continue
+		}
+		delete(s.index.Entries, entry)
+		s.index.Entries[moved] = hash
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// 释放单个引用，返回索引是否有变化，调用方需持有锁
+func (s *Store) releaseLocked(rel string) bool {
+	hash, ok := s.index.Entries[rel]
+	if !ok {
+		return false
+	}
+	delete(s.index.Entries, rel)
+	s.unrefLocked(hash)
+	return true
+}
+
+// 引用计数减一，归零时删除 blob，调用方需持有锁
+func (s *Store) unrefLocked(hash string) {
+	s.index.Refs[hash]--
+	if s.index.Refs[hash] > 0 {
+		return
+	}
+	delete(s.index.Refs, hash)
+	if err := os.Remove(s.blobPath(hash)); err != nil && !os.IsNotExist(err) {
+		log.Printf("[DEDUP] 警告: 无法删除 blob %s - %v", hash, err)
+	} else {
+		log.Printf("[DEDUP] 引用计数归零，已删除 blob: %s", hash)
+	}
+}
+
+func (s *Store) rel(fullPath string) (string, error) {
+	rel, err := filepath.Rel(s.root, fullPath)
+	if err != nil || !storage.Within(s.root, fullPath) {
+		return "", storage.ErrInvalidPath
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,244 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newStore 在临时目录中创建去重存储
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	root := t.TempDir()
+	s, err := For(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		storesMu.Lock()
+		delete(stores, root)
+		storesMu.Unlock()
+	})
+	return s
+}
+
+// reload 丢弃内存中的实例，从磁盘重新读取索引
+func reload(t *testing.T, s *Store) *Store {
+	t.Helper()
+	storesMu.Lock()
+	delete(stores, s.root)
+	storesMu.Unlock()
+	s, err := For(s.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// upload 模拟上传：写入临时文件后入库到 rel
+func upload(t *testing.T, s *Store, rel, content string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	f, err := s.CreateTemp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(content)
+	f.Close()
+	fullPath := filepath.Join(s.root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ingest(f.Name(), hash, fullPath); err != nil {
+		t.Fatalf("Ingest(%s) error = %v", rel, err)
+	}
+	return hash
+}
+
+// remove 模拟删除文件
+func remove(t *testing.T, s *Store, rel string) {
+	t.Helper()
+	fullPath := filepath.Join(s.root, filepath.FromSlash(rel))
+	if err := os.Remove(fullPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(fullPath); err != nil {
+		t.Fatalf("Release(%s) error = %v", rel, err)
+	}
+}
+
+// check 校验引用计数、索引条目和 blob 文件，并确认磁盘上的索引与内存一致
+func check(t *testing.T, s *Store, hash string, refs int, entries ...string) {
+	t.Helper()
+	for _, store := range []*Store{s, reload(t, s)} {
+		if got := store.index.Refs[hash]; got != refs {
+			t.Errorf("refs = %d, want %d", got, refs)
+		}
+		if _, ok := store.index.Refs[hash]; refs == 0 && ok {
+			t.Errorf("stale refs entry for %s", hash)
+		}
+		var got []string
+		for rel, h := range store.index.Entries {
+			if h == hash {
+				got = append(got, rel)
+			}
+		}
+		if len(got) != len(entries) {
+			t.Errorf("entries = %v, want %v", got, entries)
+		}
+		for _, rel := range entries {
+			if store.index.Entries[rel] != hash {
+				t.Errorf("entry %s = %q, want %s", rel, store.index.Entries[rel], hash)
+			}
+		}
+	}
+	_, err := os.Stat(s.blobPath(hash))
+	if refs > 0 && err != nil {
+		t.Errorf("blob missing: %v", err)
+	}
+	if refs == 0 && !os.IsNotExist(err) {
+		t.Errorf("blob still exists after last reference released")
+	}
+}
+
+func TestRefcountUploadAndDelete(t *testing.T) {
+	s := newStore(t)
+
+	hash := upload(t, s, "a.txt", "hello")
+	check(t, s, hash, 1, "a.txt")
+	if s.Shared(filepath.Join(s.root, "a.txt")) {
+		t.Error("Shared(a.txt) = true with a single reference")
+	}
+
+	if got := upload(t, s, "dir/b.txt", "hello"); got != hash {
+		t.Fatalf("duplicate upload hash = %s, want %s", got, hash)
+	}
+	check(t, s, hash, 2, "a.txt", "dir/b.txt")
+	if !s.Shared(filepath.Join(s.root, "a.txt")) {
+		t.Error("Shared(a.txt) = false with two hard links")
+	}
+
+	remove(t, s, "a.txt")
+	check(t, s, hash, 1, "dir/b.txt")
+	data, err := os.ReadFile(filepath.Join(s.root, "dir", "b.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("remaining file = %q, %v", data, err)
+	}
+
+	remove(t, s, "dir/b.txt")
+	check(t, s, hash, 0)
+	s = reload(t, s)
+	if len(s.index.Entries) != 0 || len(s.index.Refs) != 0 {
+		t.Errorf("index not empty: %+v", s.index)
+	}
+	if s.Has(hash) {
+		t.Error("Has() = true after all references released")
+	}
+}
+
+func TestRefcountOverwrite(t *testing.T) {
+	s := newStore(t)
+
+	hello := upload(t, s, "a.txt", "hello")
+	// 相同内容覆盖同一路径，引用计数不变
+	upload(t, s, "a.txt", "hello")
+	check(t, s, hello, 1, "a.txt")
+
+	// 不同内容覆盖，旧 blob 被释放
+	world := upload(t, s, "a.txt", "world")
+	check(t, s, hello, 0)
+	check(t, s, world, 1, "a.txt")
+}
+
+func TestRefcountRename(t *testing.T) {
+	s := newStore(t)
+
+	hash := upload(t, s, "a.txt", "hello")
+	upload(t, s, "dir/b.txt", "hello")
+	upload(t, s, "dir/sub/c.txt", "hello")
+
+	// 移动文件
+	if err := os.Rename(filepath.Join(s.root, "a.txt"), filepath.Join(s.root, "moved.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename(filepath.Join(s.root, "a.txt"), filepath.Join(s.root, "moved.txt")); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, hash, 3, "moved.txt", "dir/b.txt", "dir/sub/c.txt")
+
+	// 移动目录，不影响名称前缀相同的其他条目
+	upload(t, s, "dir2.txt", "hello")
+	if err := os.Rename(filepath.Join(s.root, "dir"), filepath.Join(s.root, "renamed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename(filepath.Join(s.root, "dir"), filepath.Join(s.root, "renamed")); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, hash, 4, "moved.txt", "renamed/b.txt", "renamed/sub/c.txt", "dir2.txt")
+
+	// 删除移动后的目录和文件，引用全部释放
+	if err := os.RemoveAll(filepath.Join(s.root, "renamed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReleaseTree(filepath.Join(s.root, "renamed")); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, hash, 2, "moved.txt", "dir2.txt")
+	remove(t, s, "moved.txt")
+	remove(t, s, "dir2.txt")
+	check(t, s, hash, 0)
+}
+
+func TestLinkExisting(t *testing.T) {
+	s := newStore(t)
+
+	sum := sha256.Sum256([]byte("hello"))
+	unknown := hex.EncodeToString(sum[:])
+	if err := s.LinkExisting(unknown, filepath.Join(s.root, "a.txt")); err != ErrUnknownBlob {
+		t.Fatalf("LinkExisting() on unknown blob error = %v, want %v", err, ErrUnknownBlob)
+	}
+
+	hash := upload(t, s, "a.txt", "hello")
+	if err := s.LinkExisting(hash, filepath.Join(s.root, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, hash, 2, "a.txt", "b.txt")
+}
+
+func TestDetach(t *testing.T) {
+	s := newStore(t)
+
+	hash := upload(t, s, "a.txt", "hello")
+	upload(t, s, "b.txt", "hello")
+	a, b := filepath.Join(s.root, "a.txt"), filepath.Join(s.root, "b.txt")
+	if err := s.Detach(b); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, hash, 1, "a.txt")
+	if s.Shared(a) {
+		t.Error("Shared(a.txt) = true after b.txt detached")
+	}
+
+	// 修改独立副本的属性不影响共用 blob 的文件
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(b, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(a); info.ModTime().Equal(mtime) {
+		t.Error("a.txt modification time changed with b.txt")
+	}
+	if data, err := os.ReadFile(b); err != nil || string(data) != "hello" {
+		t.Errorf("b.txt = %q, %v", data, err)
+	}
+
+	// 删除独立副本不影响引用计数
+	os.Remove(b)
+	if err := s.Release(b); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, hash, 1, "a.txt")
+}
@@ -0,0 +1,210 @@
+// Package dirstats 在后台递归统计目录的总大小、文件数和最近修改时间，并缓存结果
+package dirstats
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fileSystem/internal/storage"
+)
+
+// Stats 目录的递归统计结果，不包括内部数据目录和正在上传的临时文件
+type Stats struct {
+	Size    int64     // 所有文件的总大小
+	Files   int64     // 文件数
+	Dirs    int64     // 子目录数
+	ModTime time.Time // 目录树中最近的修改时间（含目录本身）
+}
+
+const (
+	// maxEntries 缓存条数上限，超过后清空重新统计
+	maxEntries = 100000
+	// queueSize 等待统计的目录数上限，队列满时忽略新请求，下次列出目录时再排队
+	queueSize = 64
+)
+
+var (
+	mu     sync.Mutex
+	cache  = make(map[string]Stats)
+	queued = make(map[string]bool)
+	queue  = make(chan string, queueSize)
+	start  sync.Once
+
+	// active 正在进行的统计，统计期间失效的路径记录在其中，完成后不保存与其相关的结果
+	active = make(map[*scan]bool)
+)
+
+// scan 一次正在进行的统计
+type scan struct {
+	dirty []string
+}
+
+func init() {
+	storage.Usage = Usage
+}
+
+// Usage 目录的总大小，优先使用缓存，尚未统计或已失效时立即统计；用于配额检查
+func Usage(dir string) (int64, error) {
+	if st, ok := Lookup(dir); ok {
+		return st.Size, nil
+	}
+	results, err := Compute(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return results[dir].Size, nil
+}
+
+// Lookup 返回目录（完整路径）的统计结果，尚未统计或已失效时返回 false
+func Lookup(dir string) (Stats, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	st, ok := cache[dir]
+	return st, ok
+}
+
+// Scan 请求在后台统计目录及其所有子目录，已在排队时忽略
+func Scan(dir string) {
+	start.Do(func() { go worker() })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if queued[dir] {
+		return
+	}
+	select {
+	case queue <- dir:
+		queued[dir] = true
+	default:
+	}
+}
+
+// Invalidate 路径上发生写入后使缓存失效：清除路径本身和所有上级目录，isDir 时还清除其下的子目录
+func Invalidate(path string, isDir bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	for s := range active {
+		s.dirty = append(s.dirty, path)
+	}
+	for p := path; ; p = filepath.Dir(p) {
+		delete(cache, p)
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+	if isDir {
+		prefix := path + string(filepath.Separator)
+		for dir := range cache {
+			if strings.HasPrefix(dir, prefix) {
+				delete(cache, dir)
+			}
+		}
+	}
+}
+
+// Compute 立即统计目录，返回目录及其所有子目录（完整路径）的结果，同时更新缓存
+func Compute(dir string) (map[string]Stats, error) {
+	s := &scan{}
+	mu.Lock()
+	active[s] = true
+	mu.Unlock()
+
+	results := make(map[string]Stats)
+	_, err := walk(dir, results)
+
+	mu.Lock()
+	delete(active, s)
+	if len(cache)+len(results) > maxEntries {
+		cache = make(map[string]Stats)
+	}
+	for d, st := range results {
+		if !affected(d, s.dirty) {
+			cache[d] = st
+		}
+	}
+	mu.Unlock()
+	return results, err
+}
+
+// worker 依次统计排队的目录，一次遍历同时得到所有子目录的结果
+func worker() {
+	for dir := range queue {
+		if _, ok := Lookup(dir); !ok {
+			startTime := time.Now()
+			results, err := Compute(dir)
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("[DIRSTATS] 警告: 无法统计目录 %s - %v", dir, err)
+			} else if err == nil {
+				log.Printf("[DIRSTATS] 已统计 %s（%d 个目录），耗时: %v", dir, len(results), time.Since(startTime))
+			}
+		}
+
+		mu.Lock()
+		delete(queued, dir)
+		mu.Unlock()
+	}
+}
+
+// walk 递归统计目录，把每个子目录的结果写入 results
+func walk(dir string, results map[string]Stats) (Stats, error) {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return Stats{}, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	st := Stats{ModTime: info.ModTime()}
+	for _, e := range entries {
+		if e.IsDir() {
+			if e.Name() == storage.MetaDirName {
+				continue
+			}
+			sub, err := walk(filepath.Join(dir, e.Name()), results)
+			if err != nil {
+				continue
+			}
+			st.Size += sub.Size
+			st.Files += sub.Files
+			st.Dirs += sub.Dirs + 1
+			if sub.ModTime.After(st.ModTime) {
+				st.ModTime = sub.ModTime
+			}
+			continue
+		}
+		if storage.IsUploadTemp(e.Name()) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		st.Size += fi.Size()
+		st.Files++
+		if fi.ModTime().After(st.ModTime) {
+			st.ModTime = fi.ModTime()
+		}
+	}
+	results[dir] = st
+	return st, nil
+}
+
+// affected 判断目录的统计结果是否受失效路径影响（目录本身、其上级或下级发生了变化）
+func affected(dir string, paths []string) bool {
+	sep := string(filepath.Separator)
+	for _, p := range paths {
+		if p == dir || strings.HasPrefix(p, dir+sep) || strings.HasPrefix(dir, p+sep) {
+			return true
+		}
+	}
+	return false
+}
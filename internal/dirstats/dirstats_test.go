@@ -0,0 +1,33 @@
+package dirstats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fileSystem/internal/storage"
+)
+
+func TestUsageCached(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "hello")
+	write(storage.UploadTempPrefix+"b.txt-1.tmp", "uploading")
+
+	if got, err := storage.Usage(root); err != nil || got != 5 {
+		t.Fatalf("Usage() = %d, %v, want 5", got, err)
+	}
+	// 未通过服务写入的变化在失效前使用缓存的结果，不重新遍历
+	write("c.txt", "world")
+	if got, _ := storage.Usage(root); got != 5 {
+		t.Errorf("cached Usage() = %d, want 5", got)
+	}
+	Invalidate(filepath.Join(root, "c.txt"), false)
+	if got, _ := storage.Usage(root); got != 10 {
+		t.Errorf("Usage() after invalidation = %d, want 10", got)
+	}
+}
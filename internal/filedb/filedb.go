@@ -0,0 +1,362 @@
+// Package filedb 用嵌入式数据库（bbolt）为存储根目录下的每个文件保存一条记录
+//
+// 记录中的 SHA-256 取自文件的摘要记录（checksum 包），摘要记录失效时为空。
+// 记录由上传、删除、移动等操作实时更新，启动时再与磁盘上的文件核对一次，
+// 补上在服务器外放入的文件并删除已不存在的文件。
+package filedb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/storage"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Record 一个文件的记录
+type Record struct {
+	Path     string    `json:"path"`               // 相对存储根目录的路径（使用 / 分隔）
+	Size     int64     `json:"size"`               // 文件大小
+	ModTime  time.Time `json:"modTime"`            // 修改时间
+	SHA256   string    `json:"sha256,omitempty"`   // 内容摘要，取自摘要记录，未知时为空
+	Uploader string    `json:"uploader,omitempty"` // 上传者，在服务器外放入的文件为空
+	Created  time.Time `json:"created"`            // 首次上传的时间，在服务器外放入的文件为首次发现的时间
+	History  []Upload  `json:"history,omitempty"`  // 最近的上传记录，按时间先后排列
+}
+
+// Upload 一次上传的记录
+type Upload struct {
+	Uploader  string        `json:"uploader"`            // 上传者：SFTP 用户名或客户端 IP
+	IP        string        `json:"ip,omitempty"`        // 客户端 IP
+	UserAgent string        `json:"userAgent,omitempty"` // 客户端 User-Agent
+	Via       string        `json:"via"`                 // 上传方式：web、edit、drop、webdav、sftp、s3
+	Time      time.Time     `json:"time"`                // 完成时间
+	Size      int64         `json:"size"`                // 上传的字节数
+	Duration  time.Duration `json:"duration"`            // 耗时
+	Speed     float64       `json:"speed"`               // 平均速度（字节/秒）
+}
+
+// maxHistory 每个文件保留的上传记录条数
+const maxHistory = 20
+
+var filesBucket = []byte("files")
+
+// DB 一个存储根目录的文件数据库，保存在 <root>/.filesystem/files.db
+type DB struct {
+	root string
+	db   *bolt.DB
+}
+
+var (
+	dbsMu sync.Mutex
+	dbs   = make(map[string]*DB)
+)
+
+// For 获取存储根目录对应的数据库，首次调用时打开
+func For(root string) (*DB, error) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+
+	if d, ok := dbs[root]; ok {
+		return d, nil
+	}
+
+	path := filepath.Join(root, storage.MetaDirName, "files.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// 数据库文件被其他进程占用时不要一直等待
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件数据库 %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(filesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	d := &DB{root: root, db: db}
+	dbs[root] = d
+	return d, nil
+}
+
+func (d *DB) rel(fullPath string) (string, error) {
+	rel, err := filepath.Rel(d.root, fullPath)
+	if err != nil || !storage.Within(d.root, fullPath) || rel == "." {
+		return "", storage.ErrInvalidPath
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Get 返回文件的记录
+func (d *DB) Get(fullPath string) (Record, bool) {
+	rel, err := d.rel(fullPath)
+	if err != nil {
+		return Record{}, false
+	}
+	var rec Record
+	found := false
+	d.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(filesBucket).Get([]byte(rel))
+		found = data != nil && json.Unmarshal(data, &rec) == nil
+		return nil
+	})
+	return rec, found
+}
+
+// Uploaded 上传完成后更新记录并追加上传历史，覆盖已有文件时保留首次上传的时间和之前的历史。
+// 调用前需先保存文件的摘要记录
+func (d *DB) Uploaded(fullPath string, up Upload) error {
+	rel, err := d.rel(fullPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+	if up.Time.IsZero() {
+		up.Time = time.Now()
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		var rec Record
+		if data := b.Get([]byte(rel)); data != nil {
+			json.Unmarshal(data, &rec)
+		}
+		if rec.Created.IsZero() {
+			rec.Created = up.Time
+		}
+		rec.Path = rel
+		rec.Size = info.Size()
+		rec.ModTime = info.ModTime()
+		rec.SHA256 = d.sha256(fullPath, info)
+		rec.Uploader = up.Uploader
+		rec.History = append(rec.History, up)
+		if len(rec.History) > maxHistory {
+			rec.History = rec.History[len(rec.History)-maxHistory:]
+		}
+		return put(b, rec)
+	})
+}
+
+// Remove 文件或目录删除后删除对应的记录
+func (d *DB) Remove(fullPath string, isDir bool) error {
+	rel, err := d.rel(fullPath)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		if err := b.Delete([]byte(rel)); err != nil || !isDir {
+			return err
+		}
+		c := b.Cursor()
+		prefix := []byte(rel + "/")
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rename 文件或目录移动后更新记录中的路径
+func (d *DB) Rename(oldPath, newPath string, isDir bool) error {
+	from, err := d.rel(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := d.rel(newPath)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		var moved []Record
+		if data := b.Get([]byte(from)); data != nil {
+			var rec Record
+			if json.Unmarshal(data, &rec) == nil {
+				rec.Path = to
+				moved = append(moved, rec)
+			}
+			if err := b.Delete([]byte(from)); err != nil {
+				return err
+			}
+		}
+		if isDir {
+			c := b.Cursor()
+			prefix := []byte(from + "/")
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Seek(prefix) {
+				var rec Record
+				if json.Unmarshal(v, &rec) == nil {
+					rec.Path = to + strings.TrimPrefix(string(k), from)
+					moved = append(moved, rec)
+				}
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		for _, rec := range moved {
+			if err := put(b, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reconcile 与磁盘上的文件核对：补充缺少的记录，更新大小或修改时间已变化的记录，删除已不存在的文件的记录
+func (d *DB) Reconcile() (added, updated, removed int, err error) {
+	walkStart := time.Now()
+	onDisk := make(map[string]os.FileInfo)
+	err = filepath.WalkDir(d.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == d.root {
+				return err
+			}
+			// 无法读取的目录或文件跳过，不影响其他文件
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if entry.Name() == storage.MetaDirName && filepath.Dir(path) == d.root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := d.rel(path)
+		if err != nil {
+			return nil
+		}
+		onDisk[rel] = info
+		return nil
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	now := time.Now()
+	err = d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+
+		// 先删除已不存在的文件，遍历时不能修改，记下后统一删除。
+		// 遍历磁盘之后才上传的文件以实时更新的记录为准
+		var stale [][]byte
+		existing := make(map[string]Record)
+		err := b.ForEach(func(k, v []byte) error {
+			var rec Record
+			if json.Unmarshal(v, &rec) != nil {
+				stale = append(stale, append([]byte(nil), k...))
+				return nil
+			}
+			if rec.updated().After(walkStart) {
+				delete(onDisk, string(k))
+				return nil
+			}
+			if _, ok := onDisk[string(k)]; !ok {
+				stale = append(stale, append([]byte(nil), k...))
+				return nil
+			}
+			existing[string(k)] = rec
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(stale)
+
+		for rel, info := range onDisk {
+			rec, ok := existing[rel]
+			if ok && rec.Size == info.Size() && rec.ModTime.Equal(info.ModTime()) {
+				continue
+			}
+			if !ok {
+				rec = Record{Path: rel, Created: now}
+				added++
+			} else {
+				updated++
+			}
+			rec.Size = info.Size()
+			rec.ModTime = info.ModTime()
+			// 文件在服务器外被修改后原摘要失效，有有效的摘要记录时沿用
+			rec.SHA256 = d.sha256(filepath.Join(d.root, filepath.FromSlash(rel)), info)
+			if err := put(b, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return added, updated, removed, err
+}
+
+// sha256 从摘要记录读取文件的 SHA-256，摘要记录不存在或已过期时返回空字符串
+func (d *DB) sha256(fullPath string, info os.FileInfo) string {
+	sums, _ := checksum.Load(d.root, fullPath, info)
+	return sums.SHA256
+}
+
+// updated 最近一次由上传写入记录的时间
+func (rec *Record) updated() time.Time {
+	if n := len(rec.History); n > 0 {
+		return rec.History[n-1].Time
+	}
+	return rec.Created
+}
+
+func put(b *bolt.Bucket, rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(rec.Path), data)
+}
+
+// ReconcileAll 依次核对所有存储根目录，在启动时于后台调用
+func ReconcileAll(roots []string) {
+	for _, root := range roots {
+		startTime := time.Now()
+		d, err := For(root)
+		if err != nil {
+			log.Printf("[FILEDB] 警告: %v", err)
+			continue
+		}
+		added, updated, removed, err := d.Reconcile()
+		if err != nil {
+			log.Printf("[FILEDB] 警告: 无法核对文件记录 %s - %v", root, err)
+			continue
+		}
+		log.Printf("[FILEDB] 已核对 %s - 新增: %d, 更新: %d, 删除: %d, 耗时: %v",
+			root, added, updated, removed, time.Since(startTime))
+	}
+}
@@ -0,0 +1,104 @@
+package filedb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/models"
+)
+
+// openDB 在临时目录中打开文件数据库
+func openDB(t *testing.T) *DB {
+	t.Helper()
+	root := t.TempDir()
+	d, err := For(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dbsMu.Lock()
+		delete(dbs, root)
+		dbsMu.Unlock()
+		d.db.Close()
+	})
+	return d
+}
+
+func TestUploadedRecord(t *testing.T) {
+	d := openDB(t)
+	fullPath := filepath.Join(d.root, "a.txt")
+	const sum = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	if err := os.WriteFile(fullPath, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checksum.Save(d.root, fullPath, models.Checksums{SHA256: sum}); err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := d.Uploaded(fullPath, Upload{Uploader: "alice", Via: "web", Time: first}); err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := d.Get(fullPath)
+	if !ok || rec.Path != "a.txt" || rec.Size != 11 || rec.SHA256 != sum || rec.Uploader != "alice" || !rec.Created.Equal(first) {
+		t.Fatalf("record = %+v, %v", rec, ok)
+	}
+
+	// 覆盖上传：保留首次上传时间，摘要记录已失效时摘要为空
+	if err := os.WriteFile(fullPath, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Uploaded(fullPath, Upload{Uploader: "bob", Via: "sftp", Time: first.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ = d.Get(fullPath)
+	if !rec.Created.Equal(first) || rec.Uploader != "bob" || rec.SHA256 != "" || len(rec.History) != 2 {
+		t.Errorf("record after overwrite = %+v", rec)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	d := openDB(t)
+	a := filepath.Join(d.root, "a.txt")
+	b := filepath.Join(d.root, "sub", "b.txt")
+	os.MkdirAll(filepath.Dir(b), 0755)
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("hello world"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const sum = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if err := checksum.Save(d.root, a, models.Checksums{SHA256: sum}); err != nil {
+		t.Fatal(err)
+	}
+
+	added, updated, removed, err := d.Reconcile()
+	if err != nil || added != 2 || updated != 0 || removed != 0 {
+		t.Fatalf("Reconcile() = %d, %d, %d, %v", added, updated, removed, err)
+	}
+	if rec, _ := d.Get(a); rec.SHA256 != sum {
+		t.Errorf("a.txt sha256 = %q, want %q", rec.SHA256, sum)
+	}
+	if rec, _ := d.Get(b); rec.SHA256 != "" || rec.Path != "sub/b.txt" {
+		t.Errorf("b.txt record = %+v", rec)
+	}
+
+	// 在服务器外修改和删除文件
+	if err := os.WriteFile(a, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(b)
+	added, updated, removed, err = d.Reconcile()
+	if err != nil || added != 0 || updated != 1 || removed != 1 {
+		t.Fatalf("Reconcile() = %d, %d, %d, %v", added, updated, removed, err)
+	}
+	if rec, _ := d.Get(a); rec.SHA256 != "" || rec.Size != 7 {
+		t.Errorf("a.txt record after change = %+v", rec)
+	}
+	if _, ok := d.Get(b); ok {
+		t.Error("b.txt record not removed")
+	}
+}
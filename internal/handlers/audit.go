@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/config"
+	"fileSystem/internal/models"
+	"fileSystem/internal/utils"
+)
+
+// 查询接口默认和最多返回的记录条数
+const (
+	defaultAuditLimit = 500
+	maxAuditLimit     = 10000
+)
+
+// AuditLog 查询审计日志，按时间从新到旧返回
+// 参数：from、to（RFC3339 时间或 2006-01-02 日期）、user（操作者或 IP）、path（路径及其下的文件）、action、limit
+func AuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.Limit = defaultAuditLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			utils.SendError(w, "无效的 limit 参数", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(limit, maxAuditLimit)
+	}
+
+	entries, err := audit.Query(filter)
+	if err != nil {
+		log.Printf("[AUDIT] 错误: 无法查询审计日志 - %v", err)
+		utils.SendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	log.Printf("[AUDIT] 查询审计日志 - 返回 %d 条, 客户端IP: %s", len(entries), r.RemoteAddr)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// ExportAuditLog 按与 AuditLog 相同的条件导出全部匹配的记录为 CSV
+func ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	entries, err := audit.Query(filter)
+	if err != nil {
+		log.Printf("[AUDIT] 错误: 无法导出审计日志 - %v", err)
+		utils.SendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", utils.ContentDisposition("attachment", filename))
+	// UTF-8 BOM，便于 Excel 正确识别中文
+	w.Write([]byte("\xef\xbb\xbf"))
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "actor", "ip", "via", "action", "path", "target",
+		"bytes_in", "bytes_out", "result", "status", "error", "duration_ms"})
+	for _, e := range entries {
+		status := ""
+		if e.Status != 0 {
+			status = strconv.Itoa(e.Status)
+		}
+		cw.Write([]string{
+			e.Time.Format(time.RFC3339Nano), csvText(e.Actor), e.IP, e.Via, e.Action, csvText(e.Path), csvText(e.Target),
+			strconv.FormatInt(e.BytesIn, 10), strconv.FormatInt(e.BytesOut, 10),
+			e.Result, status, csvText(e.Error), strconv.FormatInt(e.DurationMs, 10),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("[AUDIT] 错误: 导出审计日志中断 - %v", err)
+		return
+	}
+	log.Printf("[AUDIT] 导出审计日志 - %d 条, 客户端IP: %s", len(entries), r.RemoteAddr)
+}
+
+// csvText 处理客户端可控的文本单元格：以 = + - @ 制表符或回车开头的内容在 Excel 中会被当作公式执行，
+// 前面加上 ' 使其按文本显示
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// auditFilter 校验访问令牌并解析查询条件，失败时已写入错误响应
+func auditFilter(w http.ResponseWriter, r *http.Request) (audit.Filter, bool) {
+	var filter audit.Filter
+	if !audit.Enabled() {
+		utils.SendError(w, "审计日志未启用", http.StatusNotFound)
+		return filter, false
+	}
+	// 审计记录包含所有客户端 IP、路径和分享令牌，未配置令牌时不开放
+	want := config.AuditSettings().AdminToken
+	if want == "" {
+		log.Printf("[AUDIT] 错误: 未配置 admin_token，拒绝查询, 客户端IP: %s", r.RemoteAddr)
+		utils.SendError(w, "未配置审计日志访问令牌", http.StatusForbidden)
+		return filter, false
+	}
+	if !checkAuditToken(r, want) {
+		log.Printf("[AUDIT] 错误: 访问令牌无效, 客户端IP: %s", r.RemoteAddr)
+		utils.SendError(w, "访问令牌无效", http.StatusUnauthorized)
+		return filter, false
+	}
+
+	query := r.URL.Query()
+	var err error
+	if filter.From, err = parseAuditTime(query.Get("from"), false); err != nil {
+		utils.SendError(w, "无效的 from 参数", http.StatusBadRequest)
+		return filter, false
+	}
+	if filter.To, err = parseAuditTime(query.Get("to"), true); err != nil {
+		utils.SendError(w, "无效的 to 参数", http.StatusBadRequest)
+		return filter, false
+	}
+	filter.Actor = query.Get("user")
+	filter.Path = query.Get("path")
+	filter.Action = query.Get("action")
+	return filter, true
+}
+
+// checkAuditToken 校验 Authorization: Bearer <token> 请求头或查询参数 token
+func checkAuditToken(r *http.Request, want string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		got = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// parseAuditTime 解析 RFC3339 时间或本地日期，作为结束时间的日期包含当天
+func parseAuditTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/config"
+)
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"docs/a.txt", "docs/a.txt"},
+		{"=HYPERLINK(\"http://x\",\"y\")", "'=HYPERLINK(\"http://x\",\"y\")"},
+		{"+1", "'+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=b", "a=b"},
+		{"中文=1", "中文=1"},
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.in); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExportAuditLogEscapesFormulas(t *testing.T) {
+	useTempStorage(t)
+	config.Cfg.Audit = &config.AuditConfig{AdminToken: "adm"}
+	if err := audit.Init(t.TempDir(), 1<<20, 1); err != nil {
+		t.Fatal(err)
+	}
+	audit.Record(audit.Entry{
+		Actor: "@evil", IP: "10.0.0.1", Via: "web", Action: "upload",
+		Path: "=HYPERLINK(\"http://x\",\"click\")", Target: "+cmd", Error: "-1",
+	})
+
+	r := httptest.NewRequest("GET", "/api/audit/export", nil)
+	r.Header.Set("Authorization", "Bearer adm")
+	w := httptest.NewRecorder()
+	ExportAuditLog(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\xef\xbb\xbf"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row []string
+	for _, r := range rows[1:] {
+		if strings.Contains(r[5], "HYPERLINK") {
+			row = r
+		}
+	}
+	if row == nil {
+		t.Fatalf("entry not exported: %v", rows)
+	}
+	for i, want := range map[int]string{1: "'@evil", 5: "'=HYPERLINK(\"http://x\",\"click\")", 6: "'+cmd", 11: "'-1"} {
+		if row[i] != want {
+			t.Errorf("column %s = %q, want %q", rows[0][i], row[i], want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxEditSize 在线编辑允许的最大文件大小
+const MaxEditSize = 1 << 20
+
+// contentMu 保证版本检查和写入之间不会插入其他编辑请求
+var contentMu sync.Mutex
+
+// SaveContent 保存在线编辑的文本内容，请求体为文件的完整内容
+//
+// 乐观并发控制：客户端通过 If-Match 提供加载时的 ETag，或通过 If-Unmodified-Since 提供加载时的修改时间，
+// 文件在此之后被修改过时返回 409。文件已存在时必须提供其中之一，新建文件时可以省略。
+func SaveContent(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	filePath := mux.Vars(r)["filename"]
+	log.Printf("[EDIT] 请求开始 - 文件路径: %s, If-Match: %s, If-Unmodified-Since: %s, 客户端IP: %s",
+		filePath, r.Header.Get("If-Match"), r.Header.Get("If-Unmodified-Since"), r.RemoteAddr)
+
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.Virtual || target.IsRoot() {
+		log.Printf("[EDIT] 错误: 无效的文件路径 - filePath=%s, 错误: %v", filePath, err)
+		utils.SendError(w, "无效的文件路径", http.StatusBadRequest)
+		return
+	}
+	if err := target.CheckWritable(); err != nil {
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxEditSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.SendError(w, "文件超过在线编辑的大小限制（1 MB）", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("[EDIT] 错误: 无法读取请求内容 - %v", err)
+		utils.SendError(w, "无法读取请求内容", http.StatusBadRequest)
+		return
+	}
+
+	contentMu.Lock()
+	defer contentMu.Unlock()
+
+	fullPath := target.FullPath
+	info, err := os.Stat(fullPath)
+	switch {
+	case err == nil && info.IsDir():
+		utils.SendError(w, "不能编辑目录", http.StatusBadRequest)
+		return
+	case err == nil:
+		if status, msg := checkUnmodified(r, target, info); status != 0 {
+			log.Printf("[EDIT] 拒绝保存 %s - %s", fullPath, msg)
+			utils.SendError(w, msg, status)
+			return
+		}
+	case os.IsNotExist(err):
+		if r.Header.Get("If-Match") != "" || r.Header.Get("If-Unmodified-Since") != "" {
+			utils.SendError(w, "文件已被删除", http.StatusConflict)
+			return
+		}
+		if parent, err := os.Stat(filepath.Dir(fullPath)); err != nil || !parent.IsDir() {
+			utils.SendError(w, "目录不存在", http.StatusNotFound)
+			return
+		}
+	default:
+		log.Printf("[EDIT] 错误: 无法获取文件信息 - %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法访问文件", http.StatusInternalServerError)
+		return
+	}
+
+	dst, err := createUploadFile(target, fullPath)
+	if err != nil {
+		log.Printf("[EDIT] 错误: 无法创建文件 %s - %v", fullPath, err)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+	dst.origin = uploadOrigin(r, "edit")
+	if _, err := dst.Write(content); err != nil {
+		dst.Abort()
+		log.Printf("[EDIT] 错误: 文件写入失败 - %s, 错误: %v", fullPath, err)
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			utils.SendError(w, err.Error(), storageErrorStatus(err))
+			return
+		}
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+	sums := dst.Checksums()
+	if err := dst.Commit(); err != nil {
+		log.Printf("[EDIT] 错误: 无法完成文件保存 - %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+
+	info, err = os.Stat(fullPath)
+	if err != nil {
+		log.Printf("[EDIT] 错误: 无法获取文件信息 - %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法访问文件", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", checksum.ETag(sums, info))
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	log.Printf("[EDIT] 成功: 已保存 %s, 大小: %s, 耗时: %v", fullPath, utils.FormatSize(info.Size()), time.Since(startTime))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: "已保存",
+		Data: models.FileInfo{
+			Name:      info.Name(),
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+			Extension: strings.TrimPrefix(filepath.Ext(info.Name()), "."),
+			Path:      strings.Trim(filePath, "/"),
+			MimeType:  mimetype.Detect(fullPath, info),
+			SHA256:    sums.SHA256,
+		},
+	})
+}
+
+// checkUnmodified 检查已有文件是否与客户端加载时一致，返回非零状态码表示拒绝
+func checkUnmodified(r *http.Request, target *storage.Target, info os.FileInfo) (int, string) {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		sums, _ := checksum.Load(target.Root, target.FullPath, info)
+		if !etagMatches(ifMatch, checksum.ETag(sums, info)) {
+			return http.StatusConflict, "文件已被修改，请重新加载后再编辑"
+		}
+		return 0, ""
+	}
+	if since := r.Header.Get("If-Unmodified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		if err != nil {
+			return http.StatusBadRequest, "无效的 If-Unmodified-Since"
+		}
+		// HTTP 时间只精确到秒
+		if info.ModTime().Truncate(time.Second).After(t) {
+			return http.StatusConflict, "文件已被修改，请重新加载后再编辑"
+		}
+		return 0, ""
+	}
+	return http.StatusPreconditionRequired, "覆盖已有文件需要提供 If-Match 或 If-Unmodified-Since"
+}
+
+// etagMatches 按弱比较判断 If-Match 中是否包含当前 ETag
+func etagMatches(header, current string) bool {
+	current = strings.TrimPrefix(current, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/filedb"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// FileDetails 返回文件的详情：上传者、上传时间和最近的上传历史
+func FileDetails(w http.ResponseWriter, r *http.Request) {
+	filePath := mux.Vars(r)["filename"]
+	log.Printf("[DETAILS] 请求开始 - 文件路径: %s, 客户端IP: %s", filePath, r.RemoteAddr)
+
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.IsRoot() {
+		log.Printf("[DETAILS] 错误: 无效的路径 - path=%s, 错误: %v", filePath, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil {
+		log.Printf("[DETAILS] 错误: 文件不存在 %s", target.FullPath)
+		utils.SendError(w, "文件不存在", http.StatusNotFound)
+		return
+	}
+	if info.IsDir() {
+		utils.SendError(w, "只能查看文件的详情", http.StatusBadRequest)
+		return
+	}
+
+	details := models.FileDetails{
+		Name:     info.Name(),
+		Path:     filePath,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		MimeType: mimetype.Detect(target.FullPath, info),
+		Uploads:  []models.UploadInfo{},
+	}
+	if sums, ok := checksum.Load(target.Root, target.FullPath, info); ok {
+		details.SHA256 = sums.SHA256
+	}
+
+	db, err := filedb.For(target.Root)
+	if err != nil {
+		log.Printf("[DETAILS] 警告: %v", err)
+	} else if rec, ok := db.Get(target.FullPath); ok {
+		details.Uploader = rec.Uploader
+		details.Created = &rec.Created
+		for i := len(rec.History) - 1; i >= 0; i-- {
+			up := rec.History[i]
+			details.Uploads = append(details.Uploads, models.UploadInfo{
+				Uploader:  up.Uploader,
+				IP:        up.IP,
+				UserAgent: up.UserAgent,
+				Via:       up.Via,
+				Time:      up.Time,
+				Size:      up.Size,
+				Duration:  up.Duration.Round(time.Microsecond).String(),
+				Speed:     up.Speed,
+				SpeedText: utils.FormatSpeed(up.Speed),
+			})
+		}
+	}
+
+	log.Printf("[DETAILS] 成功: %s - 上传记录: %d 条", target.FullPath, len(details.Uploads))
+	utils.SendJSON(w, models.Response{Success: true, Data: details})
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/models"
+	"fileSystem/internal/share"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// createDropRequest 创建上传链接的请求体
+type createDropRequest struct {
+	Path         string `json:"path"`
+	ExpiresIn    int64  `json:"expiresIn"`    // 有效期（秒），0 表示永久有效
+	MaxFileSize  int64  `json:"maxFileSize"`  // 单个文件大小上限（字节），0 表示不限制
+	MaxTotalSize int64  `json:"maxTotalSize"` // 累计上传大小上限（字节），0 表示不限制
+}
+
+// dropInfo 将上传链接转换为响应数据
+func dropInfo(d *share.Drop) models.DropInfo {
+	info := models.DropInfo{
+		Token:        d.Token,
+		Path:         d.Path,
+		Name:         path.Base(d.Path),
+		CreatedAt:    d.CreatedAt,
+		ExpiresAt:    d.ExpiresAt,
+		MaxFileSize:  d.MaxFileSize,
+		MaxTotalSize: d.MaxTotalSize,
+		Uploaded:     d.Uploaded,
+		Files:        d.Files,
+		URL:          rootPrefix() + "/d/" + d.Token,
+	}
+	if d.Path == "" {
+		info.Name = "根目录"
+	}
+	if err := d.Usable(time.Now()); err != nil {
+		info.Status = err.Error()
+	}
+	return info
+}
+
+// CreateDrop 创建上传链接
+func CreateDrop(w http.ResponseWriter, r *http.Request) {
+	var req createDropRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[DROP] 错误: 无法解析请求 - %v", err)
+		utils.SendError(w, "无效的请求", http.StatusBadRequest)
+		return
+	}
+	log.Printf("[DROP] 创建上传链接 - 路径: %s, 有效期: %d 秒, 单文件上限: %d, 总容量: %d, 客户端IP: %s",
+		req.Path, req.ExpiresIn, req.MaxFileSize, req.MaxTotalSize, r.RemoteAddr)
+	audit.SetPath(r, req.Path, "")
+
+	if req.ExpiresIn < 0 || req.MaxFileSize < 0 || req.MaxTotalSize < 0 {
+		utils.SendError(w, "有效期和大小限制不能为负数", http.StatusBadRequest)
+		return
+	}
+
+	target, err := storage.Resolve(req.Path)
+	if err != nil {
+		log.Printf("[DROP] 错误: 无效的路径 - path=%s, 错误: %v", req.Path, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+	if err := target.CheckWritable(); err != nil {
+		log.Printf("[DROP] 错误: 目标位置不可写 - path=%s, 错误: %v", req.Path, err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil || !info.IsDir() {
+		log.Printf("[DROP] 错误: 目录不存在 - %s, 错误: %v", target.FullPath, err)
+		utils.SendError(w, "目录不存在", http.StatusNotFound)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		expiresAt = &t
+	}
+
+	d, err := share.CreateDrop(strings.Trim(filepath.ToSlash(req.Path), "/"), expiresAt, req.MaxFileSize, req.MaxTotalSize)
+	if err != nil {
+		log.Printf("[DROP] 错误: 无法创建上传链接 - %v", err)
+		utils.SendError(w, "无法创建上传链接", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[DROP] 成功: 已创建上传链接 %s -> %s", d.Token, d.Path)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: "上传链接已创建",
+		Data:    dropInfo(d),
+	})
+}
+
+// ListDrops 列出所有上传链接
+func ListDrops(w http.ResponseWriter, r *http.Request) {
+	list := share.ListDrops()
+	infos := make([]models.DropInfo, 0, len(list))
+	for i := range list {
+		infos = append(infos, dropInfo(&list[i]))
+	}
+	log.Printf("[DROP] 返回 %d 个上传链接", len(infos))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    infos,
+	})
+}
+
+// RevokeDrop 撤销上传链接
+func RevokeDrop(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if err := share.RevokeDrop(token); err != nil {
+		log.Printf("[DROP] 错误: 无法撤销上传链接 %s - %v", token, err)
+		if errors.Is(err, share.ErrNotFound) {
+			utils.SendError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		utils.SendError(w, "无法撤销上传链接", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[DROP] 成功: 已撤销上传链接 %s", token)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: "上传链接已撤销",
+	})
+}
+
+// ServeDropPage 返回上传页面
+func ServeDropPage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, r, "drop.html", "[DROP]")
+}
+
+// openDrop 校验公开请求中的上传令牌
+func openDrop(w http.ResponseWriter, r *http.Request) (*share.Drop, bool) {
+	token := mux.Vars(r)["token"]
+	d, err := share.GetDrop(token)
+	if err == nil {
+		err = d.Usable(time.Now())
+	}
+	if err != nil {
+		log.Printf("[DROP] 错误: 上传链接不可用 %s - %v, 客户端IP: %s", token, err, r.RemoteAddr)
+		status := http.StatusGone
+		if errors.Is(err, share.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.SendError(w, err.Error(), status)
+		return nil, false
+	}
+	return d, true
+}
+
+// DropInfo 返回上传链接的限制信息（不包含目录内容）
+func DropInfo(w http.ResponseWriter, r *http.Request) {
+	d, ok := openDrop(w, r)
+	if !ok {
+		return
+	}
+	info := dropInfo(d)
+	info.Path = ""
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    info,
+	})
+}
+
+// DropUpload 通过上传链接上传文件，同名文件自动重命名，不会覆盖已有文件
+func DropUpload(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	d, ok := openDrop(w, r)
+	if !ok {
+		return
+	}
+	log.Printf("[DROP] 上传请求 - 令牌: %s, 客户端IP: %s, User-Agent: %s", d.Token, r.RemoteAddr, r.UserAgent())
+	audit.SetPath(r, d.Path, "")
+
+	target, err := validateAndPreparePath(d.Path)
+	if err != nil {
+		log.Printf("[DROP] 错误: 路径验证失败 - %v", err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		log.Printf("[DROP] 错误: 无法创建 MultipartReader - %v", err)
+		utils.SendError(w, "无法解析上传请求", http.StatusBadRequest)
+		return
+	}
+	part, err := reader.NextPart()
+	for err == nil && part.FileName() == "" {
+		part.Close()
+		part, err = reader.NextPart()
+	}
+	if err != nil {
+		log.Printf("[DROP] 错误: 无法读取文件 part - %v", err)
+		utils.SendError(w, "无法读取文件数据", http.StatusBadRequest)
+		return
+	}
+	defer part.Close()
+
+	filename := part.FileName()
+	if !validFilename(filename) {
+		log.Printf("[DROP] 错误: 无效的文件名 - filename=%s", filename)
+		utils.SendError(w, "无效的文件名", http.StatusBadRequest)
+		return
+	}
+
+	fullPath, err := reservePath(target.FullPath, filepath.Base(filename))
+	if err != nil {
+		log.Printf("[DROP] 错误: 无法创建文件 %s - %v", filename, err)
+		utils.SendError(w, "无法创建文件", http.StatusInternalServerError)
+		return
+	}
+	audit.SetPath(r, d.Path+"/"+filepath.Base(fullPath), "")
+	dst, err := createUploadFile(target, fullPath)
+	if err != nil {
+		os.Remove(fullPath)
+		log.Printf("[DROP] 错误: 无法创建文件 %s - %v", fullPath, err)
+		utils.SendError(w, "无法创建文件", http.StatusInternalServerError)
+		return
+	}
+	dst.origin = uploadOrigin(r, "drop")
+	defer dst.Close()
+
+	var src io.Reader = part
+	limit, limited := d.Limit()
+	if limited {
+		src = io.LimitReader(part, limit+1)
+	}
+
+	speedTracker := utils.NewSpeedTracker(dst)
+	bytesWritten, err := io.Copy(speedTracker, src)
+	if err == nil && limited && bytesWritten > limit {
+		err = share.ErrFileTooLarge
+	}
+	if err == nil {
+		err = share.RecordDropUpload(d.Token, bytesWritten)
+	}
+	if err != nil {
+		log.Printf("[DROP] 错误: 文件保存失败 - 文件: %s, 已写入: %d 字节, 错误: %v", fullPath, bytesWritten, err)
+		dst.Abort()
+		os.Remove(fullPath)
+		switch {
+		case errors.Is(err, share.ErrFileTooLarge), errors.Is(err, share.ErrDropFull):
+			utils.SendError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, storage.ErrQuotaExceeded):
+			utils.SendError(w, err.Error(), storageErrorStatus(err))
+		default:
+			utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := dst.Commit(); err != nil {
+		os.Remove(fullPath)
+		log.Printf("[DROP] 错误: 无法完成文件保存 - 文件: %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+
+	duration := time.Since(startTime)
+	avgSpeed := speedTracker.GetAverageSpeed()
+	savedName := filepath.Base(fullPath)
+	log.Printf("[DROP] 成功: 文件 %s 已通过上传链接 %s 保存, 大小: %s, 耗时: %v",
+		savedName, d.Token, utils.FormatSize(bytesWritten), duration)
+
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: fmt.Sprintf("文件 %s 上传成功", savedName),
+		Speed: &models.SpeedInfo{
+			AverageSpeed: avgSpeed,
+			CurrentSpeed: speedTracker.GetSpeed(),
+			TotalBytes:   bytesWritten,
+			Duration:     duration.String(),
+			SpeedText:    utils.FormatSpeed(avgSpeed),
+		},
+	})
+}
+
+// reservePath 以独占方式在 dir 下创建空文件占用文件名，已存在时在文件名后追加序号，
+// 并发上传同名文件时各自得到不同的文件名。上传完成时覆盖占位文件，失败时由调用方删除
+func reservePath(dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		// 占位文件必须是 dir 下的直接子项，不能是 dir 本身或其他目录中的文件
+		if filepath.Dir(candidate) != dir || !storage.Within(dir, candidate) {
+			return "", storage.ErrInvalidPath
+		}
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return candidate, f.Close()
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		candidate = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReservePath(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := reservePath(dir, "a.txt")
+	if err != nil || first != filepath.Join(dir, "a.txt") {
+		t.Fatalf("reservePath() = %q, %v", first, err)
+	}
+	second, err := reservePath(dir, "a.txt")
+	if err != nil || second != filepath.Join(dir, "a (1).txt") {
+		t.Fatalf("reservePath() = %q, %v", second, err)
+	}
+
+	// 不能占用目录本身或目录之外的文件名
+	for _, name := range []string{".", "", "../a.txt", "sub/a.txt"} {
+		if got, err := reservePath(dir, name); err == nil {
+			t.Errorf("reservePath(%q) = %q, want error", name, got)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dir))
+	for _, e := range entries {
+		if e.Name() != filepath.Base(dir) {
+			t.Errorf("file created outside dir: %s", e.Name())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/config"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/storage"
+
+	"golang.org/x/net/webdav"
+)
+
+// entryInfo 附带存储位置的文件信息，挂载点根目录使用挂载点名称，ETag 与下载接口一致
+type entryInfo struct {
+	os.FileInfo
+	name     string
+	root     string
+	fullPath string
+}
+
+func newEntryInfo(target *storage.Target, fullPath string, info os.FileInfo) *entryInfo {
+	e := &entryInfo{FileInfo: info, root: target.Root, fullPath: fullPath}
+	if target.Mount != nil && fullPath == target.Root {
+		e.name = target.Mount.Name
+	}
+	return e
+}
+
+func (e *entryInfo) Name() string {
+	if e.name != "" {
+		return e.name
+	}
+	return e.FileInfo.Name()
+}
+
+// ETag 实现 webdav.ETager 接口
+func (e *entryInfo) ETag(ctx context.Context) (string, error) {
+	if e.IsDir() {
+		return "", webdav.ErrNotImplemented
+	}
+	sums, _ := checksum.Load(e.root, e.fullPath, e.FileInfo)
+	return checksum.ETag(sums, e.FileInfo), nil
+}
+
+// ContentType 实现 webdav.ContentTyper 接口
+func (e *entryInfo) ContentType(ctx context.Context) (string, error) {
+	if e.IsDir() {
+		return "", webdav.ErrNotImplemented
+	}
+	return mimetype.Header(mimetype.Detect(e.fullPath, e.FileInfo)), nil
+}
+
+// virtualRootInfo 虚拟根目录的文件信息
+type virtualRootInfo struct{}
+
+func (virtualRootInfo) Name() string       { return "/" }
+func (virtualRootInfo) Size() int64        { return 0 }
+func (virtualRootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (virtualRootInfo) ModTime() time.Time { return time.Time{} }
+func (virtualRootInfo) IsDir() bool        { return true }
+func (virtualRootInfo) Sys() interface{}   { return nil }
+
+// mountInfos 返回各挂载点根目录的文件信息，用于列出虚拟根目录
+func mountInfos() []fs.FileInfo {
+	var list []fs.FileInfo
+	for i := range config.Mounts {
+		m := &config.Mounts[i]
+		info, err := os.Stat(m.Path)
+		if err != nil {
+			log.Printf("[LIST] 警告: 无法获取挂载点信息 %s - %v", m.Path, err)
+			continue
+		}
+		list = append(list, &entryInfo{FileInfo: info, name: m.Name, root: m.Path, fullPath: m.Path})
+	}
+	return list
+}
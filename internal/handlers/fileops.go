@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+)
+
+// mkdirRequest 创建目录的请求体
+type mkdirRequest struct {
+	Path string `json:"path"`
+}
+
+// moveRequest 移动/重命名的请求体
+type moveRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Mkdir 创建目录（含中间目录），目录已存在时视为成功
+func Mkdir(w http.ResponseWriter, r *http.Request) {
+	var req mkdirRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[MKDIR] 错误: 无法解析请求 - %v", err)
+		utils.SendError(w, "无效的请求", http.StatusBadRequest)
+		return
+	}
+	log.Printf("[MKDIR] 请求开始 - 路径: %s, 客户端IP: %s", req.Path, r.RemoteAddr)
+	audit.SetPath(r, req.Path, "")
+
+	target, err := storage.Resolve(req.Path)
+	if err != nil || target.IsRoot() {
+		log.Printf("[MKDIR] 错误: 无效的路径 - path=%s, 错误: %v", req.Path, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+	if err := target.CheckWritable(); err != nil {
+		log.Printf("[MKDIR] 错误: 目标位置不可写 - path=%s, 错误: %v", req.Path, err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	if info, err := os.Stat(target.FullPath); err == nil && !info.IsDir() {
+		log.Printf("[MKDIR] 错误: 同名文件已存在 - %s", target.FullPath)
+		utils.SendError(w, "同名文件已存在", http.StatusConflict)
+		return
+	}
+	if err := os.MkdirAll(target.FullPath, 0755); err != nil {
+		log.Printf("[MKDIR] 错误: 无法创建目录 %s - %v", target.FullPath, err)
+		utils.SendError(w, "无法创建目录", http.StatusInternalServerError)
+		return
+	}
+	dirstats.Invalidate(target.FullPath, false)
+
+	log.Printf("[MKDIR] 成功: 已创建目录 %s", target.FullPath)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: fmt.Sprintf("目录 %s 创建成功", filepath.Base(target.FullPath)),
+	})
+}
+
+// MoveFile 在同一存储位置内移动或重命名文件/目录，目标已存在时返回 409
+func MoveFile(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[MOVE] 错误: 无法解析请求 - %v", err)
+		utils.SendError(w, "无效的请求", http.StatusBadRequest)
+		return
+	}
+	log.Printf("[MOVE] 请求开始 - 源路径: %s, 目标路径: %s, 客户端IP: %s", req.From, req.To, r.RemoteAddr)
+	audit.SetPath(r, req.From, req.To)
+
+	src, err := storage.Resolve(req.From)
+	if err != nil || src.IsRoot() {
+		log.Printf("[MOVE] 错误: 无效的源路径 - from=%s, 错误: %v", req.From, err)
+		utils.SendError(w, "无效的源路径", http.StatusBadRequest)
+		return
+	}
+	dst, err := storage.Resolve(req.To)
+	if err != nil || dst.IsRoot() {
+		log.Printf("[MOVE] 错误: 无效的目标路径 - to=%s, 错误: %v", req.To, err)
+		utils.SendError(w, "无效的目标路径", http.StatusBadRequest)
+		return
+	}
+	if src.Root != dst.Root {
+		log.Printf("[MOVE] 错误: 不能跨存储位置移动 - %s -> %s", src.Root, dst.Root)
+		utils.SendError(w, "不能跨存储位置移动", http.StatusBadRequest)
+		return
+	}
+	if err := src.CheckWritable(); err != nil {
+		log.Printf("[MOVE] 错误: 目标位置不可写 - %v", err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	info, err := os.Stat(src.FullPath)
+	if os.IsNotExist(err) {
+		log.Printf("[MOVE] 错误: 文件或目录不存在 - %s", src.FullPath)
+		utils.SendError(w, "文件或目录不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[MOVE] 错误: 无法获取文件信息 - %s, 错误: %v", src.FullPath, err)
+		utils.SendError(w, "无法访问文件", http.StatusInternalServerError)
+		return
+	}
+	if info.IsDir() && storage.Within(src.FullPath, dst.FullPath) {
+		log.Printf("[MOVE] 错误: 不能将目录移动到自身内部 - %s -> %s", src.FullPath, dst.FullPath)
+		utils.SendError(w, "不能将目录移动到自身内部", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Lstat(dst.FullPath); err == nil {
+		log.Printf("[MOVE] 错误: 目标已存在 - %s", dst.FullPath)
+		utils.SendError(w, "目标已存在", http.StatusConflict)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst.FullPath), 0755); err != nil {
+		log.Printf("[MOVE] 错误: 无法创建目录 %s - %v", filepath.Dir(dst.FullPath), err)
+		utils.SendError(w, "无法创建目录", http.StatusInternalServerError)
+		return
+	}
+	if err := renameFile(src, src.FullPath, dst.FullPath, info.IsDir()); err != nil {
+		log.Printf("[MOVE] 错误: 移动失败 - %s -> %s, 错误: %v", src.FullPath, dst.FullPath, err)
+		utils.SendError(w, "无法移动", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[MOVE] 成功: %s -> %s, 耗时: %v", src.FullPath, dst.FullPath, time.Since(startTime))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: fmt.Sprintf("已移动到 %s", req.To),
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -12,8 +13,15 @@ import (
 	"strings"
 	"time"
 
+	"fileSystem/internal/audit"
+	"fileSystem/internal/checksum"
 	"fileSystem/internal/config"
+	"fileSystem/internal/dedup"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/filedb"
+	"fileSystem/internal/mimetype"
 	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
 	"fileSystem/internal/utils"
 
 	"github.com/gorilla/mux"
@@ -28,20 +36,25 @@ func InitHandlers(fs embed.FS) {
 
 // ServeIndex 返回前端页面
 func ServeIndex(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[INDEX] 请求开始 - 方法: %s, 路径: %s, 客户端IP: %s, User-Agent: %s",
-		r.Method, r.URL.Path, r.RemoteAddr, r.UserAgent())
+	servePage(w, r, "index.html", "[INDEX]")
+}
+
+// servePage 返回嵌入的 HTML 页面，并根据根路径改写静态文件路径、注入配置
+func servePage(w http.ResponseWriter, r *http.Request, name, tag string) {
+	log.Printf("%s 请求开始 - 方法: %s, 路径: %s, 客户端IP: %s, User-Agent: %s",
+		tag, r.Method, r.URL.Path, r.RemoteAddr, r.UserAgent())
 
 	// 从嵌入的文件系统读取
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
-		log.Printf("[INDEX] 错误: 无法读取嵌入的静态文件系统 - %v", err)
+		log.Printf("%s 错误: 无法读取嵌入的静态文件系统 - %v", tag, err)
 		http.Error(w, "无法读取前端文件", http.StatusInternalServerError)
 		return
 	}
 
-	indexFile, err := staticFS.Open("index.html")
+	indexFile, err := staticFS.Open(name)
 	if err != nil {
-		log.Printf("[INDEX] 错误: 无法打开 index.html - %v", err)
+		log.Printf("%s 错误: 无法打开 %s - %v", tag, name, err)
 		http.Error(w, "无法读取前端页面", http.StatusInternalServerError)
 		return
 	}
@@ -50,7 +63,7 @@ func ServeIndex(w http.ResponseWriter, r *http.Request) {
 	// 读取 HTML 内容
 	htmlContent, err := io.ReadAll(indexFile)
 	if err != nil {
-		log.Printf("[INDEX] 错误: 无法读取 index.html 内容 - %v", err)
+		log.Printf("%s 错误: 无法读取 %s 内容 - %v", tag, name, err)
 		http.Error(w, "无法读取前端页面", http.StatusInternalServerError)
 		return
 	}
@@ -90,45 +103,56 @@ func ServeIndex(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	bytesWritten, err := w.Write(htmlContent)
 	if err != nil {
-		log.Printf("[INDEX] 错误: 写入响应失败 - %v", err)
+		log.Printf("%s 错误: 写入响应失败 - %v", tag, err)
 		return
 	}
-	log.Printf("[INDEX] 成功: 已返回前端页面, 大小: %d 字节", bytesWritten)
+	log.Printf("%s 成功: 已返回页面 %s, 大小: %d 字节", tag, name, bytesWritten)
 }
 
-// ListFiles 列出所有文件
+// ListFiles 列出目录内容
+//
+// 支持 sort/order 排序（默认按名称升序，目录在前）、name/ext/type 过滤；带 limit 参数时分页返回 models.FileList，
+// 否则返回全部结果的数组。dirStats=true 时目录的大小为后台统计的递归总大小，尚未统计完成的目录标记 statsPending。
 func ListFiles(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 	path := r.URL.Query().Get("path")
-	log.Printf("[LIST] 请求开始 - 方法: %s, 路径参数: %s, 客户端IP: %s, User-Agent: %s",
-		r.Method, path, r.RemoteAddr, r.UserAgent())
+	log.Printf("[LIST] 请求开始 - 方法: %s, 路径参数: %s, 查询: %s, 客户端IP: %s, User-Agent: %s",
+		r.Method, path, r.URL.RawQuery, r.RemoteAddr, r.UserAgent())
 
-	// 验证路径
-	if path != "" {
-		if strings.Contains(path, "..") || strings.HasPrefix(path, "/") {
-			log.Printf("[LIST] 错误: 无效的路径参数 - path=%s (包含 '..' 或以 '/' 开头)", path)
-			utils.SendError(w, "无效的路径", http.StatusBadRequest)
-			return
-		}
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		log.Printf("[LIST] 错误: 无效的查询参数 - %v", err)
+		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// 构建完整路径
-	targetDir := config.UploadDir
-	if path != "" {
-		targetDir = filepath.Join(config.UploadDir, path)
-		absTarget, _ := filepath.Abs(targetDir)
-		absUpload, _ := filepath.Abs(config.UploadDir)
-		log.Printf("[LIST] 路径验证 - 目标目录: %s, 绝对路径: %s, 基础目录: %s",
-			targetDir, absTarget, absUpload)
-		if !strings.HasPrefix(absTarget, absUpload) {
-			log.Printf("[LIST] 错误: 路径遍历攻击尝试 - 目标: %s, 基础: %s", absTarget, absUpload)
-			utils.SendError(w, "无效的路径", http.StatusBadRequest)
-			return
-		}
+	// 解析并验证路径，返回的文件路径不带首尾的 /
+	target, err := storage.Resolve(path)
+	path = strings.Trim(path, "/")
+	if err != nil {
+		log.Printf("[LIST] 错误: 路径解析失败 - path=%s, 错误: %v", path, err)
+		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 挂载点模式下，根目录列出所有挂载点
+	if target.Virtual {
+		mounts, total, next := query.apply(listMounts(query.dirStats))
+		log.Printf("[LIST] 成功: 返回 %d 个挂载点, 耗时: %v", len(mounts), time.Since(startTime))
+		sendFileList(w, query, mounts, total, next)
+		return
 	}
 
+	targetDir := target.FullPath
+	log.Printf("[LIST] 路径验证 - 目标目录: %s, 存储根目录: %s", targetDir, target.Root)
+
 	log.Printf("[LIST] 正在读取目录: %s", targetDir)
 	files, err := os.ReadDir(targetDir)
+	if os.IsNotExist(err) {
+		log.Printf("[LIST] 错误: 目录不存在 %s", targetDir)
+		utils.SendError(w, "目录不存在", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("[LIST] 错误: 无法读取目录 %s - %v", targetDir, err)
 		utils.SendError(w, "无法读取文件列表", http.StatusInternalServerError)
@@ -138,7 +162,13 @@ func ListFiles(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[LIST] 找到 %d 个文件/目录", len(files))
 	var fileList []models.FileInfo
 	skippedCount := 0
+	pending := false
 	for _, file := range files {
+		// 隐藏内部数据目录
+		if target.IsRoot() && file.Name() == storage.MetaDirName {
+			continue
+		}
+
 		info, err := file.Info()
 		if err != nil {
 			log.Printf("[LIST] 警告: 无法获取文件信息 %s - %v", file.Name(), err)
@@ -158,22 +188,124 @@ func ListFiles(w http.ResponseWriter, r *http.Request) {
 			IsDir:     file.IsDir(),
 			Extension: strings.TrimPrefix(filepath.Ext(file.Name()), "."),
 			Path:      relativePath,
+			ReadOnly:  target.Mount != nil && target.Mount.ReadOnly,
+		}
+		if query.dirStats && file.IsDir() && !fillDirStats(&fileInfo, filepath.Join(targetDir, file.Name())) {
+			pending = true
 		}
 		fileList = append(fileList, fileInfo)
 	}
+	// 一次遍历即可得到所有子目录的统计
+	if pending {
+		dirstats.Scan(targetDir)
+	}
 
 	if skippedCount > 0 {
 		log.Printf("[LIST] 警告: 跳过了 %d 个无法读取的文件", skippedCount)
 	}
 
+	// 类型、摘要和文件记录需要读取文件或数据库，只为当前页补充
+	page, total, next := query.apply(fileList)
+	fillTags(target.Root, targetDir, page)
+	db, err := filedb.For(target.Root)
+	if err != nil {
+		log.Printf("[LIST] 警告: %v", err)
+	}
+	for i := range page {
+		if page[i].IsDir {
+			continue
+		}
+		fullPath := filepath.Join(targetDir, page[i].Name)
+		info, err := os.Lstat(fullPath)
+		if err != nil {
+			continue
+		}
+		page[i].MimeType = mimetype.Detect(fullPath, info)
+		if sums, ok := checksum.Load(target.Root, fullPath, info); ok {
+			page[i].SHA256 = sums.SHA256
+			page[i].MD5 = sums.MD5
+			page[i].CRC32C = sums.CRC32C
+		}
+		if db == nil {
+			continue
+		}
+		if rec, ok := db.Get(fullPath); ok {
+			page[i].Uploader = rec.Uploader
+			page[i].Created = &rec.Created
+		}
+	}
+
 	duration := time.Since(startTime)
-	log.Printf("[LIST] 成功: 返回 %d 个文件/目录, 耗时: %v", len(fileList), duration)
+	log.Printf("[LIST] 成功: 返回 %d/%d 个文件/目录, 耗时: %v", len(page), total, duration)
+	sendFileList(w, query, page, total, next)
+}
+
+// sendFileList 分页时返回 models.FileList，否则直接返回数组
+func sendFileList(w http.ResponseWriter, query *listQuery, page []models.FileInfo, total int, next string) {
+	if query.limit == 0 {
+		utils.SendJSON(w, models.Response{Success: true, Data: page})
+		return
+	}
+	if page == nil {
+		page = []models.FileInfo{}
+	}
 	utils.SendJSON(w, models.Response{
 		Success: true,
-		Data:    fileList,
+		Data:    models.FileList{Files: page, Total: total, NextCursor: next},
 	})
 }
 
+// fillDirStats 用后台统计结果填充目录的大小和统计信息，尚未统计时标记为 pending 并返回 false
+func fillDirStats(f *models.FileInfo, dir string) bool {
+	st, ok := dirstats.Lookup(dir)
+	if !ok {
+		f.StatsPending = true
+		return false
+	}
+	f.Size = st.Size
+	f.Stats = &models.DirStats{Files: st.Files, Dirs: st.Dirs, ModTime: st.ModTime}
+	return true
+}
+
+// listMounts 将挂载点作为顶层目录返回，dirStats 时附带各挂载点的递归统计
+func listMounts(dirStats bool) []models.FileInfo {
+	var mounts []models.FileInfo
+	for _, m := range config.Mounts {
+		fileInfo := models.FileInfo{
+			Name:     m.Name,
+			IsDir:    true,
+			Path:     m.Name,
+			Mount:    true,
+			ReadOnly: m.ReadOnly,
+			Quota:    m.Quota,
+		}
+		if info, err := os.Stat(m.Path); err == nil {
+			fileInfo.ModTime = info.ModTime()
+		} else {
+			log.Printf("[LIST] 警告: 无法获取挂载点信息 %s - %v", m.Path, err)
+		}
+		if dirStats {
+			if root, err := filepath.Abs(m.Path); err == nil && !fillDirStats(&fileInfo, root) {
+				dirstats.Scan(root)
+			}
+		}
+		mounts = append(mounts, fileInfo)
+	}
+	return mounts
+}
+
+// storageErrorStatus 将存储错误映射为 HTTP 状态码
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 // UploadFile 上传文件
 func UploadFile(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
@@ -190,20 +322,39 @@ func UploadFile(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// 客户端提供的期望摘要，上传完成后校验
+	expected, err := checksum.ParseExpected(r)
+	if err != nil {
+		log.Printf("[UPLOAD] 错误: 无效的期望摘要 - %v", err)
+		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 客户端指定的修改时间（RFC 3339），用于同步时保留本地文件的修改时间
+	var modTime time.Time
+	if v := r.URL.Query().Get("modTime"); v != "" {
+		modTime, err = time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			log.Printf("[UPLOAD] 错误: 无效的修改时间 - modTime=%s", v)
+			utils.SendError(w, "无效的修改时间", http.StatusBadRequest)
+			return
+		}
+	}
+
 	// 解析 multipart form
-	err := r.ParseMultipartForm(32 << 20)
+	err = r.ParseMultipartForm(32 << 20)
 	if err != nil {
 		log.Printf("[UPLOAD] 标准解析失败，尝试流式处理 - 错误: %v", err)
-		handleStreamUpload(w, r, uploadPath, startTime)
+		handleStreamUpload(w, r, uploadPath, expected, modTime, startTime)
 		return
 	}
 
 	// 标准方式处理（小文件）
-	handleStandardUpload(w, r, uploadPath, startTime)
+	handleStandardUpload(w, r, uploadPath, expected, modTime, startTime)
 }
 
 // 处理流式上传（大文件）
-func handleStreamUpload(w http.ResponseWriter, r *http.Request, uploadPath string, startTime time.Time) {
+func handleStreamUpload(w http.ResponseWriter, r *http.Request, uploadPath string, expected checksum.Expected, modTime, startTime time.Time) {
 	reader, err := r.MultipartReader()
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 无法创建 MultipartReader - %v", err)
@@ -221,28 +372,31 @@ func handleStreamUpload(w http.ResponseWriter, r *http.Request, uploadPath strin
 
 	filename := part.FileName()
 	log.Printf("[UPLOAD] 流式上传 - 文件名: %s, Content-Type: %s", filename, part.Header.Get("Content-Type"))
-	if filename == "" || strings.Contains(filename, "..") || strings.Contains(filename, "/") {
+	audit.SetPath(r, uploadPath+"/"+filename, "")
+	if !validFilename(filename) {
 		log.Printf("[UPLOAD] 错误: 无效的文件名 - filename=%s", filename)
 		utils.SendError(w, "无效的文件名", http.StatusBadRequest)
 		return
 	}
 
-	targetPath, err := validateAndPreparePath(uploadPath)
+	target, err := validateAndPreparePath(uploadPath)
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 路径验证失败 - %v", err)
-		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
 		return
 	}
 
-	fullPath := filepath.Join(targetPath, filename)
+	fullPath := filepath.Join(target.FullPath, filename)
 	log.Printf("[UPLOAD] 正在创建目标文件: %s", fullPath)
-	dst, err := os.Create(fullPath)
+	dst, err := createUploadFile(target, fullPath, expected.Algos()...)
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 无法创建文件 %s - %v", fullPath, err)
 		utils.SendError(w, "无法创建文件", http.StatusInternalServerError)
 		return
 	}
+	dst.origin = uploadOrigin(r, "web")
 	defer dst.Close()
+	dst.modTime = modTime
 
 	// 使用速度跟踪器
 	speedTracker := utils.NewSpeedTracker(dst)
@@ -275,14 +429,35 @@ func handleStreamUpload(w http.ResponseWriter, r *http.Request, uploadPath strin
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 文件写入失败 - 文件: %s, 已写入: %d 字节, 错误: %v",
 			fullPath, bytesWritten, err)
-		os.Remove(dst.Name())
+		dst.Abort()
 		log.Printf("[UPLOAD] 已删除不完整的文件: %s", dst.Name())
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			utils.SendError(w, err.Error(), storageErrorStatus(err))
+			return
+		}
 		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
 		return
 	}
-
+	sums := dst.Checksums()
+	if err := checksum.Verify(sums, expected); err != nil {
+		log.Printf("[UPLOAD] 错误: 摘要校验失败 - 文件: %s, 期望: %v, 实际: %+v", fullPath, expected, sums)
+		dst.Abort()
+		log.Printf("[UPLOAD] 已删除不完整的文件: %s", dst.Name())
+		utils.SendError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	// 上传历史记录与返回给客户端的速度信息一致
 	duration := time.Since(startTime)
 	avgSpeed := speedTracker.GetAverageSpeed()
+	dst.origin.Size = bytesWritten
+	dst.origin.Duration = duration
+	dst.origin.Speed = avgSpeed
+	if err := dst.Commit(); err != nil {
+		log.Printf("[UPLOAD] 错误: 无法完成文件保存 - 文件: %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[UPLOAD] 文件摘要 - SHA-256: %s", sums.SHA256)
 
 	log.Printf("[UPLOAD] 成功: 文件 %s 上传完成, 大小: %s, 耗时: %v, 平均速度: %s",
 		filename, utils.FormatSize(bytesWritten), duration, utils.FormatSpeed(avgSpeed))
@@ -290,6 +465,7 @@ func handleStreamUpload(w http.ResponseWriter, r *http.Request, uploadPath strin
 	utils.SendJSON(w, models.Response{
 		Success: true,
 		Message: fmt.Sprintf("文件 %s 上传成功", filename),
+		Data:    sums,
 		Speed: &models.SpeedInfo{
 			AverageSpeed: avgSpeed,
 			CurrentSpeed: speedTracker.GetSpeed(),
@@ -301,7 +477,7 @@ func handleStreamUpload(w http.ResponseWriter, r *http.Request, uploadPath strin
 }
 
 // 处理标准上传（小文件）
-func handleStandardUpload(w http.ResponseWriter, r *http.Request, uploadPath string, startTime time.Time) {
+func handleStandardUpload(w http.ResponseWriter, r *http.Request, uploadPath string, expected checksum.Expected, modTime, startTime time.Time) {
 	log.Printf("[UPLOAD] 使用标准方式处理（小文件）")
 	file, handler, err := r.FormFile("file")
 	if err != nil {
@@ -314,28 +490,31 @@ func handleStandardUpload(w http.ResponseWriter, r *http.Request, uploadPath str
 	filename := handler.Filename
 	log.Printf("[UPLOAD] 标准上传 - 文件名: %s, 大小: %s, Content-Type: %s",
 		filename, utils.FormatSize(handler.Size), handler.Header.Get("Content-Type"))
-	if filename == "" || strings.Contains(filename, "..") || strings.Contains(filename, "/") {
+	audit.SetPath(r, uploadPath+"/"+filename, "")
+	if !validFilename(filename) {
 		log.Printf("[UPLOAD] 错误: 无效的文件名 - filename=%s", filename)
 		utils.SendError(w, "无效的文件名", http.StatusBadRequest)
 		return
 	}
 
-	targetPath, err := validateAndPreparePath(uploadPath)
+	target, err := validateAndPreparePath(uploadPath)
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 路径验证失败 - %v", err)
-		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
 		return
 	}
 
-	fullPath := filepath.Join(targetPath, filename)
+	fullPath := filepath.Join(target.FullPath, filename)
 	log.Printf("[UPLOAD] 正在创建目标文件: %s", fullPath)
-	dst, err := os.Create(fullPath)
+	dst, err := createUploadFile(target, fullPath, expected.Algos()...)
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 无法创建文件 %s - %v", fullPath, err)
 		utils.SendError(w, "无法创建文件", http.StatusInternalServerError)
 		return
 	}
+	dst.origin = uploadOrigin(r, "web")
 	defer dst.Close()
+	dst.modTime = modTime
 
 	// 使用速度跟踪器
 	speedTracker := utils.NewSpeedTracker(dst)
@@ -345,15 +524,37 @@ func handleStandardUpload(w http.ResponseWriter, r *http.Request, uploadPath str
 	if err != nil {
 		log.Printf("[UPLOAD] 错误: 文件写入失败 - 文件: %s, 已写入: %d 字节, 错误: %v",
 			fullPath, bytesWritten, err)
-		os.Remove(dst.Name())
+		dst.Abort()
 		log.Printf("[UPLOAD] 已删除不完整的文件: %s", dst.Name())
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			utils.SendError(w, err.Error(), storageErrorStatus(err))
+			return
+		}
 		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
 		return
 	}
-
-	fileInfo, _ := os.Stat(fullPath)
+	sums := dst.Checksums()
+	if err := checksum.Verify(sums, expected); err != nil {
+		log.Printf("[UPLOAD] 错误: 摘要校验失败 - 文件: %s, 期望: %v, 实际: %+v", fullPath, expected, sums)
+		dst.Abort()
+		log.Printf("[UPLOAD] 已删除不完整的文件: %s", dst.Name())
+		utils.SendError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	// 上传历史记录与返回给客户端的速度信息一致
 	duration := time.Since(startTime)
 	avgSpeed := speedTracker.GetAverageSpeed()
+	dst.origin.Size = bytesWritten
+	dst.origin.Duration = duration
+	dst.origin.Speed = avgSpeed
+	if err := dst.Commit(); err != nil {
+		log.Printf("[UPLOAD] 错误: 无法完成文件保存 - 文件: %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[UPLOAD] 文件摘要 - SHA-256: %s", sums.SHA256)
+
+	fileInfo, _ := os.Stat(fullPath)
 
 	log.Printf("[UPLOAD] 成功: 文件 %s 上传完成, 大小: %s, 耗时: %v, 平均速度: %s",
 		filename, utils.FormatSize(bytesWritten), duration, utils.FormatSpeed(avgSpeed))
@@ -365,6 +566,7 @@ func handleStandardUpload(w http.ResponseWriter, r *http.Request, uploadPath str
 	utils.SendJSON(w, models.Response{
 		Success: true,
 		Message: fmt.Sprintf("文件 %s 上传成功", filename),
+		Data:    sums,
 		Speed: &models.SpeedInfo{
 			AverageSpeed: avgSpeed,
 			CurrentSpeed: speedTracker.GetSpeed(),
@@ -375,26 +577,139 @@ func handleStandardUpload(w http.ResponseWriter, r *http.Request, uploadPath str
 	})
 }
 
-// validateAndPreparePath 验证并准备路径
-func validateAndPreparePath(uploadPath string) (string, error) {
-	targetPath := config.UploadDir
-	if uploadPath != "" {
-		targetPath = filepath.Join(config.UploadDir, uploadPath)
-		absTarget, _ := filepath.Abs(targetPath)
-		absUpload, _ := filepath.Abs(config.UploadDir)
-		log.Printf("[PATH] 路径验证 - 目标路径: %s, 绝对路径: %s, 基础目录: %s",
-			targetPath, absTarget, absUpload)
-		if !strings.HasPrefix(absTarget, absUpload) {
-			log.Printf("[PATH] 错误: 路径遍历攻击尝试 - 目标: %s, 基础: %s", absTarget, absUpload)
-			return "", fmt.Errorf("无效的路径")
+// InstantUpload 秒传：去重存储中已有相同内容时直接创建文件，无需上传内容
+// 同名文件已存在时返回 409，查询参数 overwrite=true 时覆盖
+func InstantUpload(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	query := r.URL.Query()
+	uploadPath := query.Get("path")
+	filename := query.Get("filename")
+	hash := strings.ToLower(query.Get("sha256"))
+	log.Printf("[UPLOAD] 秒传请求 - 上传路径参数: %s, 文件名: %s, SHA-256: %s, 客户端IP: %s",
+		uploadPath, filename, hash, r.RemoteAddr)
+	audit.SetPath(r, uploadPath+"/"+filename, "")
+
+	if !dedup.Enabled() {
+		log.Printf("[UPLOAD] 错误: 未启用去重存储，无法秒传")
+		utils.SendError(w, "未启用去重存储", http.StatusNotFound)
+		return
+	}
+	if !dedup.ValidHash(hash) {
+		log.Printf("[UPLOAD] 错误: 无效的 SHA-256 - %s", hash)
+		utils.SendError(w, "无效的 SHA-256", http.StatusBadRequest)
+		return
+	}
+	if !validFilename(filename) {
+		log.Printf("[UPLOAD] 错误: 无效的文件名 - filename=%s", filename)
+		utils.SendError(w, "无效的文件名", http.StatusBadRequest)
+		return
+	}
+
+	target, err := validateAndPreparePath(uploadPath)
+	if err != nil {
+		log.Printf("[UPLOAD] 错误: 路径验证失败 - %v", err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	store, err := dedup.For(target.Root)
+	if err != nil {
+		log.Printf("[UPLOAD] 错误: 无法打开去重存储 - %v", err)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+	if !store.Has(hash) {
+		log.Printf("[UPLOAD] 秒传未命中 - SHA-256: %s", hash)
+		utils.SendError(w, dedup.ErrUnknownBlob.Error(), http.StatusNotFound)
+		return
+	}
+
+	fullPath := filepath.Join(target.FullPath, filename)
+	if info, err := os.Lstat(fullPath); err == nil {
+		if info.IsDir() {
+			log.Printf("[UPLOAD] 错误: 同名目录已存在 - %s", fullPath)
+			utils.SendError(w, "同名目录已存在", http.StatusConflict)
+			return
+		}
+		if query.Get("overwrite") != "true" {
+			log.Printf("[UPLOAD] 错误: 同名文件已存在 - %s", fullPath)
+			utils.SendError(w, "同名文件已存在", http.StatusConflict)
+			return
 		}
-		if err := os.MkdirAll(targetPath, 0755); err != nil {
-			log.Printf("[PATH] 错误: 无法创建目录 %s - %v", targetPath, err)
-			return "", fmt.Errorf("无法创建目录")
+	}
+	size, err := store.Size(hash)
+	if err != nil {
+		log.Printf("[UPLOAD] 秒传未命中 - SHA-256: %s, 错误: %v", hash, err)
+		utils.SendError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	quota := target.Reserve(existingSize(fullPath))
+	if err := quota.Extend(size); err != nil {
+		log.Printf("[UPLOAD] 错误: 秒传超出配额 - 文件: %s, 大小: %s, 错误: %v", fullPath, utils.FormatSize(size), err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+	err = store.LinkExisting(hash, fullPath)
+	dirstats.Invalidate(fullPath, false)
+	quota.Release()
+	if err != nil {
+		log.Printf("[UPLOAD] 错误: 秒传失败 - 文件: %s, 错误: %v", fullPath, err)
+		if errors.Is(err, dedup.ErrUnknownBlob) {
+			utils.SendError(w, err.Error(), http.StatusNotFound)
+			return
 		}
-		log.Printf("[PATH] 已创建/确认目录存在: %s", targetPath)
+		utils.SendError(w, "无法保存文件", http.StatusInternalServerError)
+		return
+	}
+
+	sums := models.Checksums{SHA256: hash}
+	if err := checksum.Save(target.Root, fullPath, sums); err != nil {
+		log.Printf("[UPLOAD] 警告: 无法保存文件摘要 %s - %v", fullPath, err)
+	}
+	recordUpload(target.Root, fullPath, uploadOrigin(r, "instant"))
+
+	log.Printf("[UPLOAD] 成功: 文件 %s 秒传完成, 耗时: %v", filename, time.Since(startTime))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: fmt.Sprintf("文件 %s 秒传成功", filename),
+		Data:    sums,
+	})
+}
+
+// validateAndPreparePath 验证并准备路径
+func validateAndPreparePath(uploadPath string) (*storage.Target, error) {
+	target, err := storage.Resolve(uploadPath)
+	if err != nil {
+		log.Printf("[PATH] 错误: 路径解析失败 - path=%s, 错误: %v", uploadPath, err)
+		return nil, err
+	}
+	log.Printf("[PATH] 路径验证 - 目标路径: %s, 存储根目录: %s", target.FullPath, target.Root)
+	if err := target.CheckWritable(); err != nil {
+		log.Printf("[PATH] 错误: 目标位置不可写 - path=%s, 错误: %v", uploadPath, err)
+		return nil, err
 	}
-	return targetPath, nil
+	if err := os.MkdirAll(target.FullPath, 0755); err != nil {
+		log.Printf("[PATH] 错误: 无法创建目录 %s - %v", target.FullPath, err)
+		return nil, fmt.Errorf("无法创建目录")
+	}
+	log.Printf("[PATH] 已创建/确认目录存在: %s", target.FullPath)
+	return target, nil
+}
+
+// copyWithQuota 复制文件内容，超出挂载点配额时返回 storage.ErrQuotaExceeded
+func copyWithQuota(target *storage.Target, dst io.Writer, src io.Reader) (int64, error) {
+	remaining, limited, err := target.Remaining()
+	if err != nil {
+		return 0, err
+	}
+	if !limited {
+		return io.Copy(dst, src)
+	}
+	bytesWritten, err := io.Copy(dst, io.LimitReader(src, remaining+1))
+	if err == nil && bytesWritten > remaining {
+		return bytesWritten, storage.ErrQuotaExceeded
+	}
+	return bytesWritten, err
 }
 
 // DownloadFile 下载文件
@@ -412,20 +727,16 @@ func DownloadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 构建完整路径
-	fullPath := filepath.Join(config.UploadDir, filePath)
-	log.Printf("[DOWNLOAD] 构建完整路径 - 基础目录: %s, 相对路径: %s, 完整路径: %s",
-		config.UploadDir, filePath, fullPath)
-
-	// 确保路径在 config.UploadDir 内
-	absTarget, _ := filepath.Abs(fullPath)
-	absUpload, _ := filepath.Abs(config.UploadDir)
-	log.Printf("[DOWNLOAD] 路径验证 - 绝对目标路径: %s, 绝对基础路径: %s", absTarget, absUpload)
-	if !strings.HasPrefix(absTarget, absUpload) {
-		log.Printf("[DOWNLOAD] 错误: 路径遍历攻击尝试 - 目标: %s, 基础: %s", absTarget, absUpload)
+	// 解析并验证路径
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.Virtual {
+		log.Printf("[DOWNLOAD] 错误: 路径解析失败 - filePath=%s, 错误: %v", filePath, err)
 		utils.SendError(w, "无效的文件路径", http.StatusBadRequest)
 		return
 	}
+	fullPath := target.FullPath
+	log.Printf("[DOWNLOAD] 构建完整路径 - 存储根目录: %s, 相对路径: %s, 完整路径: %s",
+		target.Root, filePath, fullPath)
 
 	// 检查文件是否存在
 	log.Printf("[DOWNLOAD] 正在检查文件是否存在: %s", fullPath)
@@ -462,11 +773,37 @@ func DownloadFile(w http.ResponseWriter, r *http.Request) {
 
 	// 设置响应头
 	filename := filepath.Base(filePath)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	w.Header().Set("Content-Type", "application/octet-stream")
+	disposition := utils.ContentDisposition("attachment", filename)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Type", mimetype.Header(mimetype.Detect(fullPath, info)))
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
-	log.Printf("[DOWNLOAD] 已设置响应头 - Content-Disposition: attachment; filename=%s, 大小: %s",
-		filename, utils.FormatSize(info.Size()))
+
+	// 摘要与 ETag
+	sums, _ := checksum.Load(target.Root, fullPath, info)
+	etag := checksum.ETag(sums, info)
+	w.Header().Set("ETag", etag)
+	if digest := checksum.DigestHeader(sums); digest != "" {
+		w.Header().Set("Digest", digest)
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		log.Printf("[DOWNLOAD] 文件未修改，返回 304 - %s", fullPath)
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+
+	// 断点续传：带 Range 请求头时交给 http.ServeContent 处理（含 If-Range 校验）
+	if r.Header.Get("Range") != "" {
+		log.Printf("[DOWNLOAD] 范围请求 - 文件: %s, Range: %s, If-Range: %s",
+			fullPath, r.Header.Get("Range"), r.Header.Get("If-Range"))
+		w.Header().Del("Content-Length")
+		http.ServeContent(w, r, filename, info.ModTime(), file)
+		return
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	log.Printf("[DOWNLOAD] 已设置响应头 - Content-Disposition: %s, 大小: %s",
+		disposition, utils.FormatSize(info.Size()))
 
 	// 使用速度跟踪器
 	speedTracker := utils.NewSpeedTrackerReader(file)
@@ -524,20 +861,28 @@ func DeleteFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 构建完整路径
-	fullPath := filepath.Join(config.UploadDir, filePath)
-	log.Printf("[DELETE] 构建完整路径 - 基础目录: %s, 相对路径: %s, 完整路径: %s",
-		config.UploadDir, filePath, fullPath)
-
-	// 确保路径在 config.UploadDir 内
-	absTarget, _ := filepath.Abs(fullPath)
-	absUpload, _ := filepath.Abs(config.UploadDir)
-	log.Printf("[DELETE] 路径验证 - 绝对目标路径: %s, 绝对基础路径: %s", absTarget, absUpload)
-	if !strings.HasPrefix(absTarget, absUpload) {
-		log.Printf("[DELETE] 错误: 路径遍历攻击尝试 - 目标: %s, 基础: %s", absTarget, absUpload)
+	// 解析并验证路径
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.Virtual {
+		log.Printf("[DELETE] 错误: 路径解析失败 - filePath=%s, 错误: %v", filePath, err)
 		utils.SendError(w, "无效的文件路径", http.StatusBadRequest)
 		return
 	}
+	fullPath := target.FullPath
+	log.Printf("[DELETE] 构建完整路径 - 存储根目录: %s, 相对路径: %s, 完整路径: %s",
+		target.Root, filePath, fullPath)
+
+	// 挂载点根目录不允许删除，只读挂载点不允许修改
+	if target.IsRoot() {
+		log.Printf("[DELETE] 错误: 尝试删除存储根目录 - %s", fullPath)
+		utils.SendError(w, "不能删除存储根目录", http.StatusBadRequest)
+		return
+	}
+	if err := target.CheckWritable(); err != nil {
+		log.Printf("[DELETE] 错误: 目标位置不可写 - %s, 错误: %v", fullPath, err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
 
 	// 检查文件是否存在
 	log.Printf("[DELETE] 正在检查文件/目录是否存在: %s", fullPath)
@@ -575,6 +920,7 @@ func DeleteFile(w http.ResponseWriter, r *http.Request) {
 		utils.SendError(w, "无法删除", http.StatusInternalServerError)
 		return
 	}
+	releaseFile(target, fullPath, info.IsDir())
 
 	name := filepath.Base(filePath)
 	duration := time.Since(startTime)
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fileSystem/internal/config"
+)
+
+// useTempStorage 将存储目录切换到临时目录（单根模式），测试结束后恢复
+func useTempStorage(t *testing.T) string {
+	t.Helper()
+	dir, mounts, cfg := config.UploadDir, config.Mounts, config.Cfg
+	t.Cleanup(func() {
+		config.UploadDir, config.Mounts, config.Cfg = dir, mounts, cfg
+	})
+	config.UploadDir = t.TempDir()
+	config.Mounts = nil
+	config.Cfg = config.Config{}
+	return config.UploadDir
+}
+
+// uploadRequest 构造 multipart 上传请求
+func uploadRequest(t *testing.T, query, filename, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	mw.Close()
+	r := httptest.NewRequest("POST", "/api/upload?"+query, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestUploadChecksum(t *testing.T) {
+	const (
+		helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+		otherSHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+	)
+	tests := []struct {
+		name       string
+		query      string
+		digest     string
+		wantStatus int
+	}{
+		{"hex query matches", "sha256=" + helloSHA256, "", http.StatusOK},
+		{"digest header matches", "", "sha-256=uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=", http.StatusOK},
+		{"hex query mismatch", "sha256=" + otherSHA256, "", http.StatusUnprocessableEntity},
+		{"digest header mismatch", "", "md5=AAAAAAAAAAAAAAAAAAAAAA==", http.StatusUnprocessableEntity},
+		{"invalid digest", "sha256=xyz", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := useTempStorage(t)
+			fullPath := filepath.Join(root, "hello.txt")
+			if err := os.WriteFile(fullPath, []byte("original"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			r := uploadRequest(t, tt.query, "hello.txt", "hello world")
+			if tt.digest != "" {
+				r.Header.Set("Digest", tt.digest)
+			}
+			w := httptest.NewRecorder()
+			UploadFile(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			data, err := os.ReadFile(fullPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "original"
+			if tt.wantStatus == http.StatusOK {
+				want = "hello world"
+			}
+			if string(data) != want {
+				t.Errorf("file content = %q, want %q", data, want)
+			}
+			entries, _ := os.ReadDir(root)
+			for _, e := range entries {
+				if e.Name() != "hello.txt" && e.Name() != ".filesystem" {
+					t.Errorf("leftover file %s", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestValidFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"a.txt", true},
+		{"中文 名称.txt", true},
+		{".hidden", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/.", false},
+		{"a/b.txt", false},
+		{`a\b.txt`, false},
+		{".filesystem", false},
+		{"../a.txt", false},
+	}
+	for _, tt := range tests {
+		if got := validFilename(tt.name); got != tt.want {
+			t.Errorf("validFilename(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUploadRejectsDotFilename(t *testing.T) {
+	root := useTempStorage(t)
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{".", "a/.", ".filesystem"} {
+		w := httptest.NewRecorder()
+		UploadFile(w, uploadRequest(t, "path=dir", filename, "hello"))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("upload %q: status = %d, want %d", filename, w.Code, http.StatusBadRequest)
+		}
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 || entries[0].Name() != "dir" {
+		t.Errorf("root entries = %v, want only dir", entries)
+	}
+}
+
+func TestInstantUpload(t *testing.T) {
+	const helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	root := useTempStorage(t)
+	config.Cfg.Dedup = true
+	// 配额 20 字节：已有的 11 字节文件加一次 11 字节的秒传会超出
+	config.Mounts = []config.Mount{{Name: "q", Path: root, Quota: 20}}
+
+	w := httptest.NewRecorder()
+	UploadFile(w, uploadRequest(t, "path=q", "a.txt", "hello world"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload status = %d: %s", w.Code, w.Body)
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	instant := func(query string) int {
+		w := httptest.NewRecorder()
+		InstantUpload(w, httptest.NewRequest("POST", "/api/upload/instant?sha256="+helloSHA256+"&"+query, nil))
+		return w.Code
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"dot filename", "path=q/dir&filename=.", http.StatusBadRequest},
+		{"meta dir filename", "path=q&filename=.filesystem", http.StatusBadRequest},
+		{"existing directory", "path=q&filename=dir", http.StatusConflict},
+		{"existing directory with overwrite", "path=q&filename=dir&overwrite=true", http.StatusConflict},
+		{"existing file", "path=q&filename=a.txt", http.StatusConflict},
+		{"over quota", "path=q&filename=b.txt", http.StatusInsufficientStorage},
+	}
+	for _, tt := range tests {
+		if got := instant(tt.query); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "dir")); err != nil {
+		t.Errorf("directory removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("b.txt created over quota")
+	}
+
+	config.Mounts[0].Quota = 0
+	if got := instant("path=q&filename=b.txt"); got != http.StatusOK {
+		t.Errorf("instant upload status = %d, want %d", got, http.StatusOK)
+	}
+	if got := instant("path=q&filename=a.txt&overwrite=true"); got != http.StatusOK {
+		t.Errorf("overwrite status = %d, want %d", got, http.StatusOK)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "b.txt")); err != nil || string(data) != "hello world" {
+		t.Errorf("b.txt = %q, %v", data, err)
+	}
+}
+
+func TestUploadModTimeWithSharedContent(t *testing.T) {
+	root := useTempStorage(t)
+	config.Cfg.Dedup = true
+
+	times := map[string]string{
+		"a.txt": "2024-05-01T08:00:00Z",
+		"b.txt": "2023-01-02T03:04:05Z",
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w := httptest.NewRecorder()
+		UploadFile(w, uploadRequest(t, "modTime="+times[name], name, "same content"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("upload %s: status = %d: %s", name, w.Code, w.Body)
+		}
+	}
+	// 同步工具按修改时间比对，两个内容相同的文件都要保留各自指定的修改时间
+	for name, want := range times {
+		info, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.ModTime().UTC().Format(time.RFC3339); got != want {
+			t.Errorf("%s modTime = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestUploadQuotaOverwrite(t *testing.T) {
+	root := useTempStorage(t)
+	config.Mounts = []config.Mount{{Name: "q", Path: root, Quota: 20}}
+	upload := func(filename, content string) int {
+		w := httptest.NewRecorder()
+		UploadFile(w, uploadRequest(t, "path=q", filename, content))
+		return w.Code
+	}
+
+	if got := upload("a.txt", "123456789012345"); got != http.StatusOK {
+		t.Fatalf("first upload status = %d", got)
+	}
+	// 覆盖时原文件的空间会被释放，不计入新文件
+	if got := upload("a.txt", "abcdefghijklmno"); got != http.StatusOK {
+		t.Errorf("overwrite status = %d, want %d", got, http.StatusOK)
+	}
+	if got := upload("b.txt", "0123456789"); got != http.StatusInsufficientStorage {
+		t.Errorf("over quota status = %d, want %d", got, http.StatusInsufficientStorage)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "abcdefghijklmno" {
+		t.Errorf("a.txt = %q", data)
+	}
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		if e.Name() != "a.txt" && e.Name() != ".filesystem" {
+			t.Errorf("leftover file %s", e.Name())
+		}
+	}
+}
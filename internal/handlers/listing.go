@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/models"
+)
+
+// MaxListLimit 文件列表每页最多返回的条数
+const MaxListLimit = 1000
+
+// listQuery 文件列表的排序、过滤和分页参数
+type listQuery struct {
+	sort      string          // name、size 或 time
+	desc      bool            // 是否降序
+	dirsFirst bool            // 目录是否排在文件前面（不受排序方向影响）
+	name      string          // 文件名包含的文字（已转为小写）
+	exts      map[string]bool // 允许的扩展名（小写，不含点），为空时不限制
+	kind      string          // dir 或 file，为空时不限制
+	dirStats  bool            // 是否返回目录的递归统计
+	limit     int             // 每页条数，0 表示不分页
+	after     *listCursor     // 从该位置之后开始返回
+}
+
+// listCursor 分页游标，记录上一页最后一项的排序字段，目录内容变化后仍能接着往下翻
+type listCursor struct {
+	Sort      string    `json:"s"`
+	Desc      bool      `json:"o,omitempty"`
+	DirsFirst bool      `json:"d,omitempty"`
+	Name      string    `json:"n"`
+	IsDir     bool      `json:"i,omitempty"`
+	Size      int64     `json:"z,omitempty"`
+	ModTime   time.Time `json:"t"`
+}
+
+// parseListQuery 解析 sort、order、dirsFirst、name、ext、type、dirStats、limit、cursor 参数
+func parseListQuery(query url.Values) (*listQuery, error) {
+	q := &listQuery{sort: "name", dirsFirst: true}
+
+	if v := query.Get("sort"); v != "" {
+		if v != "name" && v != "size" && v != "time" {
+			return nil, errors.New("无效的排序字段（可选: name, size, time）")
+		}
+		q.sort = v
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return nil, errors.New("无效的排序方向（可选: asc, desc）")
+	}
+	if v := query.Get("dirsFirst"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("无效的 dirsFirst 参数")
+		}
+		q.dirsFirst = b
+	}
+
+	q.name = strings.ToLower(query.Get("name"))
+	for _, ext := range strings.Split(query.Get("ext"), ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext == "" {
+			continue
+		}
+		if q.exts == nil {
+			q.exts = make(map[string]bool)
+		}
+		q.exts[ext] = true
+	}
+
+	switch q.kind = query.Get("type"); q.kind {
+	case "", "dir", "file":
+	default:
+		return nil, errors.New("无效的类型（可选: dir, file）")
+	}
+	if v := query.Get("dirStats"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("无效的 dirStats 参数")
+		}
+		q.dirStats = b
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, errors.New("无效的 limit 参数")
+		}
+		q.limit = min(n, MaxListLimit)
+	}
+	if v := query.Get("cursor"); v != "" {
+		c, err := decodeListCursor(v)
+		if err != nil || c.Sort != q.sort || c.Desc != q.desc || c.DirsFirst != q.dirsFirst {
+			return nil, errors.New("无效的分页游标，排序参数变化后需从第一页开始")
+		}
+		q.after = c
+	}
+	return q, nil
+}
+
+// match 判断文件是否符合名称、扩展名和类型过滤条件
+func (q *listQuery) match(f *models.FileInfo) bool {
+	if (q.kind == "dir" && !f.IsDir) || (q.kind == "file" && f.IsDir) {
+		return false
+	}
+	if q.name != "" && !strings.Contains(strings.ToLower(f.Name), q.name) {
+		return false
+	}
+	if q.exts != nil && (f.IsDir || !q.exts[strings.ToLower(f.Extension)]) {
+		return false
+	}
+	return true
+}
+
+// less 排序比较，字段相同时按文件名区分，保证顺序稳定、游标位置唯一
+func (q *listQuery) less(a, b *models.FileInfo) bool {
+	if q.dirsFirst && a.IsDir != b.IsDir {
+		return a.IsDir
+	}
+	c := 0
+	switch q.sort {
+	case "size":
+		c = cmp.Compare(a.Size, b.Size)
+	case "time":
+		c = a.ModTime.Compare(b.ModTime)
+	default:
+		c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}
+	if c == 0 {
+		c = strings.Compare(a.Name, b.Name)
+	}
+	if q.desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// apply 过滤并排序，返回当前页、过滤后的总数和下一页游标（没有下一页时为空）
+func (q *listQuery) apply(items []models.FileInfo) (page []models.FileInfo, total int, next string) {
+	matched := items[:0]
+	for i := range items {
+		if q.match(&items[i]) {
+			matched = append(matched, items[i])
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return q.less(&matched[i], &matched[j]) })
+
+	start := 0
+	if q.after != nil {
+		last := models.FileInfo{Name: q.after.Name, IsDir: q.after.IsDir, Size: q.after.Size, ModTime: q.after.ModTime}
+		start = sort.Search(len(matched), func(i int) bool { return q.less(&last, &matched[i]) })
+	}
+	page = matched[start:]
+	if q.limit > 0 && len(page) > q.limit {
+		page = page[:q.limit]
+		next = q.cursorAfter(&page[len(page)-1])
+	}
+	return page, len(matched), next
+}
+
+// cursorAfter 生成指向 f 之后的游标
+func (q *listQuery) cursorAfter(f *models.FileInfo) string {
+	data, _ := json.Marshal(listCursor{
+		Sort:      q.sort,
+		Desc:      q.desc,
+		DirsFirst: q.dirsFirst,
+		Name:      f.Name,
+		IsDir:     f.IsDir,
+		Size:      f.Size,
+		ModTime:   f.ModTime,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(s string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
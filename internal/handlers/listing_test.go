@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fileSystem/internal/models"
+)
+
+// listPage 请求一页文件列表，返回状态码和解析后的列表
+func listPage(t *testing.T, query url.Values) (int, models.FileList) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ListFiles(w, httptest.NewRequest("GET", "/api/files?"+query.Encode(), nil))
+	var resp struct {
+		Data models.FileList `json:"data"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %s: %v", w.Body, err)
+		}
+	}
+	return w.Code, resp.Data
+}
+
+// listAll 按 limit 逐页读取全部文件名
+func listAll(t *testing.T, query url.Values) []string {
+	t.Helper()
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("too many pages")
+		}
+		status, list := listPage(t, query)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d", status)
+		}
+		limit := query.Get("limit")
+		if limit != "" && fmt.Sprint(len(list.Files)) != limit && list.NextCursor != "" {
+			t.Errorf("page has %d files, want %s", len(list.Files), limit)
+		}
+		for _, f := range list.Files {
+			names = append(names, f.Name)
+		}
+		if list.NextCursor == "" {
+			return names
+		}
+		query.Set("cursor", list.NextCursor)
+	}
+}
+
+// writeFiles 创建测试文件，size 为内容长度，modTime 按 offset 秒错开
+func writeFiles(t *testing.T, dir string, files map[string]struct {
+	size   int
+	offset int
+}) {
+	t.Helper()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, f := range files {
+		fullPath := filepath.Join(dir, name)
+		if err := os.WriteFile(fullPath, []byte(strings.Repeat("x", f.size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(f.offset) * time.Second)
+		if err := os.Chtimes(fullPath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	root := useTempStorage(t)
+	writeFiles(t, root, map[string]struct{ size, offset int }{
+		"a.txt": {5, 3}, "B.txt": {1, 1}, "c.txt": {5, 2}, "d.txt": {3, 2},
+		"e.txt": {9, 0}, "f.txt": {2, 4}, "g.txt": {5, 2},
+	})
+	for _, d := range []string{"dir1", "Dir2"} {
+		if err := os.Mkdir(filepath.Join(root, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"name", "", []string{"dir1", "Dir2", "a.txt", "B.txt", "c.txt", "d.txt", "e.txt", "f.txt", "g.txt"}},
+		{"name desc", "order=desc", []string{"Dir2", "dir1", "g.txt", "f.txt", "e.txt", "d.txt", "c.txt", "B.txt", "a.txt"}},
+		{"mixed", "dirsFirst=false", []string{"a.txt", "B.txt", "c.txt", "d.txt", "dir1", "Dir2", "e.txt", "f.txt", "g.txt"}},
+		// 大小相同的文件按名称排序
+		{"size", "sort=size&type=file", []string{"B.txt", "f.txt", "d.txt", "a.txt", "c.txt", "g.txt", "e.txt"}},
+		{"size desc", "sort=size&order=desc&type=file", []string{"e.txt", "g.txt", "c.txt", "a.txt", "d.txt", "f.txt", "B.txt"}},
+		{"time", "sort=time&type=file", []string{"e.txt", "B.txt", "c.txt", "d.txt", "g.txt", "a.txt", "f.txt"}},
+		{"filtered", "name=.txt&sort=size", []string{"B.txt", "f.txt", "d.txt", "a.txt", "c.txt", "g.txt", "e.txt"}},
+	}
+	for _, tt := range tests {
+		for _, limit := range []string{"1", "2", "3", "100"} {
+			t.Run(tt.name+"/limit="+limit, func(t *testing.T) {
+				query, _ := url.ParseQuery(tt.query)
+				query.Set("limit", limit)
+				if got := listAll(t, query); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("pages = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+
+	status, list := listPage(t, url.Values{"limit": {"4"}})
+	if status != http.StatusOK || list.Total != 9 || len(list.Files) != 4 || list.NextCursor == "" {
+		t.Errorf("first page = %d, total %d, %d files, cursor %q", status, list.Total, len(list.Files), list.NextCursor)
+	}
+}
+
+func TestListCursorTampered(t *testing.T) {
+	root := useTempStorage(t)
+	writeFiles(t, root, map[string]struct{ size, offset int }{
+		"a.txt": {1, 0}, "b.txt": {2, 0}, "c.txt": {3, 0}, "d.txt": {4, 0},
+	})
+	_, first := listPage(t, url.Values{"limit": {"2"}})
+	raw, err := base64.RawURLEncoding.DecodeString(first.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cursor map[string]any
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		t.Fatal(err)
+	}
+	encode := func(change func(map[string]any)) string {
+		c := make(map[string]any)
+		for k, v := range cursor {
+			c[k] = v
+		}
+		change(c)
+		data, _ := json.Marshal(c)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+		want   []string
+	}{
+		{"valid", url.Values{"cursor": {first.NextCursor}}, http.StatusOK, []string{"c.txt", "d.txt"}},
+		{"not base64", url.Values{"cursor": {"%%%"}}, http.StatusBadRequest, nil},
+		{"not json", url.Values{"cursor": {base64.RawURLEncoding.EncodeToString([]byte("{oops"))}}, http.StatusBadRequest, nil},
+		{"wrong field type", url.Values{"cursor": {encode(func(c map[string]any) { c["z"] = "big" })}}, http.StatusBadRequest, nil},
+		{"truncated", url.Values{"cursor": {first.NextCursor[:len(first.NextCursor)/2]}}, http.StatusBadRequest, nil},
+		{"sort changed", url.Values{"cursor": {first.NextCursor}, "sort": {"size"}}, http.StatusBadRequest, nil},
+		{"order changed", url.Values{"cursor": {first.NextCursor}, "order": {"desc"}}, http.StatusBadRequest, nil},
+		{"dirsFirst changed", url.Values{"cursor": {first.NextCursor}, "dirsFirst": {"false"}}, http.StatusBadRequest, nil},
+		{"sort field edited", url.Values{"cursor": {encode(func(c map[string]any) { c["s"] = "time" })}}, http.StatusBadRequest, nil},
+		// 修改位置字段只会改变起始位置，不会越界或重复
+		{"name edited", url.Values{"cursor": {encode(func(c map[string]any) { c["n"] = "c.txt" })}}, http.StatusOK, []string{"d.txt"}},
+		{"name past end", url.Values{"cursor": {encode(func(c map[string]any) { c["n"] = "zzz" })}}, http.StatusOK, []string{}},
+		{"name before start", url.Values{"cursor": {encode(func(c map[string]any) { c["n"] = "" })}}, http.StatusOK, []string{"a.txt", "b.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Set("limit", "2")
+			status, list := listPage(t, tt.query)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if tt.want == nil {
+				return
+			}
+			got := []string{}
+			for _, f := range list.Files {
+				got = append(got, f.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCursorDirectoryChanged(t *testing.T) {
+	root := useTempStorage(t)
+	writeFiles(t, root, map[string]struct{ size, offset int }{
+		"b.txt": {1, 0}, "d.txt": {1, 0}, "f.txt": {1, 0}, "h.txt": {1, 0}, "j.txt": {1, 0},
+	})
+	_, first := listPage(t, url.Values{"limit": {"2"}})
+	if len(first.Files) != 2 || first.Files[1].Name != "d.txt" {
+		t.Fatalf("first page = %+v", first.Files)
+	}
+
+	// 删除已返回的项（包括游标指向的项），在游标前后各新增文件
+	os.Remove(filepath.Join(root, "b.txt"))
+	os.Remove(filepath.Join(root, "d.txt"))
+	writeFiles(t, root, map[string]struct{ size, offset int }{
+		"a.txt": {1, 0}, "c.txt": {1, 0}, "e.txt": {1, 0}, "k.txt": {1, 0},
+	})
+
+	query := url.Values{"limit": {"2"}, "cursor": {first.NextCursor}}
+	if got, want := listAll(t, query), []string{"e.txt", "f.txt", "h.txt", "j.txt", "k.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining pages = %v, want %v", got, want)
+	}
+
+	// 删除下一页的文件后继续翻页，不会跳过其后的文件
+	_, second := listPage(t, url.Values{"limit": {"2"}, "cursor": {first.NextCursor}})
+	os.Remove(filepath.Join(root, "h.txt"))
+	query = url.Values{"limit": {"2"}, "cursor": {second.NextCursor}}
+	if got, want := listAll(t, query), []string{"j.txt", "k.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining pages = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+)
+
+// Manifest 返回目录下所有文件和子目录的清单（含 SHA-256），用于客户端同步
+//
+// 没有摘要记录或记录已过期的文件会现场计算 SHA-256 并保存，之后的请求直接使用记录。
+func Manifest(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	path := r.URL.Query().Get("path")
+	log.Printf("[MANIFEST] 请求开始 - 路径参数: %s, 客户端IP: %s", path, r.RemoteAddr)
+
+	target, err := storage.Resolve(path)
+	if err != nil || target.Virtual {
+		log.Printf("[MANIFEST] 错误: 无效的路径 - path=%s, 错误: %v", path, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if os.IsNotExist(err) {
+		log.Printf("[MANIFEST] 错误: 目录不存在 %s", target.FullPath)
+		utils.SendError(w, "目录不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil || !info.IsDir() {
+		log.Printf("[MANIFEST] 错误: 不是目录 %s - %v", target.FullPath, err)
+		utils.SendError(w, "不是目录", http.StatusBadRequest)
+		return
+	}
+
+	entries := []models.ManifestEntry{}
+	hashed := 0
+	err = filepath.WalkDir(target.FullPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == target.FullPath {
+			return nil
+		}
+		if d.IsDir() && d.Name() == storage.MetaDirName && filepath.Dir(p) == target.Root {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(target.FullPath, p)
+		if err != nil {
+			return err
+		}
+
+		entry := models.ManifestEntry{
+			Path:    filepath.ToSlash(rel),
+			ModTime: info.ModTime(),
+			IsDir:   d.IsDir(),
+		}
+		if !d.IsDir() {
+			entry.Size = info.Size()
+			sums, ok := checksum.Load(target.Root, p, info)
+			if !ok {
+				if sums, err = hashFile(p); err != nil {
+					return err
+				}
+				hashed++
+				if err := checksum.Save(target.Root, p, sums); err != nil {
+					log.Printf("[MANIFEST] 警告: 无法保存文件摘要 %s - %v", p, err)
+				}
+			}
+			entry.SHA256 = sums.SHA256
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[MANIFEST] 错误: 无法读取目录 %s - %v", target.FullPath, err)
+		utils.SendError(w, "无法读取文件列表", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[MANIFEST] 成功: 返回 %d 项, 新计算摘要 %d 个, 耗时: %v", len(entries), hashed, time.Since(startTime))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// hashFile 读取文件计算摘要（SHA-256 及配置启用的算法）
+func hashFile(path string) (models.Checksums, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return models.Checksums{}, err
+	}
+	defer f.Close()
+	h := checksum.NewHasher()
+	if _, err := io.Copy(h, f); err != nil {
+		return models.Checksums{}, err
+	}
+	return h.Sum(), nil
+}
@@ -0,0 +1,784 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/config"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/storage"
+)
+
+const s3XMLNamespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// s3Error S3 错误响应
+type s3Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *s3Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	s3ErrAccessDenied           = &s3Error{"AccessDenied", "拒绝访问", http.StatusForbidden}
+	s3ErrAuthorizationMalformed = &s3Error{"AuthorizationHeaderMalformed", "无效的签名参数", http.StatusBadRequest}
+	s3ErrInvalidAccessKey       = &s3Error{"InvalidAccessKeyId", "访问密钥不存在", http.StatusForbidden}
+	s3ErrSignatureMismatch      = &s3Error{"SignatureDoesNotMatch", "签名不匹配", http.StatusForbidden}
+	s3ErrTimeSkewed             = &s3Error{"RequestTimeTooSkewed", "请求时间与服务器时间相差过大", http.StatusForbidden}
+	s3ErrExpiredPresign         = &s3Error{"AccessDenied", "预签名链接已过期", http.StatusForbidden}
+	s3ErrPresignNotYetValid     = &s3Error{"AccessDenied", "预签名链接尚未生效", http.StatusForbidden}
+	s3ErrContentSHA256Mismatch  = &s3Error{"XAmzContentSHA256Mismatch", "请求体的 SHA-256 与签名不一致", http.StatusBadRequest}
+	s3ErrIncompleteBody         = &s3Error{"IncompleteBody", "请求体不完整", http.StatusBadRequest}
+	s3ErrBadDigest              = &s3Error{"BadDigest", "内容校验和不匹配", http.StatusBadRequest}
+	s3ErrInvalidDigest          = &s3Error{"InvalidDigest", "无效的校验和", http.StatusBadRequest}
+	s3ErrNoSuchBucket           = &s3Error{"NoSuchBucket", "存储桶不存在", http.StatusNotFound}
+	s3ErrNoSuchKey              = &s3Error{"NoSuchKey", "对象不存在", http.StatusNotFound}
+	s3ErrInvalidBucketName      = &s3Error{"InvalidBucketName", "无效的存储桶名称", http.StatusBadRequest}
+	s3ErrInvalidKey             = &s3Error{"InvalidArgument", "无效的对象键", http.StatusBadRequest}
+	s3ErrMalformedXML           = &s3Error{"MalformedXML", "无效的 XML 请求体", http.StatusBadRequest}
+	s3ErrNotImplemented         = &s3Error{"NotImplemented", "不支持该操作", http.StatusNotImplemented}
+	s3ErrMethodNotAllowed       = &s3Error{"MethodNotAllowed", "不支持该请求方法", http.StatusMethodNotAllowed}
+	s3ErrQuotaExceeded          = &s3Error{"InsufficientStorage", storage.ErrQuotaExceeded.Error(), http.StatusInsufficientStorage}
+	s3ErrInternal               = &s3Error{"InternalError", "服务器内部错误", http.StatusInternalServerError}
+)
+
+// s3Handler S3 兼容接口（路径风格：/s3/<bucket>/<key>）
+//
+// 挂载点模式下每个挂载点是一个存储桶，单根模式下存储目录的一级子目录是存储桶。
+type s3Handler struct {
+	prefix string
+}
+
+// NewS3Handler 创建挂载在 prefix 下的 S3 兼容接口
+func NewS3Handler(prefix string) http.Handler {
+	return audit.Wrap("s3", "", (&s3Handler{prefix: prefix}).ServeHTTP)
+}
+
+func (h *s3Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[S3] 请求 - 方法: %s, 路径: %s, 查询: %s, 客户端IP: %s, User-Agent: %s",
+		r.Method, r.URL.Path, r.URL.RawQuery, r.RemoteAddr, r.UserAgent())
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, h.prefix), "/")
+	bucket, key, _ := strings.Cut(rest, "/")
+	query := r.URL.Query()
+	// 存储桶即顶层目录，rest 与其他接口的路径一致
+	audit.SetAction(r, s3Action(r.Method, key, query))
+	audit.SetPath(r, rest, "")
+
+	sig, err := verifyS3Request(r)
+	if err != nil {
+		writeS3Error(w, r, err)
+		return
+	}
+	audit.SetActor(r, config.Cfg.S3.AccessKey)
+
+	switch {
+	case bucket == "":
+		if r.Method != http.MethodGet {
+			err = s3ErrMethodNotAllowed
+			break
+		}
+		err = s3ListBuckets(w)
+
+	case key == "":
+		switch r.Method {
+		case http.MethodGet:
+			if query.Has("location") {
+				err = s3GetBucketLocation(w, bucket)
+			} else if query.Has("uploads") || query.Has("versions") {
+				err = s3ErrNotImplemented
+			} else {
+				err = s3ListObjects(w, bucket, query)
+			}
+		case http.MethodHead:
+			_, err = s3ResolveBucket(bucket)
+		case http.MethodPut:
+			err = s3CreateBucket(bucket)
+		case http.MethodPost:
+			if query.Has("delete") {
+				err = s3DeleteObjects(w, r, sig, bucket)
+			} else {
+				err = s3ErrNotImplemented
+			}
+		default:
+			err = s3ErrNotImplemented
+		}
+
+	default:
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if query.Has("uploadId") {
+				err = s3ErrNotImplemented
+			} else {
+				err = s3GetObject(w, r, bucket, key)
+			}
+		case http.MethodPut:
+			if query.Has("uploadId") {
+				err = s3UploadPart(w, r, sig, bucket, key)
+			} else if r.Header.Get("X-Amz-Copy-Source") != "" {
+				err = s3ErrNotImplemented
+			} else {
+				err = s3PutObject(w, r, sig, bucket, key)
+			}
+		case http.MethodPost:
+			if query.Has("uploads") {
+				err = s3CreateMultipartUpload(w, bucket, key)
+			} else if query.Has("uploadId") {
+				err = s3CompleteMultipartUpload(w, r, sig, bucket, key)
+			} else {
+				err = s3ErrNotImplemented
+			}
+		case http.MethodDelete:
+			if query.Has("uploadId") {
+				err = s3AbortMultipartUpload(bucket, key, query.Get("uploadId"))
+			} else {
+				err = s3DeleteObject(bucket, key)
+			}
+			if err == nil {
+				w.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			err = s3ErrMethodNotAllowed
+		}
+	}
+
+	if err != nil {
+		writeS3Error(w, r, err)
+	}
+}
+
+// s3Action 请求对应的审计操作，HEAD 和分段上传的中间步骤不记录
+func s3Action(method, key string, query url.Values) string {
+	switch method {
+	case http.MethodGet:
+		if key == "" {
+			return "list"
+		}
+		return "download"
+	case http.MethodPut:
+		if key == "" {
+			return "mkdir"
+		}
+		if query.Has("uploadId") {
+			return ""
+		}
+		return "upload"
+	case http.MethodPost:
+		if query.Has("delete") {
+			return "delete"
+		}
+		if query.Has("uploadId") {
+			return "upload"
+		}
+	case http.MethodDelete:
+		if !query.Has("uploadId") {
+			return "delete"
+		}
+	}
+	return ""
+}
+
+// writeS3Error 输出 S3 格式的错误响应
+func writeS3Error(w http.ResponseWriter, r *http.Request, err error) {
+	var s3Err *s3Error
+	switch {
+	case errors.As(err, &s3Err):
+	case errors.Is(err, storage.ErrReadOnly), errors.Is(err, storage.ErrNoMount):
+		s3Err = s3ErrAccessDenied
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		s3Err = s3ErrQuotaExceeded
+	case errors.Is(err, checksum.ErrMismatch):
+		s3Err = s3ErrBadDigest
+	default:
+		s3Err = s3ErrInternal
+	}
+	log.Printf("[S3] 错误: %s %s - %v", r.Method, r.URL.Path, err)
+
+	if r.Method == http.MethodHead {
+		w.WriteHeader(s3Err.Status)
+		return
+	}
+	writeS3XML(w, s3Err.Status, struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Message  string   `xml:"Message"`
+		Resource string   `xml:"Resource"`
+	}{Code: s3Err.Code, Message: s3Err.Message, Resource: r.URL.Path})
+}
+
+// writeS3XML 输出 XML 响应
+func writeS3XML(w http.ResponseWriter, status int, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		log.Printf("[S3] 错误: 无法序列化响应 - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// s3ResolveBucket 解析存储桶对应的目录
+func s3ResolveBucket(bucket string) (*storage.Target, error) {
+	target, err := storage.Resolve(bucket)
+	if err != nil || target.Virtual {
+		return nil, s3ErrNoSuchBucket
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil || !info.IsDir() {
+		return nil, s3ErrNoSuchBucket
+	}
+	return target, nil
+}
+
+// s3ResolveObject 解析对象对应的文件路径
+func s3ResolveObject(bucket, key string) (*storage.Target, error) {
+	if _, err := s3ResolveBucket(bucket); err != nil {
+		return nil, err
+	}
+	target, err := storage.Resolve(bucket + "/" + key)
+	if err != nil || target.IsRoot() {
+		return nil, s3ErrInvalidKey
+	}
+	return target, nil
+}
+
+// s3ETag 返回对象的 ETag，已知 MD5 时与 S3 一致使用 MD5
+func s3ETag(target *storage.Target, info os.FileInfo) string {
+	sums, _ := checksum.Load(target.Root, target.FullPath, info)
+	if sums.MD5 != "" {
+		return `"` + sums.MD5 + `"`
+	}
+	return checksum.ETag(sums, info)
+}
+
+// s3ExpectedChecksums 读取 Content-MD5 和 x-amz-checksum-sha256 请求头
+func s3ExpectedChecksums(r *http.Request) (checksum.Expected, error) {
+	expected := make(checksum.Expected)
+	for algo, header := range map[string]string{
+		checksum.MD5:    "Content-MD5",
+		checksum.SHA256: "X-Amz-Checksum-Sha256",
+	} {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, s3ErrInvalidDigest
+		}
+		expected[algo] = hex.EncodeToString(raw)
+	}
+	return expected, nil
+}
+
+// s3Bucket ListBuckets 响应中的存储桶
+type s3Bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+func s3ListBuckets(w http.ResponseWriter) error {
+	var buckets []s3Bucket
+	if storage.HasMounts() {
+		for _, info := range mountInfos() {
+			buckets = append(buckets, s3Bucket{Name: info.Name(), CreationDate: s3Time(info.ModTime())})
+		}
+	} else {
+		entries, err := os.ReadDir(config.UploadDir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || entry.Name() == storage.MetaDirName {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			buckets = append(buckets, s3Bucket{Name: entry.Name(), CreationDate: s3Time(info.ModTime())})
+		}
+	}
+
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName xml.Name   `xml:"ListAllMyBucketsResult"`
+		Xmlns   string     `xml:"xmlns,attr"`
+		Owner   s3Owner    `xml:"Owner"`
+		Buckets []s3Bucket `xml:"Buckets>Bucket"`
+	}{Xmlns: s3XMLNamespace, Owner: s3DefaultOwner, Buckets: buckets})
+	return nil
+}
+
+func s3GetBucketLocation(w http.ResponseWriter, bucket string) error {
+	if _, err := s3ResolveBucket(bucket); err != nil {
+		return err
+	}
+	// 空值表示 us-east-1
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"LocationConstraint"`
+		Xmlns   string   `xml:"xmlns,attr"`
+	}{Xmlns: s3XMLNamespace})
+	return nil
+}
+
+// s3CreateBucket 单根模式下创建一级目录，挂载点模式下只能使用已配置的挂载点
+func s3CreateBucket(bucket string) error {
+	if _, err := s3ResolveBucket(bucket); err == nil {
+		return nil
+	}
+	if storage.HasMounts() {
+		return s3ErrAccessDenied
+	}
+	target, err := storage.Resolve(bucket)
+	if err != nil || bucket == "" {
+		return s3ErrInvalidBucketName
+	}
+	log.Printf("[S3] 创建存储桶: %s", target.FullPath)
+	if err := os.Mkdir(target.FullPath, 0755); err != nil {
+		return err
+	}
+	dirstats.Invalidate(target.FullPath, false)
+	return nil
+}
+
+// s3Owner 对象所有者，本服务只有一个用户
+type s3Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+var s3DefaultOwner = s3Owner{ID: "fileSystem", DisplayName: "fileSystem"}
+
+// s3Object 列表中的对象
+type s3Object struct {
+	Key          string   `xml:"Key"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+	Size         int64    `xml:"Size"`
+	StorageClass string   `xml:"StorageClass"`
+	Owner        *s3Owner `xml:"Owner,omitempty"`
+}
+
+type s3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// s3ListObjects 实现 ListObjectsV2（list-type=2）和 ListObjects
+func s3ListObjects(w http.ResponseWriter, bucket string, query url.Values) error {
+	bucketTarget, err := s3ResolveBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	v2 := query.Get("list-type") == "2"
+	maxKeys := 1000
+	if v := query.Get("max-keys"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	// 从哪个键之后开始列出
+	marker := query.Get("marker")
+	if v2 {
+		marker = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			raw, err := base64.URLEncoding.DecodeString(token)
+			if err != nil {
+				return &s3Error{"InvalidArgument", "无效的 continuation-token", http.StatusBadRequest}
+			}
+			marker = string(raw)
+		}
+	}
+
+	objects, prefixes, err := s3CollectKeys(bucket, bucketTarget, prefix, delimiter)
+	if err != nil {
+		return err
+	}
+
+	// 合并对象和公共前缀，按键排序后分页
+	type entry struct {
+		key    string
+		object *s3Object
+	}
+	var entries []entry
+	for i := range objects {
+		entries = append(entries, entry{key: objects[i].Key, object: &objects[i]})
+	}
+	for _, p := range prefixes {
+		entries = append(entries, entry{key: p})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	var contents []s3Object
+	var commonPrefixes []s3CommonPrefix
+	truncated := false
+	lastKey := ""
+	for _, e := range entries {
+		if e.key <= marker {
+			continue
+		}
+		if len(contents)+len(commonPrefixes) >= maxKeys {
+			truncated = true
+			break
+		}
+		lastKey = e.key
+		if e.object != nil {
+			if v2 && query.Get("fetch-owner") != "true" {
+				e.object.Owner = nil
+			}
+			contents = append(contents, *e.object)
+		} else {
+			commonPrefixes = append(commonPrefixes, s3CommonPrefix{Prefix: e.key})
+		}
+	}
+
+	// encoding-type=url 时对键进行 URL 编码
+	encode := func(s string) string { return s }
+	if query.Get("encoding-type") == "url" {
+		encode = func(s string) string { return strings.ReplaceAll(url.QueryEscape(s), "+", "%20") }
+	}
+	for i := range contents {
+		contents[i].Key = encode(contents[i].Key)
+	}
+	for i := range commonPrefixes {
+		commonPrefixes[i].Prefix = encode(commonPrefixes[i].Prefix)
+	}
+
+	result := s3ListResult{
+		Xmlns:          s3XMLNamespace,
+		Name:           bucket,
+		Prefix:         encode(prefix),
+		Delimiter:      encode(delimiter),
+		MaxKeys:        maxKeys,
+		EncodingType:   query.Get("encoding-type"),
+		IsTruncated:    truncated,
+		Contents:       contents,
+		CommonPrefixes: commonPrefixes,
+	}
+	if v2 {
+		result.KeyCount = len(contents) + len(commonPrefixes)
+		result.StartAfter = encode(query.Get("start-after"))
+		result.ContinuationToken = query.Get("continuation-token")
+		if truncated {
+			result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(lastKey))
+		}
+	} else {
+		result.Marker = encode(query.Get("marker"))
+		if truncated && delimiter != "" {
+			result.NextMarker = encode(lastKey)
+		}
+	}
+	log.Printf("[S3] 列出对象 - 存储桶: %s, 前缀: %s, 对象数: %d, 公共前缀数: %d",
+		bucket, prefix, len(contents), len(commonPrefixes))
+	writeS3XML(w, http.StatusOK, result)
+	return nil
+}
+
+// s3ListResult ListObjects / ListObjectsV2 响应
+type s3ListResult struct {
+	XMLName               xml.Name         `xml:"ListBucketResult"`
+	Xmlns                 string           `xml:"xmlns,attr"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	Marker                string           `xml:"Marker,omitempty"`
+	NextMarker            string           `xml:"NextMarker,omitempty"`
+	StartAfter            string           `xml:"StartAfter,omitempty"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int              `xml:"KeyCount,omitempty"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	EncodingType          string           `xml:"EncodingType,omitempty"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	Contents              []s3Object       `xml:"Contents"`
+	CommonPrefixes        []s3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// s3CollectKeys 收集存储桶中以 prefix 开头的对象
+//
+// 分隔符为 "/" 时只读取前缀所在的目录，子目录作为公共前缀返回；
+// 否则递归遍历，有分隔符时按分隔符折叠为公共前缀。
+func s3CollectKeys(bucketName string, bucket *storage.Target, prefix, delimiter string) ([]s3Object, []string, error) {
+	// 从前缀中最后一个 "/" 之前的目录开始查找
+	dirKey := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dirKey = prefix[:i+1]
+	}
+	start, err := storage.Resolve(bucketName + "/" + dirKey)
+	if err != nil {
+		return nil, nil, nil
+	}
+	if info, err := os.Stat(start.FullPath); err != nil || !info.IsDir() {
+		return nil, nil, nil
+	}
+
+	var objects []s3Object
+	prefixSet := make(map[string]bool)
+	add := func(key string, fullPath string, info os.FileInfo) {
+		if !strings.HasPrefix(key, prefix) {
+			return
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				prefixSet[key[:len(prefix)+i+len(delimiter)]] = true
+				return
+			}
+		}
+		objects = append(objects, s3Object{
+			Key:          key,
+			LastModified: s3Time(info.ModTime()),
+			ETag:         s3ETag(&storage.Target{Root: start.Root, FullPath: fullPath}, info),
+			Size:         info.Size(),
+			StorageClass: "STANDARD",
+			Owner:        &s3DefaultOwner,
+		})
+	}
+
+	if delimiter == "/" {
+		entries, err := os.ReadDir(start.FullPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			if entry.Name() == storage.MetaDirName {
+				continue
+			}
+			key := dirKey + entry.Name()
+			if entry.IsDir() {
+				if strings.HasPrefix(key+"/", prefix) {
+					prefixSet[key+"/"] = true
+				}
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			add(key, filepath.Join(start.FullPath, entry.Name()), info)
+		}
+	} else {
+		err := filepath.WalkDir(start.FullPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if d.Name() == storage.MetaDirName {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(bucket.FullPath, path)
+			if err != nil {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			add(filepath.ToSlash(rel), path, info)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	prefixes := make([]string, 0, len(prefixSet))
+	for p := range prefixSet {
+		prefixes = append(prefixes, p)
+	}
+	return objects, prefixes, nil
+}
+
+func s3Time(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// s3GetObject 实现 GetObject 和 HeadObject，支持 Range 和条件请求
+func s3GetObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil || info.IsDir() {
+		return s3ErrNoSuchKey
+	}
+	file, err := os.Open(target.FullPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w.Header().Set("ETag", s3ETag(target, info))
+	w.Header().Set("Content-Type", mimetype.Header(mimetype.Detect(target.FullPath, info)))
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	log.Printf("[S3] 发送对象 - 存储桶: %s, 键: %s, 方法: %s", bucket, key, r.Method)
+	return nil
+}
+
+// s3PutObject 实现 PutObject，与 REST 上传共用摘要计算、配额和去重逻辑
+func s3PutObject(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket, key string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := target.CheckWritable(); err != nil {
+		return err
+	}
+
+	// 以 "/" 结尾的空对象表示目录
+	if strings.HasSuffix(key, "/") {
+		if _, err := io.Copy(io.Discard, sig.body(r)); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(target.FullPath, 0755); err != nil {
+			return err
+		}
+		dirstats.Invalidate(target.FullPath, false)
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		return nil
+	}
+
+	expected, err := s3ExpectedChecksums(r)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(target.FullPath); err == nil && info.IsDir() {
+		return s3ErrInvalidKey
+	}
+	if err := os.MkdirAll(filepath.Dir(target.FullPath), 0755); err != nil {
+		return err
+	}
+
+	upload, err := createUploadFile(target, target.FullPath, checksum.MD5)
+	if err != nil {
+		return err
+	}
+	upload.origin = uploadOrigin(r, "s3")
+	size, err := io.Copy(upload, sig.body(r))
+	if err != nil {
+		upload.Abort()
+		return err
+	}
+	sums := upload.Checksums()
+	if err := checksum.Verify(sums, expected); err != nil {
+		upload.Abort()
+		return err
+	}
+	if err := upload.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("[S3] 上传对象 - 存储桶: %s, 键: %s, 大小: %d 字节", bucket, key, size)
+	w.Header().Set("ETag", `"`+sums.MD5+`"`)
+	return nil
+}
+
+// s3DeleteObject 删除对象，对象不存在时同样视为成功
+func s3DeleteObject(bucket, key string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := target.CheckWritable(); err != nil {
+		return err
+	}
+	info, err := os.Lstat(target.FullPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		// 目录标记只在目录为空时删除
+		if strings.HasSuffix(key, "/") {
+			os.Remove(target.FullPath)
+		}
+		return nil
+	}
+	if err := os.Remove(target.FullPath); err != nil {
+		return err
+	}
+	releaseFile(target, target.FullPath, false)
+	log.Printf("[S3] 删除对象 - 存储桶: %s, 键: %s", bucket, key)
+	return nil
+}
+
+// s3DeleteObjects 实现批量删除（POST ?delete）
+func s3DeleteObjects(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket string) error {
+	if _, err := s3ResolveBucket(bucket); err != nil {
+		return err
+	}
+	var req struct {
+		Quiet   bool `xml:"Quiet"`
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(sig.body(r), 1<<20)).Decode(&req); err != nil {
+		return s3ErrMalformedXML
+	}
+
+	type deleted struct {
+		Key string `xml:"Key"`
+	}
+	type deleteError struct {
+		Key     string `xml:"Key"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	var result struct {
+		XMLName xml.Name      `xml:"DeleteResult"`
+		Xmlns   string        `xml:"xmlns,attr"`
+		Deleted []deleted     `xml:"Deleted"`
+		Errors  []deleteError `xml:"Error"`
+	}
+	result.Xmlns = s3XMLNamespace
+	for _, obj := range req.Objects {
+		if err := s3DeleteObject(bucket, obj.Key); err != nil {
+			log.Printf("[S3] 错误: 批量删除失败 - 存储桶: %s, 键: %s, 错误: %v", bucket, obj.Key, err)
+			var s3Err *s3Error
+			if !errors.As(err, &s3Err) {
+				s3Err = s3ErrAccessDenied
+				if !errors.Is(err, storage.ErrReadOnly) {
+					s3Err = s3ErrInternal
+				}
+			}
+			result.Errors = append(result.Errors, deleteError{Key: obj.Key, Code: s3Err.Code, Message: s3Err.Message})
+			continue
+		}
+		if !req.Quiet {
+			result.Deleted = append(result.Deleted, deleted{Key: obj.Key})
+		}
+	}
+	writeS3XML(w, http.StatusOK, result)
+	return nil
+}
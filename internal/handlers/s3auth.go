@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/config"
+)
+
+// AWS Signature Version 4 相关常量
+const (
+	s3Algorithm             = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload       = "UNSIGNED-PAYLOAD"
+	s3StreamingPayload      = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	s3StreamingTrailer      = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	s3StreamingUnsigned     = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	s3EmptySHA256           = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3MaxClockSkew          = 15 * time.Minute
+	s3AmzDateFormat         = "20060102T150405Z"
+	s3MaxPresignedExpiresIn = 7 * 24 * 60 * 60
+)
+
+// s3Signature 已验证的请求签名，用于校验请求体
+type s3Signature struct {
+	payloadHash string
+	signingKey  []byte
+	amzDate     string
+	scope       string
+	signature   string
+}
+
+// verifyS3Request 校验请求的 SigV4 签名，支持 Authorization 请求头和预签名 URL
+func verifyS3Request(r *http.Request) (*s3Signature, error) {
+	query := r.URL.Query()
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+	presigned := false
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, s3Algorithm+" ") {
+			return nil, s3ErrAccessDenied
+		}
+		for _, part := range strings.Split(strings.TrimPrefix(auth, s3Algorithm+" "), ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+		if amzDate == "" {
+			if t, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+				amzDate = t.UTC().Format(s3AmzDateFormat)
+			}
+		}
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		if payloadHash == "" {
+			payloadHash = s3UnsignedPayload
+		}
+	} else if query.Get("X-Amz-Algorithm") == s3Algorithm {
+		presigned = true
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		amzDate = query.Get("X-Amz-Date")
+		payloadHash = s3UnsignedPayload
+	} else {
+		return nil, s3ErrAccessDenied
+	}
+
+	if credential == "" || signedHeaders == "" || signature == "" {
+		return nil, s3ErrAuthorizationMalformed
+	}
+	// Credential 格式: <AccessKey>/<日期>/<区域>/s3/aws4_request
+	scopeParts := strings.Split(credential, "/")
+	if len(scopeParts) != 5 || scopeParts[3] != "s3" || scopeParts[4] != "aws4_request" {
+		return nil, s3ErrAuthorizationMalformed
+	}
+	cfg := config.Cfg.S3
+	if subtle.ConstantTimeCompare([]byte(scopeParts[0]), []byte(cfg.AccessKey)) != 1 {
+		return nil, s3ErrInvalidAccessKey
+	}
+
+	requestTime, err := time.Parse(s3AmzDateFormat, amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, scopeParts[1]) {
+		return nil, s3ErrAuthorizationMalformed
+	}
+	if presigned {
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || expires < 0 || expires > s3MaxPresignedExpiresIn {
+			return nil, s3ErrAuthorizationMalformed
+		}
+		// 签名时间晚于当前时间时，有效期会被延长到超过 7 天的上限
+		if requestTime.After(time.Now().Add(s3MaxClockSkew)) {
+			return nil, s3ErrPresignNotYetValid
+		}
+		if time.Now().After(requestTime.Add(time.Duration(expires) * time.Second)) {
+			return nil, s3ErrExpiredPresign
+		}
+	} else if skew := time.Since(requestTime); skew > s3MaxClockSkew || skew < -s3MaxClockSkew {
+		return nil, s3ErrTimeSkewed
+	}
+
+	scope := strings.Join(scopeParts[1:], "/")
+	signingKey := s3SigningKey(cfg.SecretKey, scopeParts[1], scopeParts[2])
+	canonical := s3CanonicalRequest(r, signedHeaders, payloadHash, presigned)
+	expected := hex.EncodeToString(s3HMAC(signingKey, s3StringToSign(amzDate, scope, canonical)))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return nil, s3ErrSignatureMismatch
+	}
+
+	return &s3Signature{
+		payloadHash: payloadHash,
+		signingKey:  signingKey,
+		amzDate:     amzDate,
+		scope:       scope,
+		signature:   signature,
+	}, nil
+}
+
+// s3CanonicalRequest 按 SigV4 规则构造规范请求
+func s3CanonicalRequest(r *http.Request, signedHeaders, payloadHash string, presigned bool) string {
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if presigned && k == "X-Amz-Signature" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			params = append(params, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = r.Header.Get("Content-Length")
+			if value == "" && r.ContentLength >= 0 {
+				value = strconv.FormatInt(r.ContentLength, 10)
+			}
+		default:
+			values := r.Header.Values(name)
+			for i, v := range values {
+				values[i] = strings.Join(strings.Fields(v), " ")
+			}
+			value = strings.Join(values, ",")
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		s3Escape(r.URL.Path, false),
+		strings.Join(params, "&"),
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+}
+
+// s3StringToSign 按 SigV4 规则构造待签名字符串
+func s3StringToSign(amzDate, scope, canonical string) string {
+	return strings.Join([]string{s3Algorithm, amzDate, scope, s3SHA256Hex([]byte(canonical))}, "\n")
+}
+
+// s3Escape 按 SigV4 规则进行 URI 编码，只保留非保留字符
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3SigningKey(secret, date, region string) []byte {
+	key := s3HMAC([]byte("AWS4"+secret), date)
+	key = s3HMAC(key, region)
+	key = s3HMAC(key, "s3")
+	return s3HMAC(key, "aws4_request")
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func s3SHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// body 根据 x-amz-content-sha256 返回校验后的请求体
+func (s *s3Signature) body(r *http.Request) io.Reader {
+	switch s.payloadHash {
+	case s3UnsignedPayload:
+		return r.Body
+	case s3StreamingPayload, s3StreamingTrailer:
+		return &s3ChunkedReader{r: bufio.NewReader(r.Body), sig: s, prevSignature: s.signature}
+	case s3StreamingUnsigned:
+		return &s3ChunkedReader{r: bufio.NewReader(r.Body)}
+	default:
+		return &s3HashReader{r: r.Body, hash: sha256.New(), want: strings.ToLower(s.payloadHash)}
+	}
+}
+
+// s3HashReader 读取完毕时校验请求体的 SHA-256
+type s3HashReader struct {
+	r    io.Reader
+	hash hash.Hash
+	want string
+}
+
+func (h *s3HashReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(h.hash.Sum(nil)) != h.want {
+		return n, s3ErrContentSHA256Mismatch
+	}
+	return n, err
+}
+
+// s3ChunkedReader 解码 aws-chunked 编码的请求体，sig 不为空时校验每个分块的签名
+//
+// 分块格式: <十六进制长度>[;chunk-signature=<签名>]\r\n<数据>\r\n，以长度为 0 的分块结束，
+// 之后可能跟随 trailer 头部（如 x-amz-checksum-crc32），以空行结束。
+type s3ChunkedReader struct {
+	r             *bufio.Reader
+	sig           *s3Signature
+	prevSignature string
+	chunkSig      string
+	remaining     int64
+	hash          hash.Hash
+	err           error
+}
+
+func (c *s3ChunkedReader) Read(p []byte) (int, error) {
+	for c.err == nil && c.remaining == 0 {
+		if c.hash != nil {
+			c.err = c.finishChunk()
+			continue
+		}
+		c.err = c.nextChunk()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	c.remaining -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// nextChunk 读取下一个分块头，遇到结束分块时返回 io.EOF
+func (c *s3ChunkedReader) nextChunk() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	sizeText, ext, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeText), 16, 64)
+	if err != nil || size < 0 {
+		return s3ErrIncompleteBody
+	}
+	c.chunkSig = strings.TrimPrefix(ext, "chunk-signature=")
+	c.remaining = size
+	c.hash = sha256.New()
+	if size > 0 {
+		return nil
+	}
+
+	// 结束分块：校验签名并跳过 trailer
+	if err := c.verifyChunk(); err != nil {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil || line == "" {
+			return io.EOF
+		}
+	}
+}
+
+// finishChunk 读取分块数据后的换行并校验签名
+func (c *s3ChunkedReader) finishChunk() error {
+	line, err := c.readLine()
+	if err != nil || line != "" {
+		return s3ErrIncompleteBody
+	}
+	if err := c.verifyChunk(); err != nil {
+		return err
+	}
+	c.hash = nil
+	return nil
+}
+
+func (c *s3ChunkedReader) verifyChunk() error {
+	if c.sig == nil {
+		return nil
+	}
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		c.sig.amzDate,
+		c.sig.scope,
+		c.prevSignature,
+		s3EmptySHA256,
+		hex.EncodeToString(c.hash.Sum(nil)),
+	}, "\n")
+	expected := hex.EncodeToString(s3HMAC(c.sig.signingKey, stringToSign))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(c.chunkSig)) != 1 {
+		return s3ErrSignatureMismatch
+	}
+	c.prevSignature = c.chunkSig
+	return nil
+}
+
+func (c *s3ChunkedReader) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return string(bytes.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/storage"
+)
+
+var (
+	s3ErrNoSuchUpload     = &s3Error{"NoSuchUpload", "分片上传不存在或已结束", http.StatusNotFound}
+	s3ErrInvalidPart      = &s3Error{"InvalidPart", "分片不存在或 ETag 不匹配", http.StatusBadRequest}
+	s3ErrInvalidPartOrder = &s3Error{"InvalidPartOrder", "分片必须按编号升序排列", http.StatusBadRequest}
+	s3ErrInvalidPartNum   = &s3Error{"InvalidArgument", "分片编号必须在 1 到 10000 之间", http.StatusBadRequest}
+)
+
+// s3Upload 进行中的分片上传，分片暂存在存储根目录的 .filesystem/multipart/<uploadId> 下
+type s3Upload struct {
+	Key       string    `json:"key"` // <bucket>/<key>
+	Initiated time.Time `json:"initiated"`
+}
+
+// s3UploadMaxAge 分片上传超过该时长没有新的分片时视为已放弃，由 PurgeS3Uploads 删除
+const s3UploadMaxAge = 7 * 24 * time.Hour
+
+func s3UploadsDir(root string) string {
+	return filepath.Join(root, storage.MetaDirName, "multipart")
+}
+
+func s3UploadDir(target *storage.Target, uploadID string) string {
+	return filepath.Join(s3UploadsDir(target.Root), uploadID)
+}
+
+func s3PartPath(dir string, partNumber int) string {
+	return filepath.Join(dir, fmt.Sprintf("%05d", partNumber))
+}
+
+// s3OpenUpload 读取分片上传记录并确认属于该对象
+func s3OpenUpload(target *storage.Target, bucket, key, uploadID string) (string, error) {
+	if uploadID == "" || strings.ContainsAny(uploadID, `/\.`) {
+		return "", s3ErrNoSuchUpload
+	}
+	dir := s3UploadDir(target, uploadID)
+	data, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return "", s3ErrNoSuchUpload
+	}
+	var upload s3Upload
+	if err := json.Unmarshal(data, &upload); err != nil || upload.Key != bucket+"/"+key {
+		return "", s3ErrNoSuchUpload
+	}
+	return dir, nil
+}
+
+// s3CreateMultipartUpload 开始分片上传
+func s3CreateMultipartUpload(w http.ResponseWriter, bucket, key string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := target.CheckWritable(); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	uploadID := hex.EncodeToString(buf)
+	dir := s3UploadDir(target, uploadID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s3Upload{Key: bucket + "/" + key, Initiated: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "upload.json"), data, 0644); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	log.Printf("[S3] 开始分片上传 - 存储桶: %s, 键: %s, uploadId: %s", bucket, key, uploadID)
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Xmlns: s3XMLNamespace, Bucket: bucket, Key: key, UploadID: uploadID})
+	return nil
+}
+
+// s3UploadPart 上传单个分片，分片的 ETag 为其 MD5
+func s3UploadPart(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket, key string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := target.CheckWritable(); err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	dir, err := s3OpenUpload(target, bucket, key, query.Get("uploadId"))
+	if err != nil {
+		return err
+	}
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > 10000 {
+		return s3ErrInvalidPartNum
+	}
+	expected, err := s3ExpectedChecksums(r)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "part-*.tmp")
+	if err != nil {
+		return err
+	}
+	hasher := checksum.NewHasher(checksum.MD5)
+	size, err := copyWithQuota(target, io.MultiWriter(tmp, hasher), sig.body(r))
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	sums := hasher.Sum()
+	if err == nil {
+		err = checksum.Verify(sums, expected)
+	}
+	if err == nil {
+		err = os.WriteFile(s3PartPath(dir, partNumber)+".md5", []byte(sums.MD5), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s3PartPath(dir, partNumber))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	log.Printf("[S3] 上传分片 - 键: %s/%s, 分片: %d, 大小: %d 字节", bucket, key, partNumber, size)
+	w.Header().Set("ETag", `"`+sums.MD5+`"`)
+	return nil
+}
+
+// s3CompleteMultipartUpload 按顺序合并分片写入目标文件
+func s3CompleteMultipartUpload(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket, key string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := target.CheckWritable(); err != nil {
+		return err
+	}
+	dir, err := s3OpenUpload(target, bucket, key, r.URL.Query().Get("uploadId"))
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Parts []struct {
+			PartNumber int    `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(sig.body(r), 1<<20)).Decode(&req); err != nil || len(req.Parts) == 0 {
+		return s3ErrMalformedXML
+	}
+
+	// 校验分片顺序和 ETag，同时计算 S3 风格的分片上传 ETag
+	var total int64
+	etagHash := md5.New()
+	for i, part := range req.Parts {
+		if i > 0 && part.PartNumber <= req.Parts[i-1].PartNumber {
+			return s3ErrInvalidPartOrder
+		}
+		path := s3PartPath(dir, part.PartNumber)
+		sum, err := os.ReadFile(path + ".md5")
+		info, statErr := os.Stat(path)
+		if err != nil || statErr != nil || strings.Trim(part.ETag, `"`) != string(sum) {
+			return s3ErrInvalidPart
+		}
+		raw, _ := hex.DecodeString(string(sum))
+		etagHash.Write(raw)
+		total += info.Size()
+	}
+	if err := target.CheckQuota(total, existingSize(target.FullPath)); err != nil {
+		return err
+	}
+	if info, err := os.Stat(target.FullPath); err == nil && info.IsDir() {
+		return s3ErrInvalidKey
+	}
+	if err := os.MkdirAll(filepath.Dir(target.FullPath), 0755); err != nil {
+		return err
+	}
+
+	upload, err := createUploadFile(target, target.FullPath, checksum.MD5)
+	if err != nil {
+		return err
+	}
+	upload.origin = uploadOrigin(r, "s3")
+	for _, part := range req.Parts {
+		if err := s3AppendPart(upload, s3PartPath(dir, part.PartNumber)); err != nil {
+			upload.Abort()
+			return err
+		}
+	}
+	if err := upload.Commit(); err != nil {
+		return err
+	}
+	os.RemoveAll(dir)
+
+	etag := fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(etagHash.Sum(nil)), len(req.Parts))
+	log.Printf("[S3] 分片上传完成 - 存储桶: %s, 键: %s, 分片数: %d, 大小: %d 字节", bucket, key, len(req.Parts), total)
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Location string   `xml:"Location"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		ETag     string   `xml:"ETag"`
+	}{Xmlns: s3XMLNamespace, Location: r.URL.Path, Bucket: bucket, Key: key, ETag: etag})
+	return nil
+}
+
+// s3AbortMultipartUpload 放弃分片上传并删除已上传的分片
+func s3AbortMultipartUpload(bucket, key, uploadID string) error {
+	target, err := s3ResolveObject(bucket, key)
+	if err != nil {
+		return err
+	}
+	dir, err := s3OpenUpload(target, bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[S3] 放弃分片上传 - 存储桶: %s, 键: %s, uploadId: %s", bucket, key, uploadID)
+	return os.RemoveAll(dir)
+}
+
+func s3AppendPart(dst io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(dst, f)
+	return err
+}
+
+// PurgeS3Uploads 启动时及之后每小时删除各存储根目录下已放弃的分片上传，不返回
+func PurgeS3Uploads() {
+	for {
+		if n := s3PurgeUploads(storage.Roots(), time.Now().Add(-s3UploadMaxAge)); n > 0 {
+			log.Printf("[S3] 已删除 %d 个超过 %v 未完成的分片上传", n, s3UploadMaxAge)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// s3PurgeUploads 删除最后一次活动（创建或上传分片）早于 before 的分片上传，返回删除的数量
+func s3PurgeUploads(roots []string, before time.Time) int {
+	removed := 0
+	for _, root := range roots {
+		entries, err := os.ReadDir(s3UploadsDir(root))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			dir := filepath.Join(s3UploadsDir(root), e.Name())
+			if !e.IsDir() || s3LastActivity(dir).After(before) {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				log.Printf("[S3] 警告: 无法删除已放弃的分片上传 - 目录: %s, 错误: %v", dir, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed
+}
+
+// s3LastActivity 分片上传目录及其中文件的最新修改时间
+func s3LastActivity(dir string) time.Time {
+	var latest time.Time
+	if info, err := os.Stat(dir); err == nil {
+		latest = info.ModTime()
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
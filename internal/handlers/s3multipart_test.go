@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestS3PurgeUploads(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	old := now.Add(-s3UploadMaxAge - time.Hour)
+	// upload 创建分片上传目录，parts 为各分片的修改时间
+	upload := func(id string, created time.Time, parts ...time.Time) string {
+		dir := filepath.Join(s3UploadsDir(root), id)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]time.Time{filepath.Join(dir, "upload.json"): created}
+		for i, mtime := range parts {
+			files[s3PartPath(dir, i+1)] = mtime
+		}
+		for path, mtime := range files {
+			if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			os.Chtimes(path, mtime, mtime)
+		}
+		os.Chtimes(dir, created, created)
+		return dir
+	}
+	abandoned := upload("abandoned", old, old)
+	active := upload("active", old, old, now)
+	recent := upload("recent", now)
+
+	if n := s3PurgeUploads([]string{root, filepath.Join(root, "missing")}, now.Add(-s3UploadMaxAge)); n != 1 {
+		t.Errorf("removed = %d, want 1", n)
+	}
+	if _, err := os.Stat(abandoned); !os.IsNotExist(err) {
+		t.Error("abandoned upload not removed")
+	}
+	for _, dir := range []string{active, recent} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s removed: %v", filepath.Base(dir), err)
+		}
+	}
+}
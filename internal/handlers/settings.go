@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"fileSystem/internal/config"
+	"fileSystem/internal/models"
+	"fileSystem/internal/utils"
+)
+
+// GetSettings 返回前端使用的服务器设置（如建议的并发上传数、在线编辑的大小限制）
+func GetSettings(w http.ResponseWriter, r *http.Request) {
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data: models.Settings{
+			UploadConcurrency: config.Cfg.UploadConcurrency,
+			MaxEditSize:       MaxEditSize,
+		},
+	})
+}
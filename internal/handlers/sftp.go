@@ -0,0 +1,556 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/config"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/filedb"
+	"fileSystem/internal/storage"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpUser 已加载的 SFTP 用户
+type sftpUser struct {
+	password string
+	keys     [][]byte // 已授权公钥（wire 格式）
+}
+
+// StartSFTP 启动内置 SFTP 服务，未配置时直接返回
+func StartSFTP() error {
+	cfg := config.Cfg.SFTP
+	if cfg == nil {
+		return nil
+	}
+
+	hostKey, err := loadHostKey(sftpHostKeyPath(cfg))
+	if err != nil {
+		return fmt.Errorf("无法加载主机密钥: %w", err)
+	}
+
+	users := make(map[string]*sftpUser)
+	for _, u := range cfg.Users {
+		user := &sftpUser{password: u.Password}
+		if u.AuthorizedKeys != "" {
+			user.keys, err = loadAuthorizedKeys(u.AuthorizedKeys)
+			if err != nil {
+				return fmt.Errorf("无法读取用户 %s 的公钥文件: %w", u.Username, err)
+			}
+		}
+		users[u.Username] = user
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			user, ok := users[conn.User()]
+			if ok && user.password != "" && subtle.ConstantTimeCompare([]byte(user.password), password) == 1 {
+				log.Printf("[SFTP] 用户 %s 通过密码认证, 客户端IP: %s", conn.User(), conn.RemoteAddr())
+				return &ssh.Permissions{}, nil
+			}
+			log.Printf("[SFTP] 错误: 用户 %s 密码认证失败, 客户端IP: %s", conn.User(), conn.RemoteAddr())
+			return nil, errors.New("认证失败")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if user, ok := users[conn.User()]; ok {
+				for _, authorized := range user.keys {
+					if bytes.Equal(authorized, key.Marshal()) {
+						log.Printf("[SFTP] 用户 %s 通过公钥认证, 客户端IP: %s", conn.User(), conn.RemoteAddr())
+						return &ssh.Permissions{}, nil
+					}
+				}
+			}
+			return nil, errors.New("认证失败")
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", cfg.Port)
+	if err != nil {
+		return err
+	}
+	log.Printf("[SFTP] 服务已启动 - 地址: %s, 主机密钥指纹: %s", cfg.Port, ssh.FingerprintSHA256(hostKey.PublicKey()))
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Printf("[SFTP] 错误: 无法接受连接，服务已停止 - %v", err)
+				return
+			}
+			go serveSFTPConn(conn, serverConfig)
+		}
+	}()
+	return nil
+}
+
+// sftpHostKeyPath 返回主机私钥文件路径，未配置时使用存储目录下的 .filesystem/sftp_host_ed25519_key
+func sftpHostKeyPath(cfg *config.SFTPConfig) string {
+	if cfg.HostKey != "" {
+		return cfg.HostKey
+	}
+	return filepath.Join(storage.MetaDir(), "sftp_host_ed25519_key")
+}
+
+// loadHostKey 读取主机私钥，文件不存在时生成新的 ed25519 密钥
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+		log.Printf("[SFTP] 已生成主机密钥: %s", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// loadAuthorizedKeys 读取 authorized_keys 格式的公钥文件
+func loadAuthorizedKeys(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.Marshal())
+		data = rest
+	}
+	return keys, nil
+}
+
+// serveSFTPConn 处理单个 SSH 连接，只提供 sftp 子系统
+func serveSFTPConn(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	defer conn.Close()
+
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		log.Printf("[SFTP] 错误: SSH 握手失败 - %v, 客户端IP: %s", err, conn.RemoteAddr())
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "仅支持 session 通道")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("[SFTP] 错误: 无法打开通道 - %v", err)
+			continue
+		}
+
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					go serveSFTPSession(channel, sshConn.User(), sshConn.RemoteAddr())
+				}
+			}
+		}(channelRequests)
+	}
+}
+
+// serveSFTPSession 在通道上运行 SFTP 会话
+func serveSFTPSession(channel ssh.Channel, user string, remoteAddr net.Addr) {
+	defer channel.Close()
+	log.Printf("[SFTP] 会话开始 - 用户: %s, 客户端IP: %s", user, remoteAddr)
+
+	ip := remoteAddr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	handler := &sftpHandler{user: user, ip: ip}
+	server := sftp.NewRequestServer(channel, sftp.Handlers{
+		FileGet:  handler,
+		FilePut:  handler,
+		FileCmd:  handler,
+		FileList: handler,
+	})
+	if err := server.Serve(); err != nil && err != io.EOF {
+		log.Printf("[SFTP] 错误: 会话异常结束 - %v", err)
+	}
+	server.Close()
+	log.Printf("[SFTP] 会话结束 - 用户: %s", user)
+}
+
+// sftpHandler 将 SFTP 请求映射到存储目录，与 REST 接口共用路径解析和上传逻辑
+type sftpHandler struct {
+	user string
+	ip   string // 客户端 IP
+}
+
+// record 写入一条审计记录，err 不为空时记为失败
+func (h *sftpHandler) record(e audit.Entry, start time.Time, err error) {
+	e.Time = start
+	e.Actor = h.user
+	e.IP = h.ip
+	e.Via = "sftp"
+	e.Path = strings.Trim(e.Path, "/")
+	e.Target = strings.Trim(e.Target, "/")
+	e.Result = audit.ResultOK
+	if err != nil {
+		e.Result = audit.ResultError
+		e.Error = err.Error()
+	}
+	e.DurationMs = time.Since(start).Milliseconds()
+	audit.Record(e)
+}
+
+// sftpActions 需要记录审计日志的命令对应的操作
+var sftpActions = map[string]string{
+	"Rename": "move",
+	"Mkdir":  "mkdir",
+	"Rmdir":  "delete",
+	"Remove": "delete",
+	"List":   "list",
+}
+
+// resolve 解析 SFTP 路径，路径无效时返回 os.ErrNotExist
+func (h *sftpHandler) resolve(name string) (*storage.Target, error) {
+	target, err := storage.Resolve(name)
+	if err != nil {
+		log.Printf("[SFTP] 错误: 路径解析失败 - 用户: %s, 路径: %s, 错误: %v", h.user, name, err)
+		return nil, os.ErrNotExist
+	}
+	return target, nil
+}
+
+// writable 解析需要写入的路径，虚拟根目录、存储根目录和只读挂载点不允许修改
+func (h *sftpHandler) writable(name string) (*storage.Target, error) {
+	target, err := h.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsRoot() || target.CheckWritable() != nil {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	return target, nil
+}
+
+// Fileread 打开读取文件，下载成功时在关闭时记录审计日志
+func (h *sftpHandler) Fileread(r *sftp.Request) (_ io.ReaderAt, err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			h.record(audit.Entry{Action: "download", Path: r.Filepath}, start, err)
+		}
+	}()
+
+	target, err := h.resolve(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	if target.Virtual {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	log.Printf("[SFTP] 下载请求 - 用户: %s, 文件: %s", h.user, target.FullPath)
+	file, err := os.Open(target.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	return &sftpDownload{File: file, handler: h, path: r.Filepath, start: start}, nil
+}
+
+// Filewrite 打开写入文件，按上传处理：计算摘要、检查配额，去重模式下关闭时入库。
+// 文件总是被整体替换，不支持追加写入。
+func (h *sftpHandler) Filewrite(r *sftp.Request) (_ io.WriterAt, err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			h.record(audit.Entry{Action: "upload", Path: r.Filepath}, start, err)
+		}
+	}()
+
+	flags := r.Pflags()
+	if flags.Append {
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+	target, err := h.writable(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := os.Stat(filepath.Dir(target.FullPath))
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	if info, err := os.Stat(target.FullPath); err == nil {
+		if info.IsDir() || flags.Excl {
+			return nil, os.ErrExist
+		}
+	}
+
+	u, err := createUploadFile(target, target.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	u.origin = filedb.Upload{Uploader: h.user, IP: h.ip, Via: "sftp"}
+	log.Printf("[SFTP] 上传请求 - 用户: %s, 文件: %s", h.user, target.FullPath)
+	return &sftpUpload{upload: u, target: target, user: h.user, handler: h, path: r.Filepath, start: start}, nil
+}
+
+func (h *sftpHandler) Filecmd(r *sftp.Request) (err error) {
+	if action := sftpActions[r.Method]; action != "" {
+		start := time.Now()
+		defer func() {
+			h.record(audit.Entry{Action: action, Path: r.Filepath, Target: r.Target}, start, err)
+		}()
+	}
+
+	switch r.Method {
+	case "Setstat":
+		// 不支持修改权限和时间，忽略以兼容会在上传后设置属性的客户端
+		return nil
+
+	case "Rename":
+		return h.rename(r, false)
+
+	case "Mkdir":
+		target, err := h.resolve(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if target.IsRoot() {
+			return os.ErrExist
+		}
+		if target.CheckWritable() != nil {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		log.Printf("[SFTP] 创建目录 - 用户: %s, 目录: %s", h.user, target.FullPath)
+		if err := os.Mkdir(target.FullPath, 0755); err != nil {
+			return err
+		}
+		dirstats.Invalidate(target.FullPath, false)
+		return nil
+
+	case "Rmdir", "Remove":
+		target, err := h.writable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(target.FullPath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() != (r.Method == "Rmdir") {
+			return os.ErrInvalid
+		}
+		// 与 rmdir 语义一致，只删除空目录
+		if err := os.Remove(target.FullPath); err != nil {
+			return err
+		}
+		releaseFile(target, target.FullPath, info.IsDir())
+		log.Printf("[SFTP] 已删除 - 用户: %s, 路径: %s", h.user, target.FullPath)
+		return nil
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *sftpHandler) Filelist(r *sftp.Request) (_ sftp.ListerAt, err error) {
+	if action := sftpActions[r.Method]; action != "" {
+		start := time.Now()
+		defer func() {
+			h.record(audit.Entry{Action: action, Path: r.Filepath}, start, err)
+		}()
+	}
+
+	target, err := h.resolve(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Method {
+	case "List":
+		if target.Virtual {
+			return listerAt(mountInfos()), nil
+		}
+		entries, err := os.ReadDir(target.FullPath)
+		if err != nil {
+			return nil, err
+		}
+		var list []os.FileInfo
+		for _, entry := range entries {
+			if target.IsRoot() && entry.Name() == storage.MetaDirName {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			list = append(list, newEntryInfo(target, filepath.Join(target.FullPath, entry.Name()), info))
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+		return listerAt(list), nil
+
+	case "Stat":
+		if target.Virtual {
+			return listerAt{virtualRootInfo{}}, nil
+		}
+		info, err := os.Stat(target.FullPath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{newEntryInfo(target, target.FullPath, info)}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename 实现 posix-rename@openssh.com 扩展，允许覆盖已有文件
+func (h *sftpHandler) PosixRename(r *sftp.Request) error {
+	start := time.Now()
+	err := h.rename(r, true)
+	h.record(audit.Entry{Action: "move", Path: r.Filepath, Target: r.Target}, start, err)
+	return err
+}
+
+// rename 在同一存储根目录内移动文件或目录，SFTP v3 的 rename 不覆盖已有文件
+func (h *sftpHandler) rename(r *sftp.Request, overwrite bool) error {
+	from, err := h.writable(r.Filepath)
+	if err != nil {
+		return err
+	}
+	to, err := h.writable(r.Target)
+	if err != nil {
+		return err
+	}
+	if from.Root != to.Root {
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	info, err := os.Lstat(from.FullPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(to.FullPath); err == nil && !overwrite {
+		return os.ErrExist
+	}
+	if err := renameFile(from, from.FullPath, to.FullPath, info.IsDir()); err != nil {
+		return err
+	}
+	log.Printf("[SFTP] 已移动 - 用户: %s, %s -> %s", h.user, from.FullPath, to.FullPath)
+	return nil
+}
+
+// listerAt 实现 sftp.ListerAt 接口
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(f []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(f, l[offset:])
+	if n < len(f) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// sftpUpload 写入中的文件，关闭时提交上传
+//
+// pkg/sftp 会从多个 goroutine 并发调用 WriteAt，写入和大小、错误的记录由 mu 保护。
+type sftpUpload struct {
+	mu      sync.Mutex
+	upload  *uploadFile
+	target  *storage.Target
+	user    string
+	handler *sftpHandler
+	path    string
+	start   time.Time
+	size    int64
+	err     error // 写入过程中的错误（包括超出配额），关闭时丢弃文件
+}
+
+func (f *sftpUpload) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.upload.WriteAt(p, off)
+	if err != nil {
+		f.err = err
+	}
+	if end := off + int64(n); end > f.size {
+		f.size = end
+	}
+	return n, err
+}
+
+func (f *sftpUpload) Close() (err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer func() {
+		f.handler.record(audit.Entry{Action: "upload", Path: f.path, BytesIn: f.size}, f.start, err)
+	}()
+
+	if f.err != nil {
+		f.upload.Abort()
+		log.Printf("[SFTP] 错误: 写入失败，已丢弃文件 %s - %v", f.target.FullPath, f.err)
+		return f.err
+	}
+	if err := f.upload.Commit(); err != nil {
+		log.Printf("[SFTP] 错误: 无法保存文件 %s - %v", f.target.FullPath, err)
+		return err
+	}
+	log.Printf("[SFTP] 上传完成 - 用户: %s, 文件: %s, 大小: %d 字节", f.user, f.target.FullPath, f.size)
+	return nil
+}
+
+// sftpDownload 读取中的文件，统计发送的字节数，关闭时记录审计日志
+type sftpDownload struct {
+	*os.File
+	handler *sftpHandler
+	path    string
+	start   time.Time
+	n       atomic.Int64 // 客户端可能并发读取
+}
+
+func (f *sftpDownload) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off)
+	f.n.Add(int64(n))
+	return n, err
+}
+
+func (f *sftpDownload) Close() error {
+	err := f.File.Close()
+	f.handler.record(audit.Entry{Action: "download", Path: f.path, BytesOut: f.n.Load()}, f.start, nil)
+	return err
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fileSystem/internal/config"
+)
+
+func TestSFTPHostKey(t *testing.T) {
+	root := useTempStorage(t)
+
+	// 未配置 host_key 时在存储目录的内部数据目录中生成，再次启动时沿用
+	path := sftpHostKeyPath(&config.SFTPConfig{})
+	if want := filepath.Join(root, ".filesystem", "sftp_host_ed25519_key"); path != want {
+		t.Fatalf("sftpHostKeyPath() = %q, want %q", path, want)
+	}
+	first, err := loadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("host key file = %v, %v", info, err)
+	}
+	second, err := loadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.PublicKey().Marshal(), second.PublicKey().Marshal()) {
+		t.Error("host key regenerated on second load")
+	}
+
+	custom := filepath.Join(t.TempDir(), "keys", "host")
+	if got := sftpHostKeyPath(&config.SFTPConfig{HostKey: custom}); got != custom {
+		t.Errorf("sftpHostKeyPath() = %q, want %q", got, custom)
+	}
+}
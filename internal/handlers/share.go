@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/config"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/models"
+	"fileSystem/internal/share"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// createShareRequest 创建分享链接的请求体
+type createShareRequest struct {
+	Path         string `json:"path"`
+	ExpiresIn    int64  `json:"expiresIn"`    // 有效期（秒），0 表示永久有效
+	MaxDownloads int    `json:"maxDownloads"` // 最大下载次数，0 表示不限制
+	Password     string `json:"password"`     // 访问密码，可选
+}
+
+// rootPrefix 返回规范化后的根路由前缀，根路由为 "/" 时返回空字符串
+func rootPrefix() string {
+	rootPath := strings.Trim(config.Cfg.RootPath, "/")
+	if rootPath == "" {
+		return ""
+	}
+	return "/" + rootPath
+}
+
+// shareInfo 将分享链接转换为响应数据
+func shareInfo(s *share.Share) models.ShareInfo {
+	info := models.ShareInfo{
+		Token:        s.Token,
+		Path:         s.Path,
+		Name:         path.Base(s.Path),
+		IsDir:        s.IsDir,
+		CreatedAt:    s.CreatedAt,
+		ExpiresAt:    s.ExpiresAt,
+		MaxDownloads: s.MaxDownloads,
+		Downloads:    s.Downloads,
+		HasPassword:  s.HasPassword(),
+		URL:          rootPrefix() + "/s/" + s.Token,
+	}
+	if err := s.Usable(time.Now()); err != nil {
+		info.Status = err.Error()
+	}
+	return info
+}
+
+// CreateShare 创建分享链接
+func CreateShare(w http.ResponseWriter, r *http.Request) {
+	var req createShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[SHARE] 错误: 无法解析请求 - %v", err)
+		utils.SendError(w, "无效的请求", http.StatusBadRequest)
+		return
+	}
+	log.Printf("[SHARE] 创建分享链接 - 路径: %s, 有效期: %d 秒, 最大下载次数: %d, 密码: %v, 客户端IP: %s",
+		req.Path, req.ExpiresIn, req.MaxDownloads, req.Password != "", r.RemoteAddr)
+	audit.SetPath(r, req.Path, "")
+
+	if req.ExpiresIn < 0 || req.MaxDownloads < 0 {
+		utils.SendError(w, "有效期和下载次数不能为负数", http.StatusBadRequest)
+		return
+	}
+
+	target, err := storage.Resolve(req.Path)
+	if err != nil || target.Virtual || target.IsRoot() && target.Mount == nil {
+		log.Printf("[SHARE] 错误: 无效的路径 - path=%s, 错误: %v", req.Path, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil {
+		log.Printf("[SHARE] 错误: 文件不存在 - %s, 错误: %v", target.FullPath, err)
+		utils.SendError(w, "文件或目录不存在", http.StatusNotFound)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		expiresAt = &t
+	}
+
+	s, err := share.Create(strings.Trim(filepath.ToSlash(req.Path), "/"), info.IsDir(), expiresAt, req.MaxDownloads, req.Password)
+	if err != nil {
+		log.Printf("[SHARE] 错误: 无法创建分享链接 - %v", err)
+		utils.SendError(w, "无法创建分享链接", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SHARE] 成功: 已创建分享链接 %s -> %s", s.Token, s.Path)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: "分享链接已创建",
+		Data:    shareInfo(s),
+	})
+}
+
+// ListShares 列出所有分享链接
+func ListShares(w http.ResponseWriter, r *http.Request) {
+	list := share.List()
+	infos := make([]models.ShareInfo, 0, len(list))
+	for i := range list {
+		infos = append(infos, shareInfo(&list[i]))
+	}
+	log.Printf("[SHARE] 返回 %d 个分享链接", len(infos))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    infos,
+	})
+}
+
+// RevokeShare 撤销分享链接
+func RevokeShare(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if err := share.Revoke(token); err != nil {
+		log.Printf("[SHARE] 错误: 无法撤销分享链接 %s - %v", token, err)
+		if errors.Is(err, share.ErrNotFound) {
+			utils.SendError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		utils.SendError(w, "无法撤销分享链接", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[SHARE] 成功: 已撤销分享链接 %s", token)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Message: "分享链接已撤销",
+	})
+}
+
+// ServeSharePage 返回分享页面
+func ServeSharePage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, r, "share.html", "[SHARE]")
+}
+
+// openShare 校验公开请求中的令牌和密码，并解析要访问的文件
+func openShare(w http.ResponseWriter, r *http.Request) (*share.Share, *storage.Target, bool) {
+	token := mux.Vars(r)["token"]
+	// 目录分享可以访问其中的文件
+	sub := r.URL.Query().Get("path")
+	s, err := share.Get(token)
+	if err == nil {
+		err = s.Usable(time.Now())
+		// 下载次数用完前开始的下载仍可续传
+		if errors.Is(err, share.ErrExhausted) && share.Resuming(token, utils.ClientIP(r), sub) {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Printf("[SHARE] 错误: 分享链接不可用 %s - %v, 客户端IP: %s", token, err, r.RemoteAddr)
+		status := http.StatusGone
+		if errors.Is(err, share.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.SendError(w, err.Error(), status)
+		return nil, nil, false
+	}
+
+	// 密码只通过请求头传递，避免出现在访问日志、浏览历史和 Referer 中；
+	// 浏览器直接打开的下载地址使用 ShareTicket 签发的短期凭证代替密码
+	if s.HasPassword() && s.CheckTicket(r.URL.Query().Get("ticket"), utils.ClientIP(r), sub, time.Now()) {
+		log.Printf("[SHARE] 使用下载凭证访问 %s, 客户端IP: %s", token, r.RemoteAddr)
+	} else if err := s.CheckPassword(r.Header.Get("X-Share-Password")); err != nil {
+		log.Printf("[SHARE] 错误: 密码错误 %s, 客户端IP: %s", token, r.RemoteAddr)
+		utils.SendError(w, err.Error(), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	audit.SetPath(r, s.Path+"/"+sub, "")
+	if sub != "" && !s.IsDir {
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	target, err := storage.Resolve(s.Path + "/" + sub)
+	if err != nil || target.Virtual {
+		log.Printf("[SHARE] 错误: 无效的路径 - share=%s, path=%s, 错误: %v", token, sub, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return s, target, true
+}
+
+// ShareInfo 返回分享内容信息，目录分享时同时返回目录内容
+func ShareInfo(w http.ResponseWriter, r *http.Request) {
+	s, target, ok := openShare(w, r)
+	if !ok {
+		return
+	}
+	sub := strings.Trim(filepath.ToSlash(r.URL.Query().Get("path")), "/")
+	log.Printf("[SHARE] 访问分享 - 令牌: %s, 子路径: %s, 客户端IP: %s", s.Token, sub, r.RemoteAddr)
+
+	info, err := os.Stat(target.FullPath)
+	if err != nil {
+		log.Printf("[SHARE] 错误: 文件不存在 - %s, 错误: %v", target.FullPath, err)
+		utils.SendError(w, "文件或目录不存在", http.StatusNotFound)
+		return
+	}
+
+	result := shareInfo(s)
+	result.Path = ""
+	result.Name = filepath.Base(target.FullPath)
+	result.IsDir = info.IsDir()
+	if !info.IsDir() {
+		result.Size = info.Size()
+	} else {
+		entries, err := os.ReadDir(target.FullPath)
+		if err != nil {
+			log.Printf("[SHARE] 错误: 无法读取目录 %s - %v", target.FullPath, err)
+			utils.SendError(w, "无法读取文件列表", http.StatusInternalServerError)
+			return
+		}
+		for _, entry := range entries {
+			if target.IsRoot() && entry.Name() == storage.MetaDirName {
+				continue
+			}
+			entryInfo, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			fileInfo := models.FileInfo{
+				Name:      entry.Name(),
+				Size:      entryInfo.Size(),
+				ModTime:   entryInfo.ModTime(),
+				IsDir:     entry.IsDir(),
+				Extension: strings.TrimPrefix(filepath.Ext(entry.Name()), "."),
+				Path:      strings.TrimPrefix(sub+"/"+entry.Name(), "/"),
+			}
+			if !entry.IsDir() {
+				fileInfo.MimeType = mimetype.Detect(filepath.Join(target.FullPath, entry.Name()), entryInfo)
+			}
+			result.Files = append(result.Files, fileInfo)
+		}
+	}
+
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// ShareTicket 校验密码后签发下载凭证，返回可直接在浏览器中打开的下载地址。
+// 浏览器原生下载不占用页面内存，中断后也能续传
+func ShareTicket(w http.ResponseWriter, r *http.Request) {
+	s, target, ok := openShare(w, r)
+	if !ok {
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil {
+		log.Printf("[SHARE] 错误: 文件不存在 - %s, 错误: %v", target.FullPath, err)
+		utils.SendError(w, "文件不存在", http.StatusNotFound)
+		return
+	}
+	if info.IsDir() {
+		utils.SendError(w, "不能下载目录", http.StatusBadRequest)
+		return
+	}
+
+	sub := r.URL.Query().Get("path")
+	ticket, expiresAt := s.Ticket(utils.ClientIP(r), sub, time.Now())
+	query := url.Values{"ticket": {ticket}}
+	if sub != "" {
+		query.Set("path", sub)
+	}
+	log.Printf("[SHARE] 已签发下载凭证 - 令牌: %s, 文件: %s, 有效期至: %s, 客户端IP: %s",
+		s.Token, target.FullPath, expiresAt.Format(time.RFC3339), r.RemoteAddr)
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data: models.ShareTicket{
+			URL:       rootPrefix() + "/s/" + s.Token + "/download?" + query.Encode(),
+			ExpiresAt: expiresAt,
+		},
+	})
+}
+
+// ShareDownload 通过分享链接下载文件
+func ShareDownload(w http.ResponseWriter, r *http.Request) {
+	s, target, ok := openShare(w, r)
+	if !ok {
+		return
+	}
+	fullPath := target.FullPath
+	log.Printf("[SHARE] 下载请求 - 令牌: %s, 文件: %s, 客户端IP: %s", s.Token, fullPath, r.RemoteAddr)
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		log.Printf("[SHARE] 错误: 文件不存在 - %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "文件不存在", http.StatusNotFound)
+		return
+	}
+	if info.IsDir() {
+		utils.SendError(w, "不能下载目录", http.StatusBadRequest)
+		return
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		log.Printf("[SHARE] 错误: 无法打开文件 - %s, 错误: %v", fullPath, err)
+		utils.SendError(w, "无法打开文件", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	filename := filepath.Base(fullPath)
+	sums, _ := checksum.Load(target.Root, fullPath, info)
+	w.Header().Set("Content-Disposition", utils.ContentDisposition("attachment", filename))
+	w.Header().Set("Content-Type", mimetype.Header(mimetype.Detect(fullPath, info)))
+	w.Header().Set("ETag", checksum.ETag(sums, info))
+	if digest := checksum.DigestHeader(sums); digest != "" {
+		w.Header().Set("Digest", digest)
+	}
+
+	// 只有真正发送内容（200、206）时才计数，304、412、416 等条件请求的响应不计数；
+	// 同一客户端续传时不重复计数
+	cw := &downloadCounter{ResponseWriter: w, record: func() error {
+		return share.RecordDownload(s.Token, utils.ClientIP(r), r.URL.Query().Get("path"))
+	}}
+	http.ServeContent(cw, r, filename, info.ModTime(), file)
+	if cw.err != nil {
+		log.Printf("[SHARE] 错误: 无法记录下载 %s - %v", s.Token, cw.err)
+		return
+	}
+	log.Printf("[SHARE] 成功: 已通过分享链接 %s 发送文件 %s, 大小: %s, 状态码: %d",
+		s.Token, filename, utils.FormatSize(info.Size()), cw.status)
+}
+
+// errDownloadRefused 下载次数已用完，停止发送内容
+var errDownloadRefused = errors.New("下载未计数，停止发送")
+
+// downloadCounter 在 http.ServeContent 确定返回 200 或 206 时记录一次下载，
+// 记录失败（如下载次数已用完）时改为返回 410 并停止发送内容
+type downloadCounter struct {
+	http.ResponseWriter
+	record func() error
+	status int
+	err    error
+}
+
+func (w *downloadCounter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status == http.StatusOK || status == http.StatusPartialContent {
+		if w.err = w.record(); w.err != nil {
+			h := w.Header()
+			for _, k := range []string{"Content-Length", "Content-Range", "Content-Disposition", "Accept-Ranges", "Last-Modified", "ETag", "Digest"} {
+				h.Del(k)
+			}
+			w.status = http.StatusGone
+			utils.SendError(w.ResponseWriter, w.err.Error(), http.StatusGone)
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *downloadCounter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.err != nil {
+		return 0, errDownloadRefused
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// ReadFrom 转发给原始的 ResponseWriter，下载大文件时仍可使用 sendfile
+func (w *downloadCounter) ReadFrom(src io.Reader) (int64, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.err != nil {
+		return 0, errDownloadRefused
+	}
+	return io.Copy(w.ResponseWriter, src)
+}
+
+// Unwrap 供 http.ResponseController 访问原始的 ResponseWriter
+func (w *downloadCounter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fileSystem/internal/models"
+	"fileSystem/internal/share"
+
+	"github.com/gorilla/mux"
+)
+
+// shareRouter 与 main.go 中相同的公开分享路由
+func shareRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/s/{token}/info", ShareInfo).Methods("GET")
+	r.HandleFunc("/s/{token}/download", ShareDownload).Methods("GET")
+	r.HandleFunc("/s/{token}/ticket", ShareTicket).Methods("POST")
+	return r
+}
+
+// shareRequest 以指定客户端 IP 发送请求
+func shareRequest(method, target, ip string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.RemoteAddr = ip + ":12345"
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	shareRouter().ServeHTTP(w, r)
+	return w
+}
+
+func TestShareDownloadTicket(t *testing.T) {
+	root := useTempStorage(t)
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(root, "docs", name), []byte("content of "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := share.Create("docs", true, nil, 0, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "/s/" + s.Token
+	password := http.Header{"X-Share-Password": {"secret"}}
+
+	if w := shareRequest("POST", base+"/ticket?path=a.txt", "10.0.0.1", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("ticket without password: status = %d", w.Code)
+	}
+	if w := shareRequest("POST", base+"/ticket", "10.0.0.1", password); w.Code != http.StatusBadRequest {
+		t.Errorf("ticket for directory: status = %d", w.Code)
+	}
+	w := shareRequest("POST", base+"/ticket?path=a.txt", "10.0.0.1", password)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ticket: status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data models.ShareTicket `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	ticketURL := resp.Data.URL
+	if !strings.HasPrefix(ticketURL, base+"/download?") || !strings.Contains(ticketURL, "ticket=") || strings.Contains(ticketURL, "secret") {
+		t.Fatalf("ticket url = %q", ticketURL)
+	}
+
+	// 带凭证的地址可以直接下载，也可以续传
+	w = shareRequest("GET", ticketURL, "10.0.0.1", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "content of a.txt" {
+		t.Errorf("download with ticket: status = %d, body = %q", w.Code, w.Body)
+	}
+	w = shareRequest("GET", ticketURL, "10.0.0.1", http.Header{"Range": {"bytes=11-"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "a.txt" {
+		t.Errorf("resume with ticket: status = %d, body = %q", w.Code, w.Body)
+	}
+
+	// 凭证只对签发时的客户端和文件有效
+	tampered := ticketURL[:len(ticketURL)-1] + "0"
+	if strings.HasSuffix(ticketURL, "0") {
+		tampered = ticketURL[:len(ticketURL)-1] + "1"
+	}
+	rejected := map[string]string{
+		"other client":     "",
+		"other file":       strings.Replace(ticketURL, "path=a.txt", "path=b.txt", 1),
+		"tampered":         tampered,
+		"expired format":   strings.Replace(ticketURL, "ticket=", "ticket=1.", 1),
+		"no ticket at all": base + "/download?path=a.txt",
+	}
+	for name, target := range rejected {
+		ip := "10.0.0.1"
+		if target == "" {
+			target, ip = ticketURL, "10.0.0.2"
+		}
+		if w := shareRequest("GET", target, ip, nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestShareDownloadCounting(t *testing.T) {
+	root := useTempStorage(t)
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := share.Create("a.txt", false, nil, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	download := "/s/" + s.Token + "/download"
+	downloads := func() int {
+		got, err := share.Get(s.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Downloads
+	}
+
+	w := shareRequest("GET", download, "10.0.0.1", http.Header{"Range": {"bytes=100-"}})
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range: status = %d", w.Code)
+	}
+	w = shareRequest("GET", download, "10.0.0.1", http.Header{"If-Modified-Since": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional request: status = %d", w.Code)
+	}
+	if n := downloads(); n != 0 {
+		t.Fatalf("downloads after 416 and 304 = %d, want 0", n)
+	}
+
+	w = shareRequest("GET", download, "10.0.0.1", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "hello world" {
+		t.Fatalf("download: status = %d, body = %q", w.Code, w.Body)
+	}
+	if n := downloads(); n != 1 {
+		t.Errorf("downloads = %d, want 1", n)
+	}
+	etag := w.Header().Get("ETag")
+	if w := shareRequest("GET", download, "10.0.0.1", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status = %d", w.Code)
+	}
+	if w := shareRequest("GET", download, "10.0.0.2", nil); w.Code != http.StatusGone {
+		t.Errorf("exhausted share from another client: status = %d, want %d", w.Code, http.StatusGone)
+	}
+	if n := downloads(); n != 1 {
+		t.Errorf("downloads = %d, want 1", n)
+	}
+}
+
+func TestDownloadCounterRefused(t *testing.T) {
+	w := httptest.NewRecorder()
+	cw := &downloadCounter{ResponseWriter: w, record: func() error { return share.ErrExhausted }}
+	r := httptest.NewRequest("GET", "/s/x/download", nil)
+	r.Header.Set("Range", "bytes=0-4")
+	http.ServeContent(cw, r, "a.txt", time.Now(), strings.NewReader("hello world"))
+
+	if w.Code != http.StatusGone || cw.err != share.ErrExhausted {
+		t.Fatalf("status = %d, err = %v", w.Code, cw.err)
+	}
+	for _, k := range []string{"Content-Length", "Content-Range"} {
+		if v := w.Header().Get(k); v != "" {
+			t.Errorf("%s = %q left on error response", k, v)
+		}
+	}
+	if strings.Contains(w.Body.String(), "hello") {
+		t.Errorf("file content sent after refusal: %q", w.Body)
+	}
+}
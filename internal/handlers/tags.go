@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fileSystem/internal/config"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/tags"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// SetTags 替换文件或目录的标签和元数据，请求体为 {"tags": [...], "metadata": {...}}，两者都为空时清除
+func SetTags(w http.ResponseWriter, r *http.Request) {
+	filePath := mux.Vars(r)["filename"]
+	log.Printf("[TAGS] 请求开始 - 路径: %s, 客户端IP: %s", filePath, r.RemoteAddr)
+
+	var req tags.Entry
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[TAGS] 错误: 无法解析请求 - %v", err)
+		utils.SendError(w, "无效的请求", http.StatusBadRequest)
+		return
+	}
+	entry, err := req.Normalize()
+	if err != nil {
+		log.Printf("[TAGS] 错误: %v", err)
+		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.IsRoot() {
+		log.Printf("[TAGS] 错误: 无效的路径 - path=%s, 错误: %v", filePath, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+	if err := target.CheckWritable(); err != nil {
+		log.Printf("[TAGS] 错误: 目标位置不可写 - path=%s, 错误: %v", filePath, err)
+		utils.SendError(w, err.Error(), storageErrorStatus(err))
+		return
+	}
+	if _, err := os.Lstat(target.FullPath); err != nil {
+		log.Printf("[TAGS] 错误: 文件不存在 %s", target.FullPath)
+		utils.SendError(w, "文件不存在", http.StatusNotFound)
+		return
+	}
+
+	store, err := tags.For(target.Root)
+	if err == nil {
+		err = store.Set(target.FullPath, entry)
+	}
+	if err != nil {
+		log.Printf("[TAGS] 错误: 无法保存标签 %s - %v", target.FullPath, err)
+		utils.SendError(w, "无法保存标签", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[TAGS] 成功: %s - 标签: %v, 元数据: %d 项", target.FullPath, entry.Tags, len(entry.Metadata))
+	utils.SendJSON(w, models.Response{Success: true, Message: "标签已保存", Data: entry})
+}
+
+// SearchFiles 按标签和元数据搜索 path 目录下的文件
+//
+// tag 可重复，要求同时包含；meta 可重复，格式为 key=value 或 key（只要求存在）；q 匹配文件名、标签或元数据值。
+func SearchFiles(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	query := r.URL.Query()
+	path := query.Get("path")
+	log.Printf("[SEARCH] 请求开始 - 路径: %s, 标签: %v, 元数据: %v, 文字: %s, 客户端IP: %s",
+		path, query["tag"], query["meta"], query.Get("q"), r.RemoteAddr)
+
+	q := tags.Query{Text: strings.TrimSpace(query.Get("q"))}
+	for _, tag := range query["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			q.Tags = append(q.Tags, tag)
+		}
+	}
+	for _, meta := range query["meta"] {
+		key, value, _ := strings.Cut(meta, "=")
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if q.Metadata == nil {
+			q.Metadata = make(map[string]string)
+		}
+		q.Metadata[key] = strings.TrimSpace(value)
+	}
+	if len(q.Tags) == 0 && len(q.Metadata) == 0 && q.Text == "" {
+		utils.SendError(w, "至少需要一个搜索条件（tag、meta 或 q）", http.StatusBadRequest)
+		return
+	}
+
+	target, err := storage.Resolve(path)
+	if err != nil {
+		log.Printf("[SEARCH] 错误: 无效的路径 - path=%s, 错误: %v", path, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+
+	var results []models.FileInfo
+	if target.Virtual {
+		// 挂载点模式下的根目录，搜索所有挂载点
+		for _, m := range config.Mounts {
+			mountTarget, err := storage.Resolve(m.Name)
+			if err != nil {
+				continue
+			}
+			results = append(results, searchRoot(mountTarget, q)...)
+		}
+	} else {
+		q.Dir = target.FullPath
+		results = searchRoot(target, q)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	if len(results) > tags.MaxSearchHits {
+		results = results[:tags.MaxSearchHits]
+	}
+
+	log.Printf("[SEARCH] 成功: 找到 %d 个文件/目录, 耗时: %v", len(results), time.Since(startTime))
+	utils.SendJSON(w, models.Response{Success: true, Data: results})
+}
+
+// searchRoot 在一个存储根目录中搜索，跳过已不存在的文件
+func searchRoot(target *storage.Target, q tags.Query) []models.FileInfo {
+	store, err := tags.For(target.Root)
+	if err != nil {
+		log.Printf("[SEARCH] 警告: 无法加载标签数据 %s - %v", target.Root, err)
+		return nil
+	}
+
+	var results []models.FileInfo
+	for _, hit := range store.Search(q) {
+		info, err := os.Lstat(hit.FullPath)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(target.Root, hit.FullPath)
+		if err != nil {
+			continue
+		}
+		if target.Mount != nil {
+			rel = filepath.Join(target.Mount.Name, rel)
+		}
+		results = append(results, models.FileInfo{
+			Name:      info.Name(),
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+			IsDir:     info.IsDir(),
+			Extension: strings.TrimPrefix(filepath.Ext(info.Name()), "."),
+			Path:      filepath.ToSlash(rel),
+			ReadOnly:  target.Mount != nil && target.Mount.ReadOnly,
+			Tags:      hit.Entry.Tags,
+			Metadata:  hit.Entry.Metadata,
+		})
+	}
+	return results
+}
+
+// fillTags 为目录 dir 下的列表项补充标签和元数据
+func fillTags(root, dir string, items []models.FileInfo) {
+	store, err := tags.For(root)
+	if err != nil {
+		log.Printf("[TAGS] 警告: 无法加载标签数据 %s - %v", root, err)
+		return
+	}
+	for i := range items {
+		if e, ok := store.Get(filepath.Join(dir, items[i].Name)); ok {
+			items[i].Tags = e.Tags
+			items[i].Metadata = e.Metadata
+		}
+	}
+}
+
+// removeTags 文件或目录删除后清除其标签
+func removeTags(root, fullPath string, isDir bool) {
+	store, err := tags.For(root)
+	if err == nil {
+		err = store.Remove(fullPath, isDir)
+	}
+	if err != nil && !errors.Is(err, storage.ErrInvalidPath) {
+		log.Printf("[TAGS] 警告: 无法删除标签 %s - %v", fullPath, err)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"fileSystem/internal/storage"
+	"fileSystem/internal/thumbnail"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// Thumbnail 返回图片的 JPEG 缩略图（支持 JPEG/PNG/GIF/WebP），size 参数为边长，默认 256
+func Thumbnail(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	filePath := mux.Vars(r)["filename"]
+
+	size := thumbnail.DefaultSize
+	if s := r.URL.Query().Get("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || !thumbnail.ValidSize(n) {
+			utils.SendError(w, "无效的缩略图尺寸（可选: 128, 256, 512）", http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.Virtual || target.IsRoot() {
+		log.Printf("[THUMBNAIL] 错误: 无效的文件路径 - filePath=%s, 错误: %v", filePath, err)
+		utils.SendError(w, "无效的文件路径", http.StatusBadRequest)
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if os.IsNotExist(err) {
+		utils.SendError(w, "文件不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil || info.IsDir() || !thumbnail.Supported(info.Name()) {
+		utils.SendError(w, thumbnail.ErrUnsupported.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	data, err := thumbnail.Get(target.Root, target.FullPath, info, size)
+	if err != nil {
+		log.Printf("[THUMBNAIL] 错误: 无法生成缩略图 %s - %v", target.FullPath, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, thumbnail.ErrUnsupported) || errors.Is(err, thumbnail.ErrTooLarge) {
+			status = http.StatusUnsupportedMediaType
+		}
+		utils.SendError(w, err.Error(), status)
+		return
+	}
+
+	// 缩略图随源文件的修改时间变化，浏览器需重新验证
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-cache")
+	log.Printf("[THUMBNAIL] 成功: %s (%d px, %s), 耗时: %v", filePath, size, utils.FormatSize(int64(len(data))), time.Since(startTime))
+	http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(data))
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/dedup"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/filedb"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/tags"
+	"fileSystem/internal/thumbnail"
+	"fileSystem/internal/utils"
+)
+
+// uploadFile 上传写入的目标文件，写入时同时计算摘要
+//
+// 普通模式下先写入目标目录中的临时文件，Commit 时改名为目标文件，失败时原有文件不受影响；
+// 去重模式下先写入 blob 目录中的临时文件，Commit 时按内容入库并在目标路径创建引用。
+type uploadFile struct {
+	file     *os.File
+	fullPath string
+	root     string
+	hasher   *checksum.Hasher
+	store    *dedup.Store
+	quota    *storage.Reservation // 写入时逐步预留挂载点配额
+	size     int64                // 已写入内容的大小（按偏移量写入时为最大的结束位置）
+	closed   bool
+	random   bool          // 是否按偏移量写入过，此时需要在提交前重新计算摘要
+	modTime  time.Time     // 客户端指定的修改时间，为零时使用写入时间
+	started  time.Time     // 开始写入的时间
+	origin   filedb.Upload // 上传者信息，提交时补充耗时和速度后写入上传历史
+}
+
+// createUploadFile 创建上传目标文件，extraAlgos 为除配置外需要额外计算的摘要算法
+func createUploadFile(target *storage.Target, fullPath string, extraAlgos ...string) (*uploadFile, error) {
+	u := &uploadFile{
+		fullPath: fullPath,
+		root:     target.Root,
+		hasher:   checksum.NewHasher(extraAlgos...),
+		started:  time.Now(),
+	}
+	// 覆盖原有文件时，完成后原文件占用的空间会被释放
+	u.quota = target.Reserve(existingSize(fullPath))
+
+	var err error
+	if dedup.Enabled() {
+		u.store, err = dedup.For(target.Root)
+		if err != nil {
+			return nil, err
+		}
+		u.file, err = u.store.CreateTemp()
+	} else {
+		u.file, err = os.CreateTemp(filepath.Dir(fullPath), storage.UploadTempPrefix+filepath.Base(fullPath)+"-*.tmp")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 临时文件默认权限为 0600，与直接创建的文件保持一致
+	if err := u.file.Chmod(0644); err != nil {
+		u.Abort()
+		return nil, err
+	}
+	return u, nil
+}
+
+// Write 实现 io.Writer 接口，超出挂载点配额时返回 storage.ErrQuotaExceeded
+func (u *uploadFile) Write(p []byte) (int, error) {
+	if err := u.quota.Extend(u.size + int64(len(p))); err != nil {
+		return 0, err
+	}
+	n, err := u.file.Write(p)
+	u.hasher.Write(p[:n])
+	u.size += int64(n)
+	return n, err
+}
+
+// WriteAt 实现 io.WriterAt 接口，用于 SFTP 等可能乱序写入的客户端
+func (u *uploadFile) WriteAt(p []byte, off int64) (int, error) {
+	end := off + int64(len(p))
+	if err := u.quota.Extend(end); err != nil {
+		return 0, err
+	}
+	u.random = true
+	n, err := u.file.WriteAt(p, off)
+	if end := off + int64(n); end > u.size {
+		u.size = end
+	}
+	return n, err
+}
+
+// Name 返回正在写入的磁盘文件路径
+func (u *uploadFile) Name() string {
+	return u.file.Name()
+}
+
+// Checksums 返回已写入内容的摘要
+func (u *uploadFile) Checksums() models.Checksums {
+	return u.hasher.Sum()
+}
+
+// Close 关闭文件，可重复调用
+func (u *uploadFile) Close() error {
+	if u.closed {
+		return nil
+	}
+	u.closed = true
+	return u.file.Close()
+}
+
+// Abort 放弃上传并删除临时文件，目标路径上原有的文件保持不变
+func (u *uploadFile) Abort() {
+	u.Close()
+	os.Remove(u.file.Name())
+	dirstats.Invalidate(u.fullPath, false)
+	u.quota.Release()
+}
+
+// Commit 完成上传，去重模式下将内容入库，并保存文件摘要
+func (u *uploadFile) Commit() error {
+	// 目录统计失效后才释放预留的配额，期间写入的内容不会漏算
+	defer u.quota.Release()
+	defer dirstats.Invalidate(u.fullPath, false)
+	if err := u.Close(); err != nil {
+		os.Remove(u.file.Name())
+		return err
+	}
+
+	if u.random {
+		if err := u.rehash(); err != nil {
+			os.Remove(u.file.Name())
+			return err
+		}
+	}
+
+	sums := u.Checksums()
+	if u.store != nil {
+		log.Printf("[DEDUP] 上传内容 SHA-256: %s, 目标: %s", sums.SHA256, u.fullPath)
+		if err := u.store.Ingest(u.file.Name(), sums.SHA256, u.fullPath); err != nil {
+			return err
+		}
+	} else if err := os.Rename(u.file.Name(), u.fullPath); err != nil {
+		os.Remove(u.file.Name())
+		return err
+	}
+
+	// 摘要记录包含修改时间，需在设置修改时间之后保存
+	if !u.modTime.IsZero() {
+		u.setModTime()
+	}
+	if err := checksum.Save(u.root, u.fullPath, sums); err != nil {
+		log.Printf("[UPLOAD] 警告: 无法保存文件摘要 %s - %v", u.fullPath, err)
+	}
+	// 调用方未提供传输统计时按写入时间估算
+	origin := u.origin
+	if origin.Duration == 0 {
+		origin.Duration = time.Since(u.started)
+	}
+	if origin.Size == 0 {
+		if info, err := os.Stat(u.fullPath); err == nil {
+			origin.Size = info.Size()
+		}
+	}
+	if origin.Speed == 0 && origin.Duration > 0 {
+		origin.Speed = float64(origin.Size) / origin.Duration.Seconds()
+	}
+	recordUpload(u.root, u.fullPath, origin)
+	return nil
+}
+
+// setModTime 设置客户端指定的修改时间。与其他文件共用 blob 的硬链接修改时间不同时，
+// 先改为独立副本，否则其他文件的修改时间和摘要记录会一起变化
+func (u *uploadFile) setModTime() {
+	info, err := os.Stat(u.fullPath)
+	if err == nil && info.ModTime().Equal(u.modTime) {
+		return
+	}
+	if u.store != nil && u.store.Shared(u.fullPath) {
+		if err := u.store.Detach(u.fullPath); err != nil {
+			log.Printf("[UPLOAD] 警告: 内容与其他文件共用且无法复制，不设置修改时间 %s - %v", u.fullPath, err)
+			return
+		}
+	}
+	if err := os.Chtimes(u.fullPath, time.Now(), u.modTime); err != nil {
+		log.Printf("[UPLOAD] 警告: 无法设置修改时间 %s - %v", u.fullPath, err)
+	}
+}
+
+// existingSize 路径上已有的普通文件的大小，不存在或不是普通文件时为 0
+func existingSize(fullPath string) int64 {
+	if info, err := os.Lstat(fullPath); err == nil && info.Mode().IsRegular() {
+		return info.Size()
+	}
+	return 0
+}
+
+// rehash 从头读取已写入的文件重新计算摘要
+func (u *uploadFile) rehash() error {
+	f, err := os.Open(u.file.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	u.hasher = checksum.NewHasher(u.hasher.Algos()...)
+	_, err = io.Copy(u.hasher, f)
+	return err
+}
+
+// validFilename 判断客户端提供的文件名能否直接作为目标目录下的文件名：
+// 不能为空、"."、内部数据目录名，不能包含路径分隔符或 ".."
+func validFilename(name string) bool {
+	if name == "" || name == "." || name == storage.MetaDirName {
+		return false
+	}
+	if strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	return filepath.Base(name) == name
+}
+
+// uploadOrigin 根据请求生成上传者信息，via 为上传方式
+func uploadOrigin(r *http.Request, via string) filedb.Upload {
+	ip := utils.ClientIP(r)
+	return filedb.Upload{Uploader: ip, IP: ip, UserAgent: r.UserAgent(), Via: via}
+}
+
+// recordUpload 上传完成后写入文件数据库
+func recordUpload(root, fullPath string, origin filedb.Upload) {
+	db, err := filedb.For(root)
+	if err == nil {
+		err = db.Uploaded(fullPath, origin)
+	}
+	if err != nil {
+		log.Printf("[FILEDB] 警告: 无法写入文件记录 %s - %v", fullPath, err)
+	}
+}
+
+// releaseFile 文件或目录删除后清理文件记录、摘要记录、标签、缩略图缓存、目录统计并释放去重存储中的引用
+func releaseFile(target *storage.Target, fullPath string, isDir bool) {
+	dirstats.Invalidate(fullPath, isDir)
+	removeTags(target.Root, fullPath, isDir)
+	if db, err := filedb.For(target.Root); err != nil {
+		log.Printf("[FILEDB] 警告: %v", err)
+	} else if err := db.Remove(fullPath, isDir); err != nil {
+		log.Printf("[FILEDB] 警告: 无法删除文件记录 %s - %v", fullPath, err)
+	}
+	if err := checksum.Remove(target.Root, fullPath, isDir); err != nil {
+		log.Printf("[DELETE] 警告: 无法删除摘要记录 %s - %v", fullPath, err)
+	}
+	if err := thumbnail.Remove(target.Root, fullPath); err != nil {
+		log.Printf("[DELETE] 警告: 无法删除缩略图缓存 %s - %v", fullPath, err)
+	}
+
+	if !dedup.Enabled() {
+		return
+	}
+	store, err := dedup.For(target.Root)
+	if err == nil {
+		if isDir {
+			err = store.ReleaseTree(fullPath)
+		} else {
+			err = store.Release(fullPath)
+		}
+	}
+	if err != nil {
+		log.Printf("[DEDUP] 警告: 无法释放引用 %s - %v", fullPath, err)
+	}
+}
+
+// renameFile 在同一存储根目录内移动文件或目录，并同步摘要记录、标签和去重索引
+func renameFile(target *storage.Target, oldPath, newPath string, isDir bool) error {
+	replaced, statErr := os.Lstat(newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	dirstats.Invalidate(oldPath, isDir)
+	dirstats.Invalidate(newPath, isDir)
+	// 覆盖了已有文件时先释放其摘要记录和去重引用
+	if statErr == nil && !replaced.IsDir() {
+		releaseFile(target, newPath, false)
+	}
+	if err := checksum.Rename(target.Root, oldPath, newPath, isDir); err != nil {
+		log.Printf("[MOVE] 警告: 无法移动摘要记录 %s - %v", oldPath, err)
+	}
+	if store, err := tags.For(target.Root); err != nil {
+		log.Printf("[MOVE] 警告: 无法加载标签数据 %s - %v", target.Root, err)
+	} else if err := store.Rename(oldPath, newPath, isDir); err != nil {
+		log.Printf("[MOVE] 警告: 无法移动标签 %s - %v", oldPath, err)
+	}
+	if db, err := filedb.For(target.Root); err != nil {
+		log.Printf("[FILEDB] 警告: %v", err)
+	} else if err := db.Rename(oldPath, newPath, isDir); err != nil {
+		log.Printf("[FILEDB] 警告: 无法移动文件记录 %s - %v", oldPath, err)
+	}
+	// 缩略图缓存按需重新生成
+	if err := thumbnail.Remove(target.Root, oldPath); err != nil {
+		log.Printf("[MOVE] 警告: 无法删除缩略图缓存 %s - %v", oldPath, err)
+	}
+
+	if !dedup.Enabled() {
+		return nil
+	}
+	store, err := dedup.For(target.Root)
+	if err == nil {
+		err = store.Rename(oldPath, newPath)
+	}
+	if err != nil {
+		log.Printf("[DEDUP] 警告: 无法更新索引 %s -> %s - %v", oldPath, newPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/config"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/models"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+)
+
+const (
+	defaultUsageDepth = 2
+	maxUsageDepth     = 5
+	defaultUsageTop   = 10
+	maxUsageTop       = 50
+)
+
+// DiskUsage 空间分析：返回目录下占用空间最大的子目录和文件
+//
+// depth 为展开的层数（默认 2），top 为每层保留的项数（默认 10），其余项合并为一个“其他”项。
+func DiskUsage(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	query := r.URL.Query()
+	path := query.Get("path")
+	log.Printf("[USAGE] 请求开始 - 路径参数: %s, depth: %s, top: %s, 客户端IP: %s",
+		path, query.Get("depth"), query.Get("top"), r.RemoteAddr)
+
+	depth, err := intParam(query.Get("depth"), defaultUsageDepth, 1, maxUsageDepth)
+	if err != nil {
+		utils.SendError(w, fmt.Sprintf("无效的 depth 参数（1-%d）", maxUsageDepth), http.StatusBadRequest)
+		return
+	}
+	top, err := intParam(query.Get("top"), defaultUsageTop, 1, maxUsageTop)
+	if err != nil {
+		utils.SendError(w, fmt.Sprintf("无效的 top 参数（1-%d）", maxUsageTop), http.StatusBadRequest)
+		return
+	}
+
+	target, err := storage.Resolve(path)
+	if err != nil {
+		log.Printf("[USAGE] 错误: 无效的路径 - path=%s, 错误: %v", path, err)
+		utils.SendError(w, "无效的路径", http.StatusBadRequest)
+		return
+	}
+
+	var root models.UsageNode
+	if target.Virtual {
+		// 挂载点模式下的根目录，各挂载点作为子项
+		root = models.UsageNode{Name: "根目录", IsDir: true}
+		for _, m := range config.Mounts {
+			dir, err := filepath.Abs(m.Path)
+			if err != nil {
+				continue
+			}
+			stats, err := dirstats.Compute(dir)
+			if err != nil {
+				log.Printf("[USAGE] 警告: 无法统计挂载点 %s - %v", dir, err)
+				continue
+			}
+			node := usageTree(dir, m.Name, m.Name, stats, depth-1, top)
+			root.Size += node.Size
+			root.Files += node.Files
+			root.Children = append(root.Children, node)
+		}
+		root.Children = topUsage(root.Children, top)
+		root.SizeText = utils.FormatSize(root.Size)
+	} else {
+		info, err := os.Stat(target.FullPath)
+		if os.IsNotExist(err) {
+			utils.SendError(w, "目录不存在", http.StatusNotFound)
+			return
+		}
+		if err != nil || !info.IsDir() {
+			utils.SendError(w, "不是目录", http.StatusBadRequest)
+			return
+		}
+		stats, err := dirstats.Compute(target.FullPath)
+		if err != nil {
+			log.Printf("[USAGE] 错误: 无法统计目录 %s - %v", target.FullPath, err)
+			utils.SendError(w, "无法统计目录", http.StatusInternalServerError)
+			return
+		}
+
+		name := filepath.Base(target.FullPath)
+		if target.IsRoot() {
+			name = "根目录"
+			if target.Mount != nil {
+				name = target.Mount.Name
+			}
+		}
+		root = usageTree(target.FullPath, strings.Trim(path, "/"), name, stats, depth, top)
+	}
+
+	log.Printf("[USAGE] 成功: %s 共 %s, 耗时: %v", target.FullPath, root.SizeText, time.Since(startTime))
+	utils.SendJSON(w, models.Response{
+		Success: true,
+		Data:    root,
+	})
+}
+
+// usageTree 根据统计结果构建目录的空间分析树，只展开每层占用最大的 top 个子目录，depth 为剩余展开层数
+func usageTree(dir, relPath, name string, stats map[string]dirstats.Stats, depth, top int) models.UsageNode {
+	st := stats[dir]
+	node := models.UsageNode{
+		Name:     name,
+		Path:     relPath,
+		IsDir:    true,
+		Size:     st.Size,
+		SizeText: utils.FormatSize(st.Size),
+		Files:    st.Files,
+	}
+	if depth <= 0 {
+		return node
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return node
+	}
+
+	var children []models.UsageNode
+	for _, e := range entries {
+		fullPath := filepath.Join(dir, e.Name())
+		childPath := filepath.Join(relPath, e.Name())
+		if e.IsDir() {
+			sub, ok := stats[fullPath]
+			if !ok {
+				continue
+			}
+			children = append(children, models.UsageNode{Name: e.Name(), Path: childPath, IsDir: true, Size: sub.Size, Files: sub.Files})
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		children = append(children, models.UsageNode{Name: e.Name(), Path: childPath, Size: info.Size()})
+	}
+
+	node.Children = topUsage(children, top)
+	for i, c := range node.Children {
+		if c.IsDir {
+			node.Children[i] = usageTree(filepath.Join(dir, c.Name), c.Path, c.Name, stats, depth-1, top)
+		}
+	}
+	return node
+}
+
+// topUsage 按大小排序，保留前 top 项，其余合并为“其他”
+func topUsage(nodes []models.UsageNode, top int) []models.UsageNode {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Size != nodes[j].Size {
+			return nodes[i].Size > nodes[j].Size
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	if len(nodes) > top {
+		other := models.UsageNode{Other: len(nodes) - top}
+		for _, n := range nodes[top:] {
+			other.Size += n.Size
+			if n.IsDir {
+				other.Files += n.Files
+			} else {
+				other.Files++
+			}
+		}
+		other.Name = fmt.Sprintf("其他 %d 项", other.Other)
+		nodes = append(nodes[:top], other)
+	}
+	for i := range nodes {
+		nodes[i].SizeText = utils.FormatSize(nodes[i].Size)
+	}
+	return nodes
+}
+
+// intParam 解析整数查询参数，为空时返回默认值
+func intParam(s string, def, lo, hi int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("参数超出范围")
+	}
+	return n, nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"fileSystem/internal/checksum"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/storage"
+	"fileSystem/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// passiveType 判断类型是否不会被浏览器当作可执行脚本的文档打开，其他类型预览时一律放入沙箱。
+// 使用白名单而不是黑名单：各种 XML 类型（如 application/rdf+xml）都可能嵌入脚本
+func passiveType(ctype string) bool {
+	switch {
+	case ctype == "image/svg+xml":
+		return false
+	case strings.HasPrefix(ctype, "image/"), strings.HasPrefix(ctype, "audio/"), strings.HasPrefix(ctype, "video/"):
+		return true
+	}
+	return ctype == "text/plain" || ctype == "application/pdf"
+}
+
+// ViewFile 以 inline 方式返回文件内容，供浏览器内预览
+//
+// 支持 Range 请求，音视频可以拖动播放进度；图片、音视频、纯文本和 PDF 以外的类型加上 CSP sandbox，防止脚本在本站点下执行。
+func ViewFile(w http.ResponseWriter, r *http.Request) {
+	filePath := mux.Vars(r)["filename"]
+	log.Printf("[VIEW] 请求开始 - 文件路径: %s, Range: %s, 客户端IP: %s", filePath, r.Header.Get("Range"), r.RemoteAddr)
+
+	target, err := storage.Resolve(filePath)
+	if err != nil || target.Virtual || target.IsRoot() {
+		log.Printf("[VIEW] 错误: 无效的文件路径 - filePath=%s, 错误: %v", filePath, err)
+		utils.SendError(w, "无效的文件路径", http.StatusBadRequest)
+		return
+	}
+	info, err := os.Stat(target.FullPath)
+	if os.IsNotExist(err) {
+		utils.SendError(w, "文件不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[VIEW] 错误: 无法获取文件信息 - %s, 错误: %v", target.FullPath, err)
+		utils.SendError(w, "无法访问文件", http.StatusInternalServerError)
+		return
+	}
+	if info.IsDir() {
+		utils.SendError(w, "不能预览目录", http.StatusBadRequest)
+		return
+	}
+
+	file, err := os.Open(target.FullPath)
+	if err != nil {
+		log.Printf("[VIEW] 错误: 无法打开文件 - %s, 错误: %v", target.FullPath, err)
+		utils.SendError(w, "无法打开文件", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	name := info.Name()
+	ctype := mimetype.Detect(target.FullPath, info)
+	w.Header().Set("Content-Type", mimetype.Header(ctype))
+	w.Header().Set("Content-Disposition", utils.ContentDisposition("inline", name))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !passiveType(ctype) {
+		w.Header().Set("Content-Security-Policy", "sandbox")
+	}
+
+	sums, _ := checksum.Load(target.Root, target.FullPath, info)
+	w.Header().Set("ETag", checksum.ETag(sums, info))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestViewSandbox(t *testing.T) {
+	root := useTempStorage(t)
+	tests := []struct {
+		name    string
+		sandbox bool
+	}{
+		{"a.html", true},
+		{"a.svg", true},
+		{"a.xml", true},
+		{"a.rdf", true},
+		{"a.mml", true},
+		{"a.xslt", true},
+		{"a.xhtml", true},
+		{"a.js", true},
+		// 未知扩展名按内容识别为纯文本，nosniff 下浏览器不会当作文档解析
+		{"a.unknownext", false},
+		{"a.png", false},
+		{"a.jpg", false},
+		{"a.mp4", false},
+		{"a.mp3", false},
+		{"a.txt", false},
+		{"a.pdf", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(root, tt.name), []byte(`<html:script xmlns:html="http://www.w3.org/1999/xhtml">alert(1)</html:script>`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			ViewFile(w, mux.SetURLVars(httptest.NewRequest("GET", "/api/view/"+tt.name, nil), map[string]string{"filename": tt.name}))
+			csp := w.Header().Get("Content-Security-Policy")
+			if (csp == "sandbox") != tt.sandbox {
+				t.Errorf("Content-Type %q: Content-Security-Policy = %q, want sandbox %v", w.Header().Get("Content-Type"), csp, tt.sandbox)
+			}
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fileSystem/internal/audit"
+	"fileSystem/internal/dirstats"
+	"fileSystem/internal/filedb"
+	"fileSystem/internal/mimetype"
+	"fileSystem/internal/storage"
+
+	"golang.org/x/net/webdav"
+)
+
+// davFS 将 WebDAV 请求映射到存储目录，与 REST 接口共用路径解析、挂载点和去重逻辑
+type davFS struct{}
+
+// resolve 解析 WebDAV 路径，路径无效时返回 os.ErrNotExist
+func (davFS) resolve(name string) (*storage.Target, error) {
+	target, err := storage.Resolve(name)
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	return target, nil
+}
+
+// writable 解析需要写入的路径，虚拟根目录、存储根目录和只读挂载点不允许修改
+func (d davFS) writable(name string) (*storage.Target, error) {
+	target, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsRoot() || target.CheckWritable() != nil {
+		return nil, os.ErrPermission
+	}
+	return target, nil
+}
+
+func (d davFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	target, err := d.resolve(name)
+	if err != nil {
+		return err
+	}
+	if target.IsRoot() {
+		return os.ErrExist
+	}
+	if target.CheckWritable() != nil {
+		return os.ErrPermission
+	}
+	if err := os.Mkdir(target.FullPath, 0755); err != nil {
+		return err
+	}
+	dirstats.Invalidate(target.FullPath, false)
+	return nil
+}
+
+func (d davFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return d.create(ctx, name)
+	}
+
+	target, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if target.Virtual {
+		return &davMounts{}, nil
+	}
+	f, err := os.Open(target.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	return &davFile{File: f, target: target}, nil
+}
+
+// create 打开写入文件，WebDAV 的 PUT 和 COPY 总是整体替换文件内容，
+// 因此统一按上传处理：计算摘要、检查配额，去重模式下关闭时入库
+func (d davFS) create(ctx context.Context, name string) (webdav.File, error) {
+	target, err := d.writable(name)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := os.Stat(filepath.Dir(target.FullPath))
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	if info, err := os.Stat(target.FullPath); err == nil && info.IsDir() {
+		return nil, os.ErrPermission
+	}
+
+	u, err := createUploadFile(target, target.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	u.origin, _ = ctx.Value(originKey{}).(filedb.Upload)
+	log.Printf("[WEBDAV] 开始写入文件: %s", target.FullPath)
+	return &davUpload{upload: u, target: target}, nil
+}
+
+func (d davFS) RemoveAll(ctx context.Context, name string) error {
+	target, err := d.writable(name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(target.FullPath)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(target.FullPath); err != nil {
+		return err
+	}
+	releaseFile(target, target.FullPath, info.IsDir())
+	log.Printf("[WEBDAV] 已删除: %s", target.FullPath)
+	return nil
+}
+
+func (d davFS) Rename(ctx context.Context, oldName, newName string) error {
+	from, err := d.writable(oldName)
+	if err != nil {
+		return err
+	}
+	to, err := d.writable(newName)
+	if err != nil {
+		return err
+	}
+	// 不同挂载点之间不能直接移动，请求处理时已返回 502，这里只作为兜底
+	if from.Root != to.Root {
+		return os.ErrPermission
+	}
+	info, err := os.Lstat(from.FullPath)
+	if err != nil {
+		return err
+	}
+	if err := renameFile(from, from.FullPath, to.FullPath, info.IsDir()); err != nil {
+		return err
+	}
+	log.Printf("[WEBDAV] 已移动: %s -> %s", from.FullPath, to.FullPath)
+	return nil
+}
+
+func (d davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	target, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if target.Virtual {
+		return virtualRootInfo{}, nil
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	return newEntryInfo(target, target.FullPath, info), nil
+}
+
+// davFile 只读打开的文件或目录，列目录时隐藏内部数据目录
+type davFile struct {
+	*os.File
+	target *storage.Target
+}
+
+func (f *davFile) Readdir(count int) ([]fs.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	list := make([]fs.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if f.target.IsRoot() && info.Name() == storage.MetaDirName {
+			continue
+		}
+		list = append(list, newEntryInfo(f.target, filepath.Join(f.target.FullPath, info.Name()), info))
+	}
+	return list, err
+}
+
+func (f *davFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return newEntryInfo(f.target, f.target.FullPath, info), nil
+}
+
+func (f *davFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// davUpload 写入中的文件，关闭时提交上传
+type davUpload struct {
+	upload  *uploadFile
+	target  *storage.Target
+	written int64
+	err     error // 写入过程中的错误（包括超出配额），关闭时丢弃文件
+}
+
+func (f *davUpload) Write(p []byte) (int, error) {
+	n, err := f.upload.Write(p)
+	f.written += int64(n)
+	if err != nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *davUpload) Close() error {
+	if f.err != nil {
+		f.upload.Abort()
+		log.Printf("[WEBDAV] 错误: 写入失败，已丢弃文件 %s - %v", f.target.FullPath, f.err)
+		return f.err
+	}
+	if err := f.upload.Commit(); err != nil {
+		log.Printf("[WEBDAV] 错误: 无法保存文件 %s - %v", f.target.FullPath, err)
+		return err
+	}
+	log.Printf("[WEBDAV] 已保存文件: %s, 大小: %d 字节", f.target.FullPath, f.written)
+	return nil
+}
+
+func (f *davUpload) Read(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (f *davUpload) Seek(offset int64, whence int) (int64, error) {
+	// 写入中的文件只支持查询当前位置
+	if offset == 0 && (whence == io.SeekCurrent || whence == io.SeekEnd) {
+		return f.written, nil
+	}
+	return 0, os.ErrPermission
+}
+
+func (f *davUpload) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *davUpload) Stat() (fs.FileInfo, error) {
+	info, err := os.Stat(f.upload.Name())
+	if err != nil {
+		return nil, err
+	}
+	return newEntryInfo(f.target, f.target.FullPath, info), nil
+}
+
+// davMounts 挂载点模式下的虚拟根目录，列出所有挂载点
+type davMounts struct{}
+
+func (davMounts) Close() error                                 { return nil }
+func (davMounts) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (davMounts) Write(p []byte) (int, error)                  { return 0, os.ErrPermission }
+func (davMounts) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (davMounts) Stat() (fs.FileInfo, error)                   { return virtualRootInfo{}, nil }
+
+func (davMounts) Readdir(count int) ([]fs.FileInfo, error) {
+	return mountInfos(), nil
+}
+
+// NewWebDAVHandler 创建挂载在 prefix 下的 WebDAV 处理器
+func NewWebDAVHandler(prefix string) http.Handler {
+	dav := &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: davFS{},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				log.Printf("[WEBDAV] 错误: %s %s - %v, 客户端IP: %s", r.Method, r.URL.Path, err, r.RemoteAddr)
+			}
+		},
+	}
+
+	return audit.Wrap("webdav", "", func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[WEBDAV] 请求 - 方法: %s, 路径: %s, 客户端IP: %s, User-Agent: %s",
+			r.Method, r.URL.Path, r.RemoteAddr, r.UserAgent())
+		auditWebDAV(r, prefix)
+
+		// GET 时 webdav 包只按扩展名和内容判断类型，这里预先设置统一的 Content-Type。
+		// 与预览接口一样禁止浏览器猜测类型，图片、音视频、纯文本和 PDF 以外的类型在沙箱中打开
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if target, err := storage.Resolve(strings.TrimPrefix(r.URL.Path, prefix)); err == nil && !target.Virtual {
+				if info, err := os.Stat(target.FullPath); err == nil && !info.IsDir() {
+					ctype := mimetype.Detect(target.FullPath, info)
+					w.Header().Set("Content-Type", mimetype.Header(ctype))
+					w.Header().Set("X-Content-Type-Options", "nosniff")
+					if !passiveType(ctype) {
+						w.Header().Set("Content-Security-Policy", "sandbox")
+					}
+				}
+			}
+		}
+
+		// webdav 包对写入错误统一返回 404/405，这里提前检查只读和配额以返回准确的状态码
+		switch r.Method {
+		case "PUT", "MKCOL", "DELETE", "MOVE", "PROPPATCH":
+			name := strings.TrimPrefix(r.URL.Path, prefix)
+			if target, err := storage.Resolve(name); err == nil {
+				if err := target.CheckWritable(); err != nil {
+					http.Error(w, err.Error(), storageErrorStatus(err))
+					return
+				}
+				// 不同挂载点之间不能原子移动，按 RFC 4918 第 9.9.4 节返回 502，
+				// 客户端可改为复制后删除
+				if r.Method == "MOVE" {
+					if to, err := storage.Resolve(davDestination(r, prefix)); err == nil && !target.Virtual && !to.Virtual && to.Root != target.Root {
+						http.Error(w, "不能在不同挂载点之间移动", http.StatusBadGateway)
+						return
+					}
+				}
+				if r.Method == "PUT" {
+					size, _ := strconv.ParseInt(r.Header.Get("Content-Length"), 10, 64)
+					if err := target.CheckQuota(size, existingSize(target.FullPath)); err != nil {
+						http.Error(w, err.Error(), storageErrorStatus(err))
+						return
+					}
+				}
+			}
+		case "COPY":
+			// 复制写入的是目标位置，配额按源文件或目录的大小检查
+			if target, err := storage.Resolve(davDestination(r, prefix)); err == nil {
+				if err := target.CheckWritable(); err != nil {
+					http.Error(w, err.Error(), storageErrorStatus(err))
+					return
+				}
+				if err := target.CheckQuota(davSourceSize(strings.TrimPrefix(r.URL.Path, prefix)), existingSize(target.FullPath)); err != nil {
+					http.Error(w, err.Error(), storageErrorStatus(err))
+					return
+				}
+			}
+		}
+		dav.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), originKey{}, uploadOrigin(r, "webdav"))))
+	})
+}
+
+// davActions WebDAV 方法对应的审计操作，OPTIONS、HEAD、LOCK 等不记录
+var davActions = map[string]string{
+	"GET":       "download",
+	"PUT":       "upload",
+	"DELETE":    "delete",
+	"MKCOL":     "mkdir",
+	"MOVE":      "move",
+	"COPY":      "copy",
+	"PROPFIND":  "list",
+	"PROPPATCH": "edit",
+}
+
+// auditWebDAV 设置审计记录的操作和路径，移动、复制的目标路径取自 Destination 头
+func auditWebDAV(r *http.Request, prefix string) {
+	audit.SetAction(r, davActions[r.Method])
+	audit.SetPath(r, strings.TrimPrefix(r.URL.Path, prefix), davDestination(r, prefix))
+}
+
+// davDestination 移动、复制请求 Destination 头中的目标路径，没有时返回空字符串
+func davDestination(r *http.Request, prefix string) string {
+	dest := r.Header.Get("Destination")
+	if dest == "" {
+		return ""
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Path, prefix)
+}
+
+// davSourceSize 复制源的大小，目录按其中所有文件的总大小计算，无法获取时返回 0
+func davSourceSize(name string) int64 {
+	target, err := storage.Resolve(name)
+	if err != nil || target.Virtual {
+		return 0
+	}
+	info, err := os.Stat(target.FullPath)
+	if err != nil {
+		return 0
+	}
+	if !info.IsDir() {
+		return info.Size()
+	}
+	if stats, ok := dirstats.Lookup(target.FullPath); ok {
+		return stats.Size
+	}
+	results, err := dirstats.Compute(target.FullPath)
+	if err != nil {
+		return 0
+	}
+	return results[target.FullPath].Size
+}
+
+// originKey 请求上下文中上传者信息的键，WebDAV 文件系统接口只能通过上下文获取请求信息
+type originKey struct{}
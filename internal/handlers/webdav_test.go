@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fileSystem/internal/config"
+)
+
+func TestWebDAVMoveAcrossMounts(t *testing.T) {
+	root := useTempStorage(t)
+	for _, name := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		config.Mounts = append(config.Mounts, config.Mount{Name: name, Path: filepath.Join(root, name)})
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "x.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewWebDAVHandler("/dav")
+	move := func(src, dst string) int {
+		r := httptest.NewRequest("MOVE", "/dav/"+src, nil)
+		r.Header.Set("Destination", "http://example.com/dav/"+dst)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if got := move("a/x.txt", "b/x.txt"); got != http.StatusBadGateway {
+		t.Errorf("cross-mount MOVE status = %d, want %d", got, http.StatusBadGateway)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "x.txt")); err != nil {
+		t.Errorf("source removed after refused move: %v", err)
+	}
+	if got := move("a/x.txt", "a/y.txt"); got != http.StatusCreated {
+		t.Errorf("same-mount MOVE status = %d, want %d", got, http.StatusCreated)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "y.txt")); err != nil {
+		t.Errorf("moved file missing: %v", err)
+	}
+}
@@ -6,10 +6,12 @@ import "net/http"
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Digest, If-Match, If-None-Match, If-Unmodified-Since, X-Share-Password")
+		w.Header().Set("Access-Control-Expose-Headers", "Digest, ETag, Last-Modified")
 
-		if r.Method == "OPTIONS" {
+		// 只处理浏览器的预检请求，其余 OPTIONS 请求（如 WebDAV 客户端）交给后续处理器
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -0,0 +1,171 @@
+// Package mimetype 根据扩展名和文件头判断文件的 MIME 类型
+package mimetype
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default 无法识别时使用的类型
+const Default = "application/octet-stream"
+
+// sniffLen 判断类型时读取的文件头长度（tar 的标识位于 257 字节处）
+const sniffLen = 512
+
+// extensions 常见扩展名对应的类型，优先于系统的 mime 表，保证不同平台结果一致
+var extensions = map[string]string{
+	// 文本与代码
+	".txt": "text/plain", ".log": "text/plain", ".md": "text/markdown", ".csv": "text/csv", ".tsv": "text/tab-separated-values",
+	".html": "text/html", ".htm": "text/html", ".css": "text/css", ".xml": "text/xml", ".json": "application/json",
+	".js": "text/javascript", ".mjs": "text/javascript", ".ts": "text/x-typescript", ".tsx": "text/x-typescript",
+	".jsx": "text/javascript", ".yaml": "application/yaml", ".yml": "application/yaml", ".toml": "application/toml",
+	".ini": "text/plain", ".conf": "text/plain", ".cfg": "text/plain", ".env": "text/plain", ".properties": "text/plain",
+	".go": "text/x-go", ".py": "text/x-python", ".rb": "text/x-ruby", ".java": "text/x-java", ".kt": "text/x-kotlin",
+	".swift": "text/x-swift", ".c": "text/x-c", ".h": "text/x-c", ".cpp": "text/x-c++", ".hpp": "text/x-c++",
+	".cc": "text/x-c++", ".cs": "text/x-csharp", ".rs": "text/x-rust", ".php": "text/x-php", ".pl": "text/x-perl",
+	".lua": "text/x-lua", ".sh": "text/x-shellscript", ".bash": "text/x-shellscript", ".zsh": "text/x-shellscript",
+	".bat": "text/plain", ".ps1": "text/plain", ".sql": "application/sql", ".vue": "text/plain", ".scss": "text/x-scss",
+	".less": "text/x-less",
+	// 图片
+	".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".png": "image/png", ".gif": "image/gif", ".webp": "image/webp",
+	".svg": "image/svg+xml", ".bmp": "image/bmp", ".ico": "image/x-icon", ".avif": "image/avif", ".heic": "image/heic",
+	".tif": "image/tiff", ".tiff": "image/tiff",
+	// 音视频
+	".mp3": "audio/mpeg", ".wav": "audio/wav", ".ogg": "audio/ogg", ".oga": "audio/ogg", ".opus": "audio/ogg",
+	".m4a": "audio/mp4", ".aac": "audio/aac", ".flac": "audio/flac", ".mid": "audio/midi",
+	".mp4": "video/mp4", ".m4v": "video/mp4", ".webm": "video/webm", ".ogv": "video/ogg", ".mov": "video/quicktime",
+	".mkv": "video/x-matroska", ".avi": "video/x-msvideo", ".wmv": "video/x-ms-wmv", ".flv": "video/x-flv",
+	// 文档
+	".pdf": "application/pdf", ".rtf": "application/rtf", ".epub": "application/epub+zip",
+	".doc": "application/msword", ".xls": "application/vnd.ms-excel", ".ppt": "application/vnd.ms-powerpoint",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text", ".ods": "application/vnd.oasis.opendocument.spreadsheet",
+	// 压缩包与程序
+	".zip": "application/zip", ".gz": "application/gzip", ".tgz": "application/gzip", ".tar": "application/x-tar",
+	".bz2": "application/x-bzip2", ".xz": "application/x-xz", ".7z": "application/x-7z-compressed",
+	".rar": "application/vnd.rar", ".zst": "application/zstd", ".jar": "application/java-archive",
+	".apk": "application/vnd.android.package-archive", ".exe": "application/vnd.microsoft.portable-executable",
+	".dll": "application/vnd.microsoft.portable-executable", ".msi": "application/x-msi", ".deb": "application/vnd.debian.binary-package",
+	".rpm": "application/x-rpm", ".dmg": "application/x-apple-diskimage", ".iso": "application/x-iso9660-image",
+	".wasm": "application/wasm", ".sqlite": "application/vnd.sqlite3",
+	// 字体
+	".woff": "font/woff", ".woff2": "font/woff2", ".ttf": "font/ttf", ".otf": "font/otf",
+}
+
+// signatures 标准库 http.DetectContentType 不识别的文件头
+var signatures = []struct {
+	offset int
+	magic  []byte
+	ctype  string
+}{
+	{0, []byte("7z\xBC\xAF\x27\x1C"), "application/x-7z-compressed"},
+	{0, []byte("\xFD7zXZ\x00"), "application/x-xz"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte("\x28\xB5\x2F\xFD"), "application/zstd"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{0, []byte("\x7FELF"), "application/x-executable"},
+	{0, []byte("MZ"), "application/vnd.microsoft.portable-executable"},
+	{0, []byte("fLaC"), "audio/flac"},
+	{0, []byte("II*\x00"), "image/tiff"},
+	{0, []byte("MM\x00*"), "image/tiff"},
+	{0, []byte("{\\rtf"), "application/rtf"},
+	{4, []byte("ftypqt"), "video/quicktime"},
+	{4, []byte("ftypheic"), "image/heic"},
+	{4, []byte("ftypavif"), "image/avif"},
+	{4, []byte("ftypM4A"), "audio/mp4"},
+	{257, []byte("ustar"), "application/x-tar"},
+}
+
+// cacheEntry 已判断过的文件，大小或修改时间变化后重新判断
+type cacheEntry struct {
+	size    int64
+	modTime time.Time
+	ctype   string
+}
+
+// maxCacheEntries 缓存条数上限，超过后清空重新累积
+const maxCacheEntries = 10000
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cacheEntry)
+)
+
+// ByExtension 只根据扩展名判断类型，无法判断时返回空字符串
+func ByExtension(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	if ctype, ok := extensions[ext]; ok {
+		return ctype
+	}
+	ctype, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	return ctype
+}
+
+// Sniff 根据文件头判断类型（不含参数），无法判断时返回 Default
+func Sniff(head []byte) string {
+	for _, s := range signatures {
+		if len(head) >= s.offset+len(s.magic) && bytes.Equal(head[s.offset:s.offset+len(s.magic)], s.magic) {
+			return s.ctype
+		}
+	}
+	ctype, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if ctype == "" {
+		return Default
+	}
+	return ctype
+}
+
+// Detect 判断磁盘上文件的类型：优先使用扩展名，扩展名未知时读取文件头判断
+//
+// 文件头的判断结果按路径缓存，文件大小或修改时间变化后失效。
+func Detect(fullPath string, info os.FileInfo) string {
+	if ctype := ByExtension(info.Name()); ctype != "" {
+		return ctype
+	}
+	if info.Size() == 0 {
+		return "text/plain"
+	}
+
+	cacheMu.Lock()
+	e, ok := cache[fullPath]
+	cacheMu.Unlock()
+	if ok && e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+		return e.ctype
+	}
+
+	ctype := Default
+	if f, err := os.Open(fullPath); err == nil {
+		head := make([]byte, sniffLen)
+		n, _ := io.ReadFull(f, head)
+		f.Close()
+		ctype = Sniff(head[:n])
+	}
+
+	cacheMu.Lock()
+	if len(cache) >= maxCacheEntries {
+		cache = make(map[string]cacheEntry)
+	}
+	cache[fullPath] = cacheEntry{size: info.Size(), modTime: info.ModTime(), ctype: ctype}
+	cacheMu.Unlock()
+	return ctype
+}
+
+// Header 返回用于 Content-Type 响应头的值，文本类型附加 UTF-8 字符集
+func Header(ctype string) string {
+	if strings.HasPrefix(ctype, "text/") {
+		return ctype + "; charset=utf-8"
+	}
+	return ctype
+}
@@ -8,7 +8,82 @@ type FileInfo struct {
 	ModTime   time.Time `json:"modTime"`
 	IsDir     bool      `json:"isDir"`
 	Extension string    `json:"extension"`
-	Path      string    `json:"path,omitempty"` // 相对路径
+	MimeType  string    `json:"mimeType,omitempty"` // MIME 类型（仅文件）
+	Path      string    `json:"path,omitempty"`     // 相对路径
+	Mount     bool      `json:"mount,omitempty"`    // 是否为挂载点
+	ReadOnly  bool      `json:"readOnly,omitempty"` // 是否只读
+	Quota     int64     `json:"quota,omitempty"`    // 挂载点配额（字节）
+	SHA256    string    `json:"sha256,omitempty"`   // 上传时计算的 SHA-256
+	MD5       string    `json:"md5,omitempty"`      // 上传时计算的 MD5（可选）
+	CRC32C    string    `json:"crc32c,omitempty"`   // 上传时计算的 CRC32C（可选）
+
+	Stats        *DirStats `json:"stats,omitempty"`        // 目录的递归统计（请求 dirStats 时返回）
+	StatsPending bool      `json:"statsPending,omitempty"` // 目录统计尚未完成
+
+	Tags     []string          `json:"tags,omitempty"`     // 标签
+	Metadata map[string]string `json:"metadata,omitempty"` // 自定义元数据
+
+	Uploader string     `json:"uploader,omitempty"` // 上传者（SFTP 用户名或客户端 IP）
+	Created  *time.Time `json:"created,omitempty"`  // 上传时间（在服务器外放入的文件为首次发现的时间）
+}
+
+// DirStats 目录的递归统计，此时 FileInfo.Size 为目录下所有文件的总大小
+type DirStats struct {
+	Files   int64     `json:"files"`   // 文件数
+	Dirs    int64     `json:"dirs"`    // 子目录数
+	ModTime time.Time `json:"modTime"` // 目录树中最近的修改时间
+}
+
+// FileList 分页的文件列表
+type FileList struct {
+	Files      []FileInfo `json:"files"`
+	Total      int        `json:"total"`                // 符合过滤条件的总数
+	NextCursor string     `json:"nextCursor,omitempty"` // 下一页游标，没有下一页时为空
+}
+
+// UsageNode 空间分析结果中的一项，子项按大小从大到小排列
+type UsageNode struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path,omitempty"` // 相对路径，合并的“其他”项为空
+	IsDir    bool        `json:"isDir"`
+	Size     int64       `json:"size"`
+	SizeText string      `json:"sizeText"`           // 格式化的大小
+	Files    int64       `json:"files,omitempty"`    // 目录下的文件数
+	Other    int         `json:"other,omitempty"`    // 合并为“其他”的项数
+	Children []UsageNode `json:"children,omitempty"` // 展开的子项（只含占用最大的几项）
+}
+
+// FileDetails 文件详情，包括文件数据库中的记录和最近的上传历史
+type FileDetails struct {
+	Name     string       `json:"name"`
+	Path     string       `json:"path"`
+	Size     int64        `json:"size"`
+	ModTime  time.Time    `json:"modTime"`
+	MimeType string       `json:"mimeType"`
+	SHA256   string       `json:"sha256,omitempty"`
+	Uploader string       `json:"uploader,omitempty"` // 最近一次的上传者
+	Created  *time.Time   `json:"created,omitempty"`  // 最近一次的上传时间（在服务器外放入的文件为首次发现的时间）
+	Uploads  []UploadInfo `json:"uploads"`            // 上传历史，最新的在前
+}
+
+// UploadInfo 一次上传的记录
+type UploadInfo struct {
+	Uploader  string    `json:"uploader"`            // SFTP 用户名或客户端 IP
+	IP        string    `json:"ip,omitempty"`        // 客户端 IP
+	UserAgent string    `json:"userAgent,omitempty"` // 客户端 User-Agent
+	Via       string    `json:"via"`                 // 上传方式：web、instant、edit、drop、webdav、sftp、s3
+	Time      time.Time `json:"time"`                // 完成时间
+	Size      int64     `json:"size"`                // 上传的字节数（秒传为 0）
+	Duration  string    `json:"duration"`            // 耗时
+	Speed     float64   `json:"speed"`               // 平均速度（字节/秒）
+	SpeedText string    `json:"speedText"`           // 格式化的速度文本
+}
+
+// Checksums 文件摘要（十六进制）
+type Checksums struct {
+	SHA256 string `json:"sha256,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+	CRC32C string `json:"crc32c,omitempty"`
 }
 
 type Response struct {
@@ -25,3 +100,56 @@ type SpeedInfo struct {
 	Duration     string  `json:"duration"`     // 耗时
 	SpeedText    string  `json:"speedText"`    // 格式化的速度文本
 }
+
+// ShareInfo 分享链接信息
+type ShareInfo struct {
+	Token        string     `json:"token"`
+	Path         string     `json:"path,omitempty"` // 被分享的路径，公开访问时不返回
+	Name         string     `json:"name"`
+	IsDir        bool       `json:"isDir"`
+	Size         int64      `json:"size,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxDownloads int        `json:"maxDownloads,omitempty"`
+	Downloads    int        `json:"downloads"`
+	HasPassword  bool       `json:"hasPassword"`
+	Status       string     `json:"status,omitempty"` // 失效原因，有效时为空
+	URL          string     `json:"url"`
+	Files        []FileInfo `json:"files,omitempty"` // 目录分享的内容（公开访问时）
+}
+
+// ShareTicket 设置了密码的分享链接的下载凭证
+type ShareTicket struct {
+	URL       string    `json:"url"`       // 带凭证的下载地址，可直接在浏览器中打开
+	ExpiresAt time.Time `json:"expiresAt"` // 凭证过期时间，过期后需要重新获取
+}
+
+// DropInfo 上传链接信息
+type DropInfo struct {
+	Token        string     `json:"token"`
+	Path         string     `json:"path,omitempty"` // 接收文件的目录，公开访问时不返回
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxFileSize  int64      `json:"maxFileSize,omitempty"`
+	MaxTotalSize int64      `json:"maxTotalSize,omitempty"`
+	Uploaded     int64      `json:"uploaded"`
+	Files        int        `json:"files"`
+	Status       string     `json:"status,omitempty"` // 失效原因，有效时为空
+	URL          string     `json:"url"`
+}
+
+// ManifestEntry 目录清单中的一项，用于同步时比对差异
+type ManifestEntry struct {
+	Path    string    `json:"path"` // 相对于清单目录的路径，使用 / 分隔
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"isDir"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+// Settings 提供给前端的服务器设置
+type Settings struct {
+	UploadConcurrency int   `json:"uploadConcurrency"` // 建议同时上传的文件数
+	MaxEditSize       int64 `json:"maxEditSize"`       // 在线编辑允许的最大文件大小（字节）
+}
@@ -0,0 +1,143 @@
+package share
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrDropFull     = errors.New("上传链接的总容量已用完")
+	ErrFileTooLarge = errors.New("文件超过大小限制")
+)
+
+// Drop 只允许上传的收件链接，持有者只能向指定目录上传文件，不能浏览或下载
+type Drop struct {
+	Token        string     `json:"token"`
+	Path         string     `json:"path"` // 接收文件的目录（与 API 路径相同）
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxFileSize  int64      `json:"maxFileSize,omitempty"`  // 单个文件大小上限（字节），0 表示不限制
+	MaxTotalSize int64      `json:"maxTotalSize,omitempty"` // 累计上传大小上限（字节），0 表示不限制
+	Uploaded     int64      `json:"uploaded"`               // 已上传字节数
+	Files        int        `json:"files"`                  // 已上传文件数
+}
+
+// Usable 检查链接是否仍然有效
+func (d *Drop) Usable(now time.Time) error {
+	if d.ExpiresAt != nil && now.After(*d.ExpiresAt) {
+		return ErrExpired
+	}
+	if d.MaxTotalSize > 0 && d.Uploaded >= d.MaxTotalSize {
+		return ErrDropFull
+	}
+	return nil
+}
+
+// Limit 返回下一个文件允许的最大字节数，不限制时 limited 为 false
+func (d *Drop) Limit() (limit int64, limited bool) {
+	if d.MaxFileSize > 0 {
+		limit, limited = d.MaxFileSize, true
+	}
+	if d.MaxTotalSize > 0 {
+		remaining := d.MaxTotalSize - d.Uploaded
+		if !limited || remaining < limit {
+			limit, limited = remaining, true
+		}
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return limit, limited
+}
+
+// CreateDrop 创建上传链接
+func CreateDrop(path string, expiresAt *time.Time, maxFileSize, maxTotalSize int64) (*Drop, error) {
+	token, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Drop{
+		Token:        token,
+		Path:         path,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+		MaxFileSize:  maxFileSize,
+		MaxTotalSize: maxTotalSize,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	data.Drops[token] = d
+	if err := save(); err != nil {
+		delete(data.Drops, token)
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetDrop 获取上传链接的副本
+func GetDrop(token string) (*Drop, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	d, ok := data.Drops[token]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// ListDrops 按创建时间倒序返回所有上传链接
+func ListDrops() []Drop {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]Drop, 0, len(data.Drops))
+	for _, d := range data.Drops {
+		list = append(list, *d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// RecordDropUpload 记录一次上传，超出总容量时返回 ErrDropFull 且不记录
+func RecordDropUpload(token string, size int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	d, ok := data.Drops[token]
+	if !ok {
+		return ErrNotFound
+	}
+	if d.ExpiresAt != nil && time.Now().After(*d.ExpiresAt) {
+		return ErrExpired
+	}
+	if d.MaxTotalSize > 0 && d.Uploaded+size > d.MaxTotalSize {
+		return ErrDropFull
+	}
+	d.Uploaded += size
+	d.Files++
+	return save()
+}
+
+// RevokeDrop 撤销上传链接
+func RevokeDrop(token string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	d, ok := data.Drops[token]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(data.Drops, token)
+	if err := save(); err != nil {
+		data.Drops[token] = d
+		return err
+	}
+	return nil
+}
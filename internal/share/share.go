@@ -0,0 +1,317 @@
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fileSystem/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrNotFound  = errors.New("分享链接不存在或已被撤销")
+	ErrExpired   = errors.New("分享链接已过期")
+	ErrExhausted = errors.New("分享链接下载次数已用完")
+	ErrPassword  = errors.New("密码错误")
+)
+
+// Share 公开分享链接
+type Share struct {
+	Token        string     `json:"token"`
+	Path         string     `json:"path"` // 被分享的文件或目录（与 API 路径相同）
+	IsDir        bool       `json:"isDir"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxDownloads int        `json:"maxDownloads,omitempty"` // 0 表示不限制
+	Downloads    int        `json:"downloads"`
+	PasswordHash string     `json:"passwordHash,omitempty"`
+}
+
+// HasPassword 是否设置了访问密码
+func (s *Share) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// CheckPassword 校验访问密码
+func (s *Share) CheckPassword(password string) error {
+	if !s.HasPassword() {
+		return nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(s.PasswordHash), []byte(password)) != nil {
+		return ErrPassword
+	}
+	return nil
+}
+
+// Usable 检查链接是否仍然有效
+func (s *Share) Usable(now time.Time) error {
+	if s.ExpiresAt != nil && now.After(*s.ExpiresAt) {
+		return ErrExpired
+	}
+	if s.MaxDownloads > 0 && s.Downloads >= s.MaxDownloads {
+		return ErrExhausted
+	}
+	return nil
+}
+
+// 持久化到 <storage_dir>/.filesystem/shares.json 的数据
+type state struct {
+	Shares map[string]*Share `json:"shares"`
+	Drops  map[string]*Drop  `json:"drops"`
+}
+
+var (
+	mu   sync.Mutex
+	data = state{
+		Shares: make(map[string]*Share),
+		Drops:  make(map[string]*Drop),
+	}
+)
+
+func statePath() string {
+	return filepath.Join(storage.MetaDir(), "shares.json")
+}
+
+// Load 启动时加载已保存的分享链接
+func Load() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	raw, err := os.ReadFile(statePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+	if data.Shares == nil {
+		data.Shares = make(map[string]*Share)
+	}
+	if data.Drops == nil {
+		data.Drops = make(map[string]*Drop)
+	}
+	log.Printf("[SHARE] 已加载 %d 个分享链接, %d 个上传链接", len(data.Shares), len(data.Drops))
+	return nil
+}
+
+// 保存状态，调用方需持有锁
+func save() error {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(storage.MetaDir(), 0755); err != nil {
+		return err
+	}
+	tmp := statePath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statePath())
+}
+
+// NewToken 生成随机令牌
+func NewToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashPassword 生成密码哈希，密码为空时返回空字符串
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Create 创建分享链接
+func Create(path string, isDir bool, expiresAt *time.Time, maxDownloads int, password string) (*Share, error) {
+	token, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Share{
+		Token:        token,
+		Path:         path,
+		IsDir:        isDir,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+		MaxDownloads: maxDownloads,
+		PasswordHash: passwordHash,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	data.Shares[token] = s
+	if err := save(); err != nil {
+		delete(data.Shares, token)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 获取分享链接的副本
+func Get(token string) (*Share, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, ok := data.Shares[token]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *s
+	return &copied, nil
+}
+
+// List 按创建时间倒序返回所有分享链接
+func List() []Share {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]Share, 0, len(data.Shares))
+	for _, s := range data.Shares {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// resumeWindow 同一客户端在该时间内再次下载同一文件视为断点续传，不重复计数
+const resumeWindow = time.Hour
+
+// recentDownloads 最近计过数的下载（令牌、客户端、文件）及其时间，由 mu 保护，只保存在内存中
+var recentDownloads = make(map[string]time.Time)
+
+func downloadKey(token, client, path string) string {
+	return token + "\x00" + client + "\x00" + strings.Trim(path, "/")
+}
+
+// Resuming 客户端是否在续传期内下载过该文件，此时即使下载次数已用完也允许继续下载
+func Resuming(token, client, path string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	t, ok := recentDownloads[downloadKey(token, client, path)]
+	return ok && time.Since(t) < resumeWindow
+}
+
+// RecordDownload 记录一次下载，同一客户端在续传期内重复下载同一文件只计一次
+func RecordDownload(token, client, path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, ok := data.Shares[token]
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	for k, t := range recentDownloads {
+		if now.Sub(t) >= resumeWindow {
+			delete(recentDownloads, k)
+		}
+	}
+	key := downloadKey(token, client, path)
+	if _, ok := recentDownloads[key]; ok {
+		if s.ExpiresAt != nil && now.After(*s.ExpiresAt) {
+			return ErrExpired
+		}
+		return nil
+	}
+	if err := s.Usable(now); err != nil {
+		return err
+	}
+	s.Downloads++
+	if err := save(); err != nil {
+		s.Downloads--
+		return err
+	}
+	recentDownloads[key] = now
+	return nil
+}
+
+// ticketTTL 下载凭证的有效期，与续传期相同，期间中断的下载可以用同一地址续传
+const ticketTTL = resumeWindow
+
+// ticketKey 签发下载凭证的密钥，每次启动随机生成，重启后已签发的凭证失效
+var ticketKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// ticketMAC 计算下载凭证的签名，密码哈希参与签名，链接撤销重建或密码变化后旧凭证失效
+func (s *Share) ticketMAC(client, path string, expires int64) []byte {
+	mac := hmac.New(sha256.New, ticketKey)
+	mac.Write([]byte(s.Token + "\x00" + s.PasswordHash + "\x00" + downloadKey("", client, path) + "\x00" + strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil)
+}
+
+// Ticket 为已通过密码校验的客户端签发下载凭证，凭证只能由同一客户端用于下载 path，
+// 浏览器可以直接打开带凭证的下载地址，无需再通过请求头传递密码
+func (s *Share) Ticket(client, path string, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(ticketTTL)
+	expires := expiresAt.Unix()
+	return strconv.FormatInt(expires, 10) + "." + hex.EncodeToString(s.ticketMAC(client, path, expires)), expiresAt
+}
+
+// CheckTicket 校验下载凭证是否由本服务为该客户端和文件签发且尚未过期
+func (s *Share) CheckTicket(ticket, client, path string, now time.Time) bool {
+	expiresStr, sig, ok := strings.Cut(ticket, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	return err == nil && hmac.Equal(got, s.ticketMAC(client, path, expires))
+}
+
+// Revoke 撤销分享链接
+func Revoke(token string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, ok := data.Shares[token]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(data.Shares, token)
+	if err := save(); err != nil {
+		data.Shares[token] = s
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package share
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicket(t *testing.T) {
+	s := &Share{Token: "tok", PasswordHash: "hash"}
+	now := time.Now()
+	ticket, expiresAt := s.Ticket("10.0.0.1", "dir/a.txt", now)
+	if !expiresAt.Equal(now.Add(ticketTTL)) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, now.Add(ticketTTL))
+	}
+
+	tests := []struct {
+		name   string
+		share  *Share
+		ticket string
+		client string
+		path   string
+		now    time.Time
+		want   bool
+	}{
+		{"valid", s, ticket, "10.0.0.1", "dir/a.txt", now, true},
+		{"surrounding slashes", s, ticket, "10.0.0.1", "/dir/a.txt/", now, true},
+		{"before expiry", s, ticket, "10.0.0.1", "dir/a.txt", now.Add(ticketTTL - time.Second), true},
+		{"expired", s, ticket, "10.0.0.1", "dir/a.txt", now.Add(ticketTTL + time.Second), false},
+		{"other client", s, ticket, "10.0.0.2", "dir/a.txt", now, false},
+		{"other file", s, ticket, "10.0.0.1", "dir/b.txt", now, false},
+		{"other share", &Share{Token: "other", PasswordHash: "hash"}, ticket, "10.0.0.1", "dir/a.txt", now, false},
+		{"password changed", &Share{Token: "tok", PasswordHash: "new"}, ticket, "10.0.0.1", "dir/a.txt", now, false},
+		{"empty", s, "", "10.0.0.1", "dir/a.txt", now, false},
+		{"no signature", s, "99999999999", "10.0.0.1", "dir/a.txt", now, false},
+		{"extended expiry", s, "99999999999" + ticket[len(ticket)-65:], "10.0.0.1", "dir/a.txt", now, false},
+		{"bad hex", s, ticket[:len(ticket)-1] + "x", "10.0.0.1", "dir/a.txt", now, false},
+	}
+	for _, tt := range tests {
+		if got := tt.share.CheckTicket(tt.ticket, tt.client, tt.path, tt.now); got != tt.want {
+			t.Errorf("%s: CheckTicket() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import "sync"
+
+// Usage 统计存储根目录的已用空间，默认每次遍历目录；dirstats 包加载时替换为使用其缓存的实现
+var Usage = DirSize
+
+var (
+	reserveMu sync.Mutex
+	// reserved 各存储根目录中正在写入、尚未完成的字节数
+	reserved = make(map[string]int64)
+)
+
+// Remaining 返回挂载点剩余配额（已扣除正在进行的写入预留的空间），未设置配额时 limited 为 false
+func (t *Target) Remaining() (remaining int64, limited bool, err error) {
+	if t.Mount == nil || t.Mount.Quota <= 0 {
+		return 0, false, nil
+	}
+	reserveMu.Lock()
+	defer reserveMu.Unlock()
+	remaining, err = t.remainingLocked()
+	return remaining, true, err
+}
+
+// remainingLocked 计算剩余配额，调用方需持有 reserveMu
+func (t *Target) remainingLocked() (int64, error) {
+	used, err := Usage(t.Root)
+	if err != nil {
+		return 0, err
+	}
+	remaining := t.Mount.Quota - used - reserved[t.Root]
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, nil
+}
+
+// CheckQuota 检查写入 incoming 字节、覆盖 replacing 字节的原有文件后是否超出配额
+func (t *Target) CheckQuota(incoming, replacing int64) error {
+	remaining, limited, err := t.Remaining()
+	if err != nil || !limited {
+		return err
+	}
+	if incoming-replacing > remaining {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Reservation 一次写入占用的配额。写入过程中通过 Extend 逐步预留，
+// 预留的空间在 Release 前计入已用空间，同一挂载点的并发写入不会同时通过检查
+type Reservation struct {
+	target  *Target
+	credit  int64 // 被覆盖的原有文件的大小，写入完成后释放
+	size    int64 // 已预留的写入大小
+	holding int64 // 计入 reserved 的字节数，即 size 超出 credit 的部分
+}
+
+// Reserve 开始一次写入，replacing 为将被覆盖的原有文件的大小，没有时为 0
+func (t *Target) Reserve(replacing int64) *Reservation {
+	return &Reservation{target: t, credit: replacing}
+}
+
+// Extend 将预留的写入大小增加到 size 字节，超出配额时返回 ErrQuotaExceeded
+func (r *Reservation) Extend(size int64) error {
+	t := r.target
+	if size <= r.size || t.Mount == nil || t.Mount.Quota <= 0 {
+		return nil
+	}
+	holding := size - r.credit
+	if holding < 0 {
+		holding = 0
+	}
+
+	reserveMu.Lock()
+	defer reserveMu.Unlock()
+	if holding > r.holding {
+		remaining, err := t.remainingLocked()
+		if err != nil {
+			return err
+		}
+		if holding-r.holding > remaining {
+			return ErrQuotaExceeded
+		}
+		reserved[t.Root] += holding - r.holding
+		r.holding = holding
+	}
+	r.size = size
+	return nil
+}
+
+// Release 写入完成或放弃后释放预留的空间，写入完成时应在目录统计失效之后调用，可重复调用
+func (r *Reservation) Release() {
+	if r.holding == 0 {
+		return
+	}
+	reserveMu.Lock()
+	defer reserveMu.Unlock()
+	reserved[r.target.Root] -= r.holding
+	if reserved[r.target.Root] == 0 {
+		delete(reserved, r.target.Root)
+	}
+	r.holding = 0
+}
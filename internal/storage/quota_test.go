@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"fileSystem/internal/config"
+)
+
+// quotaTarget 在临时目录中创建配额为 quota 的挂载点，files 为已有文件的大小
+func quotaTarget(t *testing.T, quota int64, files map[string]int) *Target {
+	t.Helper()
+	root := t.TempDir()
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Target{Mount: &config.Mount{Name: "q", Path: root, Quota: quota}, Root: root, FullPath: root}
+}
+
+func TestCheckQuota(t *testing.T) {
+	target := quotaTarget(t, 10, map[string]int{"a.txt": 8, UploadTempPrefix + "b.txt-1.tmp": 5})
+	tests := []struct {
+		incoming, replacing int64
+		want                error
+	}{
+		{2, 0, nil},
+		{3, 0, ErrQuotaExceeded},
+		// 覆盖原有文件时按大小差值计算
+		{10, 8, nil},
+		{11, 8, ErrQuotaExceeded},
+		{1, 8, nil},
+	}
+	for _, tt := range tests {
+		if err := target.CheckQuota(tt.incoming, tt.replacing); err != tt.want {
+			t.Errorf("CheckQuota(%d, %d) = %v, want %v", tt.incoming, tt.replacing, err, tt.want)
+		}
+	}
+	if err := (&Target{Root: target.Root}).CheckQuota(1<<40, 0); err != nil {
+		t.Errorf("CheckQuota() without quota = %v", err)
+	}
+}
+
+func TestReservation(t *testing.T) {
+	target := quotaTarget(t, 10, map[string]int{"a.txt": 4})
+
+	first := target.Reserve(0)
+	if err := first.Extend(5); err != nil {
+		t.Fatal(err)
+	}
+	// 预留的空间计入已用空间
+	if remaining, _, _ := target.Remaining(); remaining != 1 {
+		t.Errorf("remaining = %d, want 1", remaining)
+	}
+	second := target.Reserve(0)
+	if err := second.Extend(2); err != ErrQuotaExceeded {
+		t.Errorf("concurrent Extend() = %v, want %v", err, ErrQuotaExceeded)
+	}
+	first.Release()
+	first.Release()
+	if err := second.Extend(2); err != nil {
+		t.Errorf("Extend() after release = %v", err)
+	}
+	second.Release()
+
+	// 覆盖 a.txt：不超过原文件大小的部分不占用新的空间
+	overwrite := target.Reserve(4)
+	if err := overwrite.Extend(4); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _, _ := target.Remaining(); remaining != 6 {
+		t.Errorf("remaining while overwriting = %d, want 6", remaining)
+	}
+	if err := overwrite.Extend(10); err != nil {
+		t.Errorf("Extend(10) overwriting = %v", err)
+	}
+	if err := overwrite.Extend(11); err != ErrQuotaExceeded {
+		t.Errorf("Extend(11) overwriting = %v, want %v", err, ErrQuotaExceeded)
+	}
+	overwrite.Release()
+	if remaining, _, _ := target.Remaining(); remaining != 6 {
+		t.Errorf("remaining after release = %d, want 6", remaining)
+	}
+}
+
+func TestReservationConcurrent(t *testing.T) {
+	target := quotaTarget(t, 10, nil)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if target.Reserve(0).Extend(3) == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 3 {
+		t.Errorf("%d reservations of 3 bytes passed with quota 10, want 3", ok)
+	}
+}
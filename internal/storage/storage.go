@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fileSystem/internal/config"
+)
+
+// MetaDirName 存储根目录下保存内部数据的目录名，对客户端隐藏
+const MetaDirName = ".filesystem"
+
+// UploadTempPrefix 上传过程中在目标目录创建的临时文件的名称前缀，完成时改名为目标文件
+const UploadTempPrefix = ".upload-"
+
+var (
+	ErrInvalidPath   = errors.New("无效的路径")
+	ErrReadOnly      = errors.New("该存储位置为只读")
+	ErrQuotaExceeded = errors.New("超出存储配额")
+	ErrNoMount       = errors.New("请先进入一个存储位置")
+)
+
+// Target 解析后的存储位置
+type Target struct {
+	Mount    *config.Mount // 所属挂载点，单根模式下为 nil
+	Root     string        // 所属存储根目录的绝对路径
+	FullPath string        // 磁盘上的完整路径
+	RelPath  string        // 相对于存储根目录的路径
+	Virtual  bool          // 是否为挂载点列表（虚拟根目录）
+}
+
+// MetaDir 全局内部数据目录（位于 storage_dir 下，挂载点模式下同样使用）
+func MetaDir() string {
+	return filepath.Join(config.UploadDir, MetaDirName)
+}
+
+// HasMounts 是否配置了挂载点
+func HasMounts() bool {
+	return len(config.Mounts) > 0
+}
+
+// Roots 所有存储根目录的绝对路径：挂载点模式下为各挂载点，否则为存储目录
+func Roots() []string {
+	var roots []string
+	if !HasMounts() {
+		if root, err := filepath.Abs(config.UploadDir); err == nil {
+			roots = append(roots, root)
+		}
+		return roots
+	}
+	for _, m := range config.Mounts {
+		if root, err := filepath.Abs(m.Path); err == nil {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// Resolve 将客户端传入的相对路径解析为磁盘路径，并防止路径遍历
+func Resolve(p string) (*Target, error) {
+	p = strings.ReplaceAll(p, "\\", "/")
+	if strings.Contains(p, "..") {
+		return nil, ErrInvalidPath
+	}
+	p = strings.Trim(p, "/")
+	for _, segment := range strings.Split(p, "/") {
+		if segment == MetaDirName {
+			return nil, ErrInvalidPath
+		}
+	}
+
+	if !HasMounts() {
+		return resolveIn(nil, config.UploadDir, p)
+	}
+
+	if p == "" {
+		return &Target{Virtual: true}, nil
+	}
+
+	name, rest, _ := strings.Cut(p, "/")
+	mount := config.FindMount(name)
+	if mount == nil {
+		return nil, ErrInvalidPath
+	}
+	return resolveIn(mount, mount.Path, rest)
+}
+
+func resolveIn(mount *config.Mount, root, rel string) (*Target, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, ErrInvalidPath
+	}
+	fullPath := filepath.Join(absRoot, filepath.FromSlash(rel))
+	if !Within(absRoot, fullPath) {
+		return nil, ErrInvalidPath
+	}
+	return &Target{
+		Mount:    mount,
+		Root:     absRoot,
+		FullPath: fullPath,
+		RelPath:  rel,
+	}, nil
+}
+
+// Within 判断 target 是否位于 root 之内（含 root 本身）
+func Within(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// IsRoot 是否为存储根目录本身（挂载点根目录或单根模式的存储目录）
+func (t *Target) IsRoot() bool {
+	return t.Virtual || t.RelPath == ""
+}
+
+// CheckWritable 检查目标位置是否允许写入
+func (t *Target) CheckWritable() error {
+	if t.Virtual {
+		return ErrNoMount
+	}
+	if t.Mount != nil && t.Mount.ReadOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// IsUploadTemp 是否为正在上传的临时文件
+func IsUploadTemp(name string) bool {
+	return strings.HasPrefix(name, UploadTempPrefix) && strings.HasSuffix(name, ".tmp")
+}
+
+// DirSize 递归计算目录下所有文件的总大小，不包括内部数据目录和正在上传的临时文件
+// （去重 blob 与目录中的硬链接是同一份内容，分片上传的暂存数据在完成时才计入，
+// 正在上传的内容由配额预留计入）
+func DirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == MetaDirName && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsUploadTemp(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,319 @@
+// Package tags 保存文件的标签和自定义元数据
+package tags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"fileSystem/internal/storage"
+)
+
+// 标签和元数据的数量与长度限制
+const (
+	MaxTags     = 32
+	MaxTagLen   = 64
+	MaxMetadata = 64
+	MaxKeyLen   = 64
+	MaxValueLen = 1024
+)
+
+// MaxSearchHits 一次搜索最多返回的条数（多个存储位置合并后同样适用）
+const MaxSearchHits = 1000
+
+var ErrInvalid = errors.New("无效的标签或元数据")
+
+// Entry 一个文件或目录的标签和元数据
+type Entry struct {
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Empty 是否没有任何标签和元数据
+func (e Entry) Empty() bool {
+	return len(e.Tags) == 0 && len(e.Metadata) == 0
+}
+
+// Normalize 去掉首尾空白、去重并排序标签，检查数量和长度限制
+func (e Entry) Normalize() (Entry, error) {
+	var out Entry
+	seen := make(map[string]bool)
+	for _, tag := range e.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLen || strings.ContainsAny(tag, ",\n") {
+			return Entry{}, fmt.Errorf("%w: 标签 %q 过长或包含逗号、换行", ErrInvalid, tag)
+		}
+		seen[tag] = true
+		out.Tags = append(out.Tags, tag)
+	}
+	if len(out.Tags) > MaxTags {
+		return Entry{}, fmt.Errorf("%w: 标签不能超过 %d 个", ErrInvalid, MaxTags)
+	}
+	sort.Strings(out.Tags)
+
+	for key, value := range e.Metadata {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if utf8.RuneCountInString(key) > MaxKeyLen || strings.ContainsAny(key, "=\n") {
+			return Entry{}, fmt.Errorf("%w: 键 %q 过长或包含等号、换行", ErrInvalid, key)
+		}
+		if utf8.RuneCountInString(value) > MaxValueLen {
+			return Entry{}, fmt.Errorf("%w: 键 %q 的值超过 %d 个字符", ErrInvalid, key, MaxValueLen)
+		}
+		if out.Metadata == nil {
+			out.Metadata = make(map[string]string)
+		}
+		out.Metadata[key] = value
+	}
+	if len(out.Metadata) > MaxMetadata {
+		return Entry{}, fmt.Errorf("%w: 元数据不能超过 %d 项", ErrInvalid, MaxMetadata)
+	}
+	return out, nil
+}
+
+// Store 存储根目录下文件的标签和元数据，保存在 <root>/.filesystem/tags.json，每个存储根目录一个实例
+type Store struct {
+	root    string
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry // 相对路径 -> 标签和元数据
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// For 获取存储根目录对应的标签存储
+func For(root string) (*Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[root]; ok {
+		return s, nil
+	}
+
+	s := &Store{
+		root:    root,
+		path:    filepath.Join(root, storage.MetaDirName, "tags.json"),
+		entries: make(map[string]Entry),
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("无法读取标签数据: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.entries); err != nil {
+			return nil, fmt.Errorf("无法解析标签数据: %w", err)
+		}
+		log.Printf("[TAGS] 已加载标签数据 - 根目录: %s, 条目数: %d", root, len(s.entries))
+	}
+	stores[root] = s
+	return s, nil
+}
+
+// 保存数据（先写临时文件再替换），调用方需持有锁
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *Store) rel(fullPath string) (string, error) {
+	rel, err := filepath.Rel(s.root, fullPath)
+	if err != nil || !storage.Within(s.root, fullPath) || rel == "." {
+		return "", storage.ErrInvalidPath
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Get 返回文件的标签和元数据
+func (s *Store) Get(fullPath string) (Entry, bool) {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return Entry{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[rel]
+	return e, ok
+}
+
+// Set 替换文件的标签和元数据，entry 为空时删除记录
+func (s *Store) Set(fullPath string, entry Entry) error {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry.Empty() {
+		if _, ok := s.entries[rel]; !ok {
+			return nil
+		}
+		delete(s.entries, rel)
+	} else {
+		s.entries[rel] = entry
+	}
+	return s.save()
+}
+
+// Remove 文件或目录删除后删除对应的记录
+func (s *Store) Remove(fullPath string, isDir bool) error {
+	rel, err := s.rel(fullPath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for entry := range s.entries {
+		if entry == rel || (isDir && strings.HasPrefix(entry, rel+"/")) {
+			delete(s.entries, entry)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// Rename 文件或目录移动后更新记录中的路径
+func (s *Store) Rename(oldPath, newPath string, isDir bool) error {
+	from, err := s.rel(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := s.rel(newPath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moved := make(map[string]Entry)
+	for entry, e := range s.entries {
+		switch {
+		case entry == from:
+			moved[to] = e
+		case isDir && strings.HasPrefix(entry, from+"/"):
+			moved[to+strings.TrimPrefix(entry, from)] = e
+		default:
+			continue
+		}
+		delete(s.entries, entry)
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	for entry, e := range moved {
+		s.entries[entry] = e
+	}
+	return s.save()
+}
+
+// Query 搜索条件，各条件同时满足
+type Query struct {
+	Dir      string            // 只搜索该目录（完整路径）下的文件，为空时搜索整个存储根目录
+	Tags     []string          // 必须包含的标签
+	Metadata map[string]string // 必须包含的元数据，值为空时只要求存在该键（值不区分大小写）
+	Text     string            // 文件名、标签或元数据值中包含的文字（不区分大小写）
+}
+
+// Hit 搜索结果
+type Hit struct {
+	FullPath string
+	Entry    Entry
+}
+
+// Search 搜索带有标签或元数据的文件，按路径排序，最多返回 1000 条
+func (s *Store) Search(q Query) []Hit {
+	prefix := ""
+	if q.Dir != "" && q.Dir != s.root {
+		rel, err := s.rel(q.Dir)
+		if err != nil {
+			return nil
+		}
+		prefix = rel + "/"
+	}
+	text := strings.ToLower(q.Text)
+
+	s.mu.Lock()
+	var rels []string
+	for rel, e := range s.entries {
+		if strings.HasPrefix(rel, prefix) && e.matches(path(rel), q.Tags, q.Metadata, text) {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+	if len(rels) > MaxSearchHits {
+		rels = rels[:MaxSearchHits]
+	}
+	hits := make([]Hit, len(rels))
+	for i, rel := range rels {
+		hits[i] = Hit{FullPath: filepath.Join(s.root, filepath.FromSlash(rel)), Entry: s.entries[rel]}
+	}
+	s.mu.Unlock()
+	return hits
+}
+
+// path 相对路径中的文件名
+func path(rel string) string {
+	return rel[strings.LastIndex(rel, "/")+1:]
+}
+
+func (e Entry) matches(name string, tags []string, metadata map[string]string, text string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range e.Tags {
+			if strings.EqualFold(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, value := range metadata {
+		v, ok := e.Metadata[key]
+		if !ok || (value != "" && !strings.EqualFold(v, value)) {
+			return false
+		}
+	}
+	if text == "" || strings.Contains(strings.ToLower(name), text) {
+		return true
+	}
+	for _, t := range e.Tags {
+		if strings.Contains(strings.ToLower(t), text) {
+			return true
+		}
+	}
+	for _, v := range e.Metadata {
+		if strings.Contains(strings.ToLower(v), text) {
+			return true
+		}
+	}
+	return false
+}
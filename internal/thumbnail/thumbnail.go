@@ -0,0 +1,143 @@
+// Package thumbnail 生成并缓存图片缩略图
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"fileSystem/internal/storage"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultSize 默认缩略图边长（像素）
+const DefaultSize = 256
+
+// maxPixels 允许解码的最大像素数，防止超大图片耗尽内存
+const maxPixels = 50_000_000
+
+var (
+	ErrUnsupported = errors.New("不支持的图片格式")
+	ErrTooLarge    = errors.New("图片尺寸过大")
+
+	// sizes 允许的缩略图边长，避免任意尺寸占满缓存
+	sizes = map[int]bool{128: true, 256: true, 512: true}
+
+	// slots 限制同时生成缩略图的数量
+	slots = make(chan struct{}, runtime.NumCPU())
+
+	extensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+)
+
+// Supported 根据扩展名判断是否可以生成缩略图
+func Supported(name string) bool {
+	return extensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// ValidSize 判断缩略图边长是否允许
+func ValidSize(size int) bool {
+	return sizes[size]
+}
+
+// cacheDir 文件在缓存中对应的目录，目录下按边长保存缩略图
+func cacheDir(root, fullPath string) (string, error) {
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || !storage.Within(root, fullPath) || rel == "." {
+		return "", storage.ErrInvalidPath
+	}
+	return filepath.Join(root, storage.MetaDirName, "thumbnails", rel), nil
+}
+
+// Get 返回文件的 JPEG 缩略图，缓存的修改时间与源文件不一致时重新生成
+func Get(root, fullPath string, info os.FileInfo, size int) ([]byte, error) {
+	dir, err := cacheDir(root, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(dir, strconv.Itoa(size)+".jpg")
+	if cached, err := os.Stat(cachePath); err == nil && cached.ModTime().Equal(info.ModTime()) {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return data, nil
+		}
+	}
+
+	slots <- struct{}{}
+	data, err := generate(fullPath, size)
+	<-slots
+	if err != nil {
+		return nil, err
+	}
+	// 写入缓存失败不影响返回结果
+	if err := os.MkdirAll(dir, 0755); err == nil {
+		if err := os.WriteFile(cachePath, data, 0644); err == nil {
+			os.Chtimes(cachePath, time.Now(), info.ModTime())
+		}
+	}
+	return data, nil
+}
+
+// generate 解码图片并缩放到 size×size 以内（保持比例，不放大），编码为 JPEG
+func generate(fullPath string, size int) ([]byte, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	// 透明部分以白色填充
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Remove 删除文件或目录对应的缩略图缓存
+func Remove(root, fullPath string) error {
+	dir, err := cacheDir(root, fullPath)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -34,6 +36,15 @@ type SpeedTracker struct {
 	lastTime   time.Time
 }
 
+// ClientIP 客户端 IP（不含端口）
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // NewSpeedTracker 创建速度跟踪器
 func NewSpeedTracker(w io.Writer) *SpeedTracker {
 	now := time.Now()
@@ -128,3 +139,39 @@ func FormatSize(bytes int64) string {
 		return fmt.Sprintf("%.2f GB", float64(bytes)/(1024*1024*1024))
 	}
 }
+
+// ContentDisposition 生成 Content-Disposition 响应头（RFC 6266），disposition 为 attachment 或 inline
+//
+// 同时给出 ASCII 的 filename（非 ASCII 和控制字符替换为 _）和 RFC 5987 编码的 filename*，
+// 支持 filename* 的客户端使用后者还原中文等原始文件名。
+func ContentDisposition(disposition, filename string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r > 0x7e:
+			fallback.WriteByte('_')
+		case r == '"' || r == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(r)
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback.String(), encoded.String())
+}
+
+// isAttrChar 判断是否为 RFC 5987 中无需编码的 attr-char
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"mime"
+	"strings"
+	"testing"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		filename    string
+		want        string
+	}{
+		{"ascii", "attachment", "report.pdf", `attachment; filename="report.pdf"; filename*=UTF-8''report.pdf`},
+		{"inline", "inline", "photo.png", `inline; filename="photo.png"; filename*=UTF-8''photo.png`},
+		{"space and percent", "attachment", "100% done.txt", `attachment; filename="100% done.txt"; filename*=UTF-8''100%25%20done.txt`},
+		{"attr chars", "attachment", "a!#$&+-.^_`|~b", "attachment; filename=\"a!#$&+-.^_`|~b\"; filename*=UTF-8''a!#$&+-.^_`|~b"},
+		{"chinese", "attachment", "中文.txt", `attachment; filename="__.txt"; filename*=UTF-8''%E4%B8%AD%E6%96%87.txt`},
+		{"accent", "attachment", "café.txt", `attachment; filename="caf_.txt"; filename*=UTF-8''caf%C3%A9.txt`},
+		{"emoji", "attachment", "😀.txt", `attachment; filename="_.txt"; filename*=UTF-8''%F0%9F%98%80.txt`},
+		{"quotes", "attachment", `say "hi".txt`, `attachment; filename="say \"hi\".txt"; filename*=UTF-8''say%20%22hi%22.txt`},
+		{"backslash", "attachment", `a\b.txt`, `attachment; filename="a\\b.txt"; filename*=UTF-8''a%5Cb.txt`},
+		{"crlf", "attachment", "a\r\nSet-Cookie: x.txt", `attachment; filename="a__Set-Cookie: x.txt"; filename*=UTF-8''a%0D%0ASet-Cookie%3A%20x.txt`},
+		{"tab and nul", "attachment", "a\tb\x00.txt", `attachment; filename="a_b_.txt"; filename*=UTF-8''a%09b%00.txt`},
+		{"semicolon", "attachment", "a;b=c.txt", `attachment; filename="a;b=c.txt"; filename*=UTF-8''a%3Bb%3Dc.txt`},
+		{"empty", "attachment", "", `attachment; filename=""; filename*=UTF-8''`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ContentDisposition(tt.disposition, tt.filename)
+			if got != tt.want {
+				t.Errorf("ContentDisposition(%q, %q)\n got %s\nwant %s", tt.disposition, tt.filename, got, tt.want)
+			}
+			if strings.ContainsAny(got, "\r\n\x00") {
+				t.Errorf("header contains control characters: %q", got)
+			}
+			if tt.filename == "" {
+				return
+			}
+			// 支持 RFC 5987 的解析器应还原出原始文件名
+			disposition, params, err := mime.ParseMediaType(got)
+			if err != nil {
+				t.Fatalf("ParseMediaType(%q) error = %v", got, err)
+			}
+			if disposition != tt.disposition || params["filename"] != tt.filename {
+				t.Errorf("parsed = %s, %q, want %s, %q", disposition, params["filename"], tt.disposition, tt.filename)
+			}
+		})
+	}
+}
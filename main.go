@@ -7,10 +7,15 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"strings"
 
+	"fileSystem/internal/audit"
 	"fileSystem/internal/config"
+	"fileSystem/internal/filedb"
 	"fileSystem/internal/handlers"
 	"fileSystem/internal/middleware"
+	"fileSystem/internal/share"
+	"fileSystem/internal/storage"
 
 	"github.com/gorilla/mux"
 )
@@ -21,6 +26,17 @@ var staticFiles embed.FS
 func init() {
 	config.LoadConfig()
 	handlers.InitHandlers(staticFiles)
+	if err := share.Load(); err != nil {
+		log.Fatalf("无法加载分享链接: %v", err)
+	}
+	if settings := config.AuditSettings(); !settings.Disabled {
+		auditDir := filepath.Join(storage.MetaDir(), "audit")
+		if err := audit.Init(auditDir, settings.MaxSizeMB<<20, settings.MaxFiles); err != nil {
+			log.Printf("[AUDIT] 警告: 无法打开审计日志，不记录操作 - %v", err)
+		} else {
+			log.Printf("[AUDIT] 审计日志目录: %s, 单个文件上限: %dMB, 保留文件数: %d", auditDir, settings.MaxSizeMB, settings.MaxFiles)
+		}
+	}
 }
 
 func main() {
@@ -29,7 +45,20 @@ func main() {
 	absPath, _ := filepath.Abs(config.UploadDir)
 	fmt.Printf("文件系统服务器启动在 http://localhost%s\n", config.Port)
 	fmt.Printf("存储目录: %s\n", absPath)
-	fmt.Printf("提示: 可通过 config.json 配置文件修改存储目录、端口和根路由\n")
+	for _, m := range config.Mounts {
+		mountPath, _ := filepath.Abs(m.Path)
+		fmt.Printf("挂载点: %s -> %s\n", m.Name, mountPath)
+	}
+	fmt.Printf("提示: 可通过 config.json 配置文件修改存储目录、挂载点、端口和根路由\n")
+
+	if err := handlers.StartSFTP(); err != nil {
+		log.Fatalf("无法启动 SFTP 服务: %v", err)
+	}
+	// 核对文件数据库与磁盘上的文件，目录较大时耗时较长，在后台进行
+	go filedb.ReconcileAll(storage.Roots())
+	if config.Cfg.S3 != nil {
+		go handlers.PurgeS3Uploads()
+	}
 
 	log.Fatal(http.ListenAndServe(config.Port, middleware.CORSMiddleware(r)))
 }
@@ -93,10 +122,67 @@ func setupRoutes() *mux.Router {
 	} else {
 		api = r.PathPrefix(rootPath + "/api").Subrouter()
 	}
-	api.HandleFunc("/files", handlers.ListFiles).Methods("GET")
-	api.HandleFunc("/upload", handlers.UploadFile).Methods("POST")
-	api.HandleFunc("/download/{filename:.*}", handlers.DownloadFile).Methods("GET")
-	api.HandleFunc("/delete/{filename:.*}", handlers.DeleteFile).Methods("DELETE")
+	api.HandleFunc("/settings", handlers.GetSettings).Methods("GET")
+	api.HandleFunc("/files", audit.Wrap("web", "list", handlers.ListFiles)).Methods("GET")
+	api.HandleFunc("/manifest", audit.Wrap("web", "manifest", handlers.Manifest)).Methods("GET")
+	api.HandleFunc("/usage", audit.Wrap("web", "usage", handlers.DiskUsage)).Methods("GET")
+	api.HandleFunc("/search", audit.Wrap("web", "search", handlers.SearchFiles)).Methods("GET")
+	api.HandleFunc("/upload", audit.Wrap("web", "upload", handlers.UploadFile)).Methods("POST")
+	api.HandleFunc("/upload/instant", audit.Wrap("web", "upload", handlers.InstantUpload)).Methods("POST")
+	api.HandleFunc("/download/{filename:.*}", audit.Wrap("web", "download", handlers.DownloadFile)).Methods("GET")
+	api.HandleFunc("/view/{filename:.*}", audit.Wrap("web", "view", handlers.ViewFile)).Methods("GET")
+	api.HandleFunc("/details/{filename:.*}", handlers.FileDetails).Methods("GET")
+	api.HandleFunc("/content/{filename:.*}", audit.Wrap("web", "edit", handlers.SaveContent)).Methods("PUT")
+	api.HandleFunc("/thumbnail/{filename:.*}", handlers.Thumbnail).Methods("GET")
+	api.HandleFunc("/delete/{filename:.*}", audit.Wrap("web", "delete", handlers.DeleteFile)).Methods("DELETE")
+	api.HandleFunc("/mkdir", audit.Wrap("web", "mkdir", handlers.Mkdir)).Methods("POST")
+	api.HandleFunc("/move", audit.Wrap("web", "move", handlers.MoveFile)).Methods("POST")
+	api.HandleFunc("/tags/{filename:.*}", audit.Wrap("web", "tag", handlers.SetTags)).Methods("PUT")
+	api.HandleFunc("/shares", handlers.ListShares).Methods("GET")
+	api.HandleFunc("/shares", audit.Wrap("web", "share-create", handlers.CreateShare)).Methods("POST")
+	api.HandleFunc("/shares/{token}", audit.Wrap("web", "share-revoke", handlers.RevokeShare)).Methods("DELETE")
+	api.HandleFunc("/drops", handlers.ListDrops).Methods("GET")
+	api.HandleFunc("/drops", audit.Wrap("web", "drop-create", handlers.CreateDrop)).Methods("POST")
+	api.HandleFunc("/drops/{token}", audit.Wrap("web", "drop-revoke", handlers.RevokeDrop)).Methods("DELETE")
+	api.HandleFunc("/audit", handlers.AuditLog).Methods("GET")
+	api.HandleFunc("/audit/export", handlers.ExportAuditLog).Methods("GET")
+
+	// 公开分享链接 - 不经过 API 路由
+	var shareRouter *mux.Router
+	if rootPath == "/" {
+		shareRouter = r.PathPrefix("/s").Subrouter()
+	} else {
+		shareRouter = r.PathPrefix(rootPath + "/s").Subrouter()
+	}
+	shareRouter.HandleFunc("/{token}", handlers.ServeSharePage).Methods("GET")
+	shareRouter.HandleFunc("/{token}/info", handlers.ShareInfo).Methods("GET")
+	shareRouter.HandleFunc("/{token}/download", audit.Wrap("share", "download", handlers.ShareDownload)).Methods("GET")
+	shareRouter.HandleFunc("/{token}/ticket", handlers.ShareTicket).Methods("POST")
+
+	// 公开上传链接 - 只允许上传
+	var dropRouter *mux.Router
+	if rootPath == "/" {
+		dropRouter = r.PathPrefix("/d").Subrouter()
+	} else {
+		dropRouter = r.PathPrefix(rootPath + "/d").Subrouter()
+	}
+	dropRouter.HandleFunc("/{token}", handlers.ServeDropPage).Methods("GET")
+	dropRouter.HandleFunc("/{token}/info", handlers.DropInfo).Methods("GET")
+	dropRouter.HandleFunc("/{token}/upload", audit.Wrap("drop", "upload", handlers.DropUpload)).Methods("POST")
+
+	// WebDAV - 可作为网络驱动器挂载
+	davPrefix := strings.TrimSuffix(rootPath, "/") + "/dav"
+	davHandler := handlers.NewWebDAVHandler(davPrefix)
+	r.Path(davPrefix).Handler(davHandler)
+	r.PathPrefix(davPrefix + "/").Handler(davHandler)
+
+	// S3 兼容接口 - 配置访问密钥后启用
+	if config.Cfg.S3 != nil {
+		s3Prefix := strings.TrimSuffix(rootPath, "/") + "/s3"
+		s3Handler := handlers.NewS3Handler(s3Prefix)
+		r.Path(s3Prefix).Handler(s3Handler)
+		r.PathPrefix(s3Prefix + "/").Handler(s3Handler)
+	}
 
 	// 前端页面 - 使用配置的根路由
 	r.HandleFunc(rootPath, handlers.ServeIndex).Methods("GET")
@@ -0,0 +1,216 @@
+// 从全局配置获取根路径，如果没有则默认为 "/"
+const ROOT_PATH = (typeof window !== 'undefined' && window.ROOT_PATH) || '/';
+const DROP_TOKEN = window.location.pathname.split('/').filter(p => p).pop();
+const DROP_BASE = (ROOT_PATH === '/' ? '' : ROOT_PATH) + '/d/' + DROP_TOKEN;
+let dropInfo = null;
+
+// DOM 元素
+const dropSubtitle = document.getElementById('dropSubtitle');
+const dropSection = document.getElementById('dropSection');
+const uploadArea = document.getElementById('uploadArea');
+const uploadHint = document.getElementById('uploadHint');
+const fileInput = document.getElementById('fileInput');
+const uploadBtn = document.getElementById('uploadBtn');
+const toast = document.getElementById('toast');
+
+// 初始化
+document.addEventListener('DOMContentLoaded', () => {
+    setupEventListeners();
+    loadDropInfo();
+});
+
+// 设置事件监听器
+function setupEventListeners() {
+    uploadBtn.addEventListener('click', () => {
+        fileInput.click();
+    });
+
+    fileInput.addEventListener('change', (e) => {
+        handleFiles(e.target.files);
+    });
+
+    uploadArea.addEventListener('click', () => {
+        fileInput.click();
+    });
+
+    uploadArea.addEventListener('dragover', (e) => {
+        e.preventDefault();
+        uploadArea.classList.add('dragover');
+    });
+
+    uploadArea.addEventListener('dragleave', () => {
+        uploadArea.classList.remove('dragover');
+    });
+
+    uploadArea.addEventListener('drop', (e) => {
+        e.preventDefault();
+        uploadArea.classList.remove('dragover');
+        handleFiles(e.dataTransfer.files);
+    });
+}
+
+// 加载上传链接信息
+async function loadDropInfo() {
+    try {
+        const response = await fetch(`${DROP_BASE}/info`);
+        const data = await response.json();
+        if (!data.success) {
+            dropSubtitle.textContent = data.message || '上传链接不可用';
+            dropSection.style.display = 'none';
+            return;
+        }
+
+        dropInfo = data.data;
+        let subtitle = `上传到: ${dropInfo.name}`;
+        if (dropInfo.expiresAt) {
+            subtitle += ` · 有效期至 ${new Date(dropInfo.expiresAt).toLocaleString('zh-CN')}`;
+        }
+        dropSubtitle.textContent = subtitle;
+
+        const hints = ['支持多个文件'];
+        if (dropInfo.maxFileSize) {
+            hints.push(`单个文件不超过 ${formatFileSize(dropInfo.maxFileSize)}`);
+        }
+        if (dropInfo.maxTotalSize) {
+            hints.push(`剩余容量 ${formatFileSize(Math.max(dropInfo.maxTotalSize - dropInfo.uploaded, 0))}`);
+        }
+        uploadHint.textContent = hints.join('，');
+        dropSection.style.display = 'block';
+    } catch (error) {
+        dropSubtitle.textContent = '加载失败';
+        showToast('加载失败: ' + error.message, 'error');
+    }
+}
+
+// 处理文件上传
+function handleFiles(fileList) {
+    if (fileList.length === 0) return;
+
+    Array.from(fileList).forEach(file => {
+        if (dropInfo && dropInfo.maxFileSize && file.size > dropInfo.maxFileSize) {
+            showToast(`${file.name} 超过大小限制`, 'error');
+            return;
+        }
+        uploadFile(file);
+    });
+
+    // 清空文件选择，允许重复选择同一文件
+    fileInput.value = '';
+}
+
+// 上传单个文件
+function uploadFile(file) {
+    const formData = new FormData();
+    formData.append('file', file);
+
+    const progressContainer = document.getElementById('uploadProgress');
+    progressContainer.style.display = 'block';
+
+    const progressItem = document.createElement('div');
+    progressItem.className = 'upload-progress-item';
+    progressItem.innerHTML = `
+        <div class="progress-header">
+            <span class="progress-filename"></span>
+            <span class="progress-percent">0%</span>
+        </div>
+        <div class="progress-bar">
+            <div class="progress-bar-fill" style="width: 0%"></div>
+        </div>
+        <div class="progress-info">
+            <span class="progress-size">0 / ${formatFileSize(file.size)}</span>
+            <span class="progress-speed">计算中...</span>
+        </div>
+    `;
+    progressItem.querySelector('.progress-filename').textContent = file.name;
+    progressContainer.appendChild(progressItem);
+
+    const xhr = new XMLHttpRequest();
+    const progressBar = progressItem.querySelector('.progress-bar-fill');
+    const progressPercent = progressItem.querySelector('.progress-percent');
+    const progressSize = progressItem.querySelector('.progress-size');
+    const progressSpeed = progressItem.querySelector('.progress-speed');
+
+    let lastLoaded = 0;
+    let lastTime = Date.now();
+
+    xhr.upload.addEventListener('progress', (e) => {
+        if (e.lengthComputable) {
+            const percent = Math.round((e.loaded / e.total) * 100);
+            progressBar.style.width = percent + '%';
+            progressPercent.textContent = percent + '%';
+            progressSize.textContent = `${formatFileSize(e.loaded)} / ${formatFileSize(e.total)}`;
+
+            const now = Date.now();
+            const timeDelta = (now - lastTime) / 1000;
+            if (timeDelta > 0.1) {
+                progressSpeed.textContent = formatSpeed((e.loaded - lastLoaded) / timeDelta);
+                lastLoaded = e.loaded;
+                lastTime = now;
+            }
+        }
+    });
+
+    xhr.addEventListener('load', () => {
+        let data = {};
+        try {
+            data = JSON.parse(xhr.responseText);
+        } catch (e) {}
+
+        if (xhr.status === 200 && data.success) {
+            progressItem.classList.add('success');
+            progressPercent.textContent = '完成';
+            if (data.speed && data.speed.speedText) {
+                progressSpeed.textContent = `平均速度: ${data.speed.speedText}`;
+            }
+            showToast(data.message || '上传成功', 'success');
+            loadDropInfo();
+        } else {
+            progressItem.classList.add('error');
+            progressPercent.textContent = '失败';
+            progressSpeed.textContent = '上传失败';
+            showToast('上传失败: ' + (data.message || 'HTTP ' + xhr.status), 'error');
+        }
+    });
+
+    xhr.addEventListener('error', () => {
+        progressItem.classList.add('error');
+        progressPercent.textContent = '失败';
+        progressSpeed.textContent = '网络错误';
+        showToast('上传失败: 网络错误', 'error');
+    });
+
+    xhr.open('POST', `${DROP_BASE}/upload`);
+    xhr.send(formData);
+}
+
+// 格式化文件大小
+function formatFileSize(bytes) {
+    if (!bytes) return '0 B';
+    const k = 1024;
+    const sizes = ['B', 'KB', 'MB', 'GB'];
+    const i = Math.floor(Math.log(bytes) / Math.log(k));
+    return Math.round(bytes / Math.pow(k, i) * 100) / 100 + ' ' + sizes[i];
+}
+
+// 格式化速度
+function formatSpeed(bytesPerSec) {
+    if (bytesPerSec < 1024) {
+        return bytesPerSec.toFixed(0) + ' B/s';
+    } else if (bytesPerSec < 1024 * 1024) {
+        return (bytesPerSec / 1024).toFixed(2) + ' KB/s';
+    } else if (bytesPerSec < 1024 * 1024 * 1024) {
+        return (bytesPerSec / (1024 * 1024)).toFixed(2) + ' MB/s';
+    } else {
+        return (bytesPerSec / (1024 * 1024 * 1024)).toFixed(2) + ' GB/s';
+    }
+}
+
+// 显示提示消息
+function showToast(message, type = 'info') {
+    toast.textContent = message;
+    toast.className = `toast ${type} show`;
+
+    setTimeout(() => {
+        toast.classList.remove('show');
+    }, 3000);
+}
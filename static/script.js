@@ -1,23 +1,57 @@
 // 从全局配置获取根路径，如果没有则默认为 "/"
 const ROOT_PATH = (typeof window !== 'undefined' && window.ROOT_PATH) || '/';
 const API_BASE = ROOT_PATH === '/' ? '/api' : ROOT_PATH + '/api';
-let files = [];
+let files = [];          // 已加载的文件（分页加载，可能只是目录的一部分）
+let totalFiles = 0;      // 符合过滤条件的文件总数
+let nextCursor = null;   // 下一页游标，为 null 时已全部加载
+let listSeq = 0;         // 每次重新加载列表递增，丢弃过期的响应
+let loadingMore = false; // 是否正在加载下一页
+let filesMoreVisible = false; // “加载更多”区域是否在可视范围内
+const PAGE_SIZE = 200;   // 每页加载的条数
+let dirStatsTimer = null; // 等待目录统计完成的定时器
 let sortField = 'name'; // 当前排序字段: name, size, time
 let sortOrder = 'asc';  // 排序方向: asc, desc
 let currentPath = '';   // 当前路径
+let viewMode = localStorage.getItem('viewMode') || 'list'; // 文件列表视图: list, grid
+
+// 可以生成缩略图的图片类型
+const THUMBNAIL_EXTENSIONS = ['jpg', 'jpeg', 'png', 'gif', 'webp'];
 
 // DOM 元素
 const uploadArea = document.getElementById('uploadArea');
 const fileInput = document.getElementById('fileInput');
+const folderInput = document.getElementById('folderInput');
 const uploadBtn = document.getElementById('uploadBtn');
+const uploadFolderBtn = document.getElementById('uploadFolderBtn');
 const refreshBtn = document.getElementById('refreshBtn');
 const filesContainer = document.getElementById('filesContainer');
+const filesTableContainer = document.getElementById('filesTableContainer');
+const filesGrid = document.getElementById('filesGrid');
+const viewToggleBtn = document.getElementById('viewToggleBtn');
+const fileFilter = document.getElementById('fileFilter');
+const filesMore = document.getElementById('filesMore');
 const breadcrumb = document.getElementById('breadcrumb');
 const toast = document.getElementById('toast');
+const shareModal = document.getElementById('shareModal');
+const shareForm = document.getElementById('shareForm');
+const sharesModal = document.getElementById('sharesModal');
+const sharesContainer = document.getElementById('sharesContainer');
+const dropModal = document.getElementById('dropModal');
+const previewModal = document.getElementById('previewModal');
+const usageModal = document.getElementById('usageModal');
+const dropForm = document.getElementById('dropForm');
+const dropsContainer = document.getElementById('dropsContainer');
+const tagsModal = document.getElementById('tagsModal');
+const detailsModal = document.getElementById('detailsModal');
+const tagsForm = document.getElementById('tagsForm');
+let sharePath = '';     // 正在创建分享的路径
+let dropPath = '';      // 正在创建上传链接的目录
+let tagsPath = '';      // 正在编辑标签的路径
 
 // 初始化
 document.addEventListener('DOMContentLoaded', () => {
     setupEventListeners();
+    loadSettings();
     loadFiles();
     updateSortIcons();
 });
@@ -29,10 +63,17 @@ function setupEventListeners() {
         fileInput.click();
     });
 
+    uploadFolderBtn.addEventListener('click', () => {
+        folderInput.click();
+    });
+
     // 文件选择
     fileInput.addEventListener('change', (e) => {
         handleFiles(e.target.files);
     });
+    folderInput.addEventListener('change', (e) => {
+        handleFiles(e.target.files);
+    });
 
     // 拖拽上传
     uploadArea.addEventListener('click', () => {
@@ -51,7 +92,7 @@ function setupEventListeners() {
     uploadArea.addEventListener('drop', (e) => {
         e.preventDefault();
         uploadArea.classList.remove('dragover');
-        handleFiles(e.dataTransfer.files);
+        handleDrop(e.dataTransfer);
     });
 
     // 刷新按钮
@@ -59,6 +100,62 @@ function setupEventListeners() {
         loadFiles();
     });
 
+    // 切换列表/网格视图
+    updateViewToggle();
+    viewToggleBtn.addEventListener('click', () => {
+        viewMode = viewMode === 'grid' ? 'list' : 'grid';
+        localStorage.setItem('viewMode', viewMode);
+        updateViewToggle();
+        renderFiles();
+    });
+
+    // 分享
+    shareForm.addEventListener('submit', (e) => {
+        e.preventDefault();
+        createShare();
+    });
+    dropForm.addEventListener('submit', (e) => {
+        e.preventDefault();
+        createDrop();
+    });
+    tagsForm.addEventListener('submit', (e) => {
+        e.preventDefault();
+        saveTags();
+    });
+    document.getElementById('sharesBtn').addEventListener('click', () => {
+        openSharesModal();
+    });
+    document.getElementById('usageBtn').addEventListener('click', () => {
+        openUsage(currentPath);
+    });
+    document.getElementById('usageUpBtn').addEventListener('click', () => {
+        openUsage(usagePath.split('/').slice(0, -1).join('/'));
+    });
+    // 点击目录区块（或其中的文件）查看该目录
+    document.getElementById('usageTreemap').addEventListener('click', (e) => {
+        const cell = e.target.closest('[data-usage-path]');
+        if (cell) openUsage(cell.dataset.usagePath);
+    });
+    document.querySelectorAll('.modal-close:not(#previewCloseBtn)').forEach(btn => {
+        btn.addEventListener('click', () => {
+            document.getElementById(btn.dataset.close).style.display = 'none';
+        });
+    });
+
+    // 预览面板：关闭时停止播放并确认未保存的修改，支持 Esc 和点击空白处关闭
+    document.getElementById('previewCloseBtn').addEventListener('click', closePreview);
+    previewModal.addEventListener('click', (e) => {
+        if (e.target === previewModal) closePreview();
+    });
+    document.addEventListener('keydown', (e) => {
+        if (e.key === 'Escape' && previewModal.style.display !== 'none') closePreview();
+    });
+
+    // 文本编辑
+    document.getElementById('previewEditBtn').addEventListener('click', () => openEditor());
+    document.getElementById('editorSaveBtn').addEventListener('click', () => saveEditor());
+    document.getElementById('editorCancelBtn').addEventListener('click', () => cancelEditor());
+
     // 排序按钮
     document.querySelectorAll('.sortable').forEach(th => {
         th.addEventListener('click', () => {
@@ -71,169 +168,628 @@ function setupEventListeners() {
                 sortField = field;
                 sortOrder = 'asc';
             }
-            sortFiles();
-            renderFiles();
             updateSortIcons();
+            loadFiles(currentPath);
         });
     });
+
+    // 文件名/扩展名过滤或标签搜索，输入停顿后重新加载
+    let filterTimer = null;
+    fileFilter.addEventListener('input', () => {
+        clearTimeout(filterTimer);
+        filterTimer = setTimeout(() => loadFiles(currentPath), 300);
+    });
+
+    // 滚动到列表底部时自动加载下一页
+    document.getElementById('loadMoreBtn').addEventListener('click', loadMoreFiles);
+    if (typeof IntersectionObserver !== 'undefined') {
+        new IntersectionObserver((entries) => {
+            filesMoreVisible = entries[0].isIntersecting;
+            if (filesMoreVisible) loadMoreFiles();
+        }).observe(filesMore);
+    }
 }
 
-// 处理文件上传
+// 处理文件上传（文件选择框或文件夹选择框）
 function handleFiles(fileList) {
     if (fileList.length === 0) return;
 
-    Array.from(fileList).forEach(file => {
-        uploadFile(file);
-    });
-    
+    // 通过文件夹选择框选择时，webkitRelativePath 为 "文件夹/子目录/文件名"
+    const items = Array.from(fileList).map(file => ({
+        file,
+        relativePath: file.webkitRelativePath || file.name
+    }));
+    uploadItems(items, []);
+
     // 清空文件选择，允许重复选择同一文件
     fileInput.value = '';
+    folderInput.value = '';
 }
 
-// 上传单个文件
-function uploadFile(file) {
-    const formData = new FormData();
-    formData.append('file', file);
+// 处理拖拽上传，支持拖入整个文件夹
+function handleDrop(dataTransfer) {
+    // 必须在事件处理函数中同步获取 entry，之后 dataTransfer 会失效
+    const entries = Array.from(dataTransfer.items || [])
+        .filter(item => item.kind === 'file' && item.webkitGetAsEntry)
+        .map(item => item.webkitGetAsEntry())
+        .filter(entry => entry);
+    if (entries.length === 0) {
+        handleFiles(dataTransfer.files);
+        return;
+    }
+
+    collectEntries(entries)
+        .then(({ items, dirs }) => uploadItems(items, dirs))
+        .catch(error => showToast('无法读取拖入的文件: ' + error.message, 'error'));
+}
+
+// 递归读取拖入的文件和文件夹，返回文件列表和空文件夹列表
+async function collectEntries(entries) {
+    const items = [];
+    const dirs = [];
+
+    async function walk(entry, prefix) {
+        if (entry.isFile) {
+            const file = await new Promise((resolve, reject) => entry.file(resolve, reject));
+            items.push({ file, relativePath: prefix + file.name });
+        } else if (entry.isDirectory) {
+            const dirPath = prefix + entry.name;
+            const children = await readDirectoryEntries(entry);
+            if (children.length === 0) {
+                dirs.push(dirPath);
+            }
+            for (const child of children) {
+                await walk(child, dirPath + '/');
+            }
+        }
+    }
+
+    for (const entry of entries) {
+        await walk(entry, '');
+    }
+    return { items, dirs };
+}
+
+// 读取目录下的所有 entry（readEntries 每次只返回一部分，需要循环读取）
+async function readDirectoryEntries(dirEntry) {
+    const reader = dirEntry.createReader();
+    const result = [];
+    while (true) {
+        const batch = await new Promise((resolve, reject) => reader.readEntries(resolve, reject));
+        if (batch.length === 0) return result;
+        result.push(...batch);
+    }
+}
+
+// 拼接上传目录
+function joinPath(...parts) {
+    return parts.filter(p => p).join('/');
+}
+
+// 上传一组文件：单独的文件各自显示进度，同一文件夹中的文件汇总显示进度
+function uploadItems(items, emptyDirs) {
+    const basePath = currentPath;
+    const folders = new Map();
+    const folderOf = (relativePath) => {
+        const name = relativePath.split('/')[0];
+        if (!folders.has(name)) {
+            folders.set(name, { items: [], dirs: [] });
+        }
+        return folders.get(name);
+    };
+
+    items.forEach(item => {
+        if (item.relativePath.includes('/')) {
+            folderOf(item.relativePath).items.push(item);
+        } else {
+            uploadFile(item.file, basePath);
+        }
+    });
+    emptyDirs.forEach(dir => folderOf(dir).dirs.push(dir));
+
+    folders.forEach((folder, name) => uploadFolder(name, folder.items, folder.dirs, basePath));
+}
+
+// 上传队列：限制同时上传的文件数，支持暂停、继续、取消，失败后按退避间隔重试
+const uploadQueue = {
+    concurrency: 3,  // 同时上传的文件数，启动时使用服务器建议值
+    maxRetries: 3,   // 失败后最多重试次数
+    retryDelay: 1000, // 首次重试等待时间（毫秒），之后每次翻倍
+    tasks: []
+};
+
+const UPLOAD_CONCURRENCY_KEY = 'uploadConcurrency';
 
-    // 创建进度条
-    const progressId = 'progress-' + Date.now() + '-' + Math.random().toString(36).substr(2, 9);
+// 加载服务器设置。并发上传数优先使用用户在本浏览器中的设置，否则使用服务器建议值
+async function loadSettings() {
+    const input = document.getElementById('uploadConcurrency');
+    const saved = parseInt(localStorage.getItem(UPLOAD_CONCURRENCY_KEY), 10);
+    if (saved > 0) {
+        setUploadConcurrency(saved);
+    }
+    try {
+        const response = await fetch(`${API_BASE}/settings`);
+        const data = await response.json();
+        if (data.success && data.data) {
+            if (!(saved > 0) && data.data.uploadConcurrency > 0) {
+                setUploadConcurrency(data.data.uploadConcurrency);
+            }
+            if (data.data.maxEditSize > 0) {
+                maxEditSize = data.data.maxEditSize;
+            }
+        }
+    } catch (error) {
+        console.error('获取服务器设置失败:', error);
+    }
+    input.value = uploadQueue.concurrency;
+    input.addEventListener('change', () => {
+        const value = parseInt(input.value, 10);
+        if (value > 0) {
+            setUploadConcurrency(value);
+            localStorage.setItem(UPLOAD_CONCURRENCY_KEY, value);
+        }
+        input.value = uploadQueue.concurrency;
+    });
+}
+
+function setUploadConcurrency(value) {
+    uploadQueue.concurrency = Math.max(1, Math.min(value, 10));
+    pumpUploadQueue();
+}
+
+// 加入上传队列，返回任务；task.promise 在上传成功时完成，失败或取消时拒绝
+// onProgress(loaded, total) 报告进度，onStateChange(task) 在状态变化时调用
+function enqueueUpload(file, path, onProgress, onStateChange) {
+    const task = {
+        file, path, onProgress, onStateChange,
+        status: 'queued', // queued, uploading, retrying, paused, done, failed, cancelled
+        attempts: 0,
+        request: null,
+        timer: null
+    };
+    task.promise = new Promise((resolve, reject) => {
+        task.resolve = resolve;
+        task.reject = reject;
+    });
+    uploadQueue.tasks.push(task);
+    pumpUploadQueue();
+    return task;
+}
+
+// 按并发数启动等待中的任务
+function pumpUploadQueue() {
+    uploadQueue.tasks = uploadQueue.tasks.filter(t => !['done', 'failed', 'cancelled'].includes(t.status));
+    let active = uploadQueue.tasks.filter(t => t.status === 'uploading').length;
+    for (const task of uploadQueue.tasks) {
+        if (active >= uploadQueue.concurrency) break;
+        if (task.status === 'queued') {
+            startUploadTask(task);
+            active++;
+        }
+    }
+}
+
+function setTaskStatus(task, status) {
+    task.status = status;
+    if (task.onStateChange) task.onStateChange(task);
+}
+
+function startUploadTask(task) {
+    task.attempts++;
+    setTaskStatus(task, 'uploading');
+    const request = sendFile(task.file, task.path, task.onProgress);
+    task.request = request;
+    request.then(data => {
+        if (task.request !== request) return; // 已暂停或取消
+        task.request = null;
+        setTaskStatus(task, 'done');
+        task.resolve(data);
+        pumpUploadQueue();
+    }).catch(error => {
+        if (task.request !== request) return;
+        task.request = null;
+        if (isRetryableError(error) && task.attempts <= uploadQueue.maxRetries) {
+            task.retryIn = uploadQueue.retryDelay * Math.pow(2, task.attempts - 1);
+            task.lastError = error;
+            setTaskStatus(task, 'retrying');
+            task.timer = setTimeout(() => {
+                task.timer = null;
+                setTaskStatus(task, 'queued');
+                pumpUploadQueue();
+            }, task.retryIn);
+        } else {
+            setTaskStatus(task, 'failed');
+            task.reject(error);
+        }
+        pumpUploadQueue();
+    });
+}
+
+// 网络错误和服务器临时错误可以重试；请求本身有误、空间不足等重试也不会成功
+function isRetryableError(error) {
+    const status = error.status || 0;
+    return status === 0 || status === 408 || status === 429 || (status >= 500 && status !== 507);
+}
+
+// 中止正在进行的请求或等待中的重试
+function stopUploadTask(task) {
+    if (task.timer) {
+        clearTimeout(task.timer);
+        task.timer = null;
+    }
+    if (task.request) {
+        const request = task.request;
+        task.request = null;
+        request.xhr.abort();
+    }
+}
+
+function pauseUpload(task) {
+    if (!['queued', 'uploading', 'retrying'].includes(task.status)) return;
+    stopUploadTask(task);
+    setTaskStatus(task, 'paused');
+    pumpUploadQueue();
+}
+
+// 继续已暂停的任务，文件从头重新上传
+function resumeUpload(task) {
+    if (task.status !== 'paused') return;
+    task.attempts = 0;
+    setTaskStatus(task, 'queued');
+    pumpUploadQueue();
+}
+
+function cancelUpload(task) {
+    if (['done', 'failed', 'cancelled'].includes(task.status)) return;
+    stopUploadTask(task);
+    setTaskStatus(task, 'cancelled');
+    const error = new Error('上传已取消');
+    error.cancelled = true;
+    task.reject(error);
+    pumpUploadQueue();
+}
+
+// 为进度条绑定暂停/继续和取消按钮，作用于 tasks 中所有未结束的任务
+function bindUploadControls(progressItem, tasks) {
+    const pauseBtn = progressItem.querySelector('.progress-pause');
+    const cancelBtn = progressItem.querySelector('.progress-cancel');
+    const pending = () => tasks.filter(t => !['done', 'failed', 'cancelled'].includes(t.status));
+
+    pauseBtn.addEventListener('click', () => {
+        const list = pending();
+        if (list.some(t => t.status === 'paused')) {
+            list.forEach(resumeUpload);
+        } else {
+            list.forEach(pauseUpload);
+        }
+    });
+    cancelBtn.addEventListener('click', () => pending().forEach(cancelUpload));
+
+    // 状态变化时刷新按钮
+    return () => {
+        const list = pending();
+        const paused = list.some(t => t.status === 'paused');
+        pauseBtn.textContent = paused ? '继续' : '暂停';
+        pauseBtn.style.display = list.length > 0 ? '' : 'none';
+        cancelBtn.style.display = list.length > 0 ? '' : 'none';
+    };
+}
+
+// 任务状态的显示文字，上传中返回 null（显示百分比）
+function uploadStatusText(task) {
+    switch (task.status) {
+        case 'queued': return '等待中';
+        case 'paused': return '已暂停';
+        case 'retrying': return `${Math.round(task.retryIn / 1000)} 秒后重试`;
+        default: return null;
+    }
+}
+
+// 发送上传请求，返回服务器响应；onProgress(loaded, total) 报告上传进度
+// 返回的 Promise 带有 xhr 属性，可用于中止上传；失败时 error.status 为 HTTP 状态码（网络错误为 0）
+function sendFile(file, path, onProgress) {
+    let xhr;
+    const promise = new Promise((resolve, reject) => {
+        const formData = new FormData();
+        formData.append('file', file);
+
+        xhr = new XMLHttpRequest();
+        xhr.upload.addEventListener('progress', (e) => {
+            if (e.lengthComputable && onProgress) {
+                onProgress(e.loaded, e.total);
+            }
+        });
+
+        const fail = (message) => {
+            const error = new Error(message);
+            error.status = xhr.status;
+            reject(error);
+        };
+        xhr.addEventListener('load', () => {
+            let data = null;
+            try {
+                data = JSON.parse(xhr.responseText);
+            } catch (e) {}
+            if (xhr.status === 200 && data && data.success) {
+                resolve(data);
+                return;
+            }
+            let message = '上传失败: HTTP ' + xhr.status;
+            if (data && data.message) message = '上传失败: ' + data.message;
+            fail(message);
+        });
+        xhr.addEventListener('error', () => fail('上传失败: 网络错误'));
+        xhr.addEventListener('abort', () => fail('上传已取消'));
+
+        // 构建上传URL，包含目标路径
+        let uploadUrl = `${API_BASE}/upload`;
+        if (path) {
+            uploadUrl += `?path=${encodeURIComponent(path)}`;
+        }
+        xhr.open('POST', uploadUrl);
+        xhr.send(formData);
+    });
+    promise.xhr = xhr;
+    return promise;
+}
+
+// 创建上传进度条
+function createProgressItem(title, totalText) {
     const progressContainer = document.getElementById('uploadProgress');
     progressContainer.style.display = 'block';
-    
+
     const progressItem = document.createElement('div');
     progressItem.className = 'upload-progress-item';
-    progressItem.id = progressId;
     progressItem.innerHTML = `
         <div class="progress-header">
-            <span class="progress-filename">${file.name}</span>
-            <span class="progress-percent">0%</span>
+            <span class="progress-filename"></span>
+            <span class="progress-percent">等待中</span>
+            <button class="progress-btn progress-pause">暂停</button>
+            <button class="progress-btn progress-cancel">取消</button>
         </div>
         <div class="progress-bar">
             <div class="progress-bar-fill" style="width: 0%"></div>
         </div>
         <div class="progress-info">
-            <span class="progress-size">0 / ${formatFileSize(file.size)}</span>
-            <span class="progress-speed">计算中...</span>
+            <span class="progress-size">0 / ${totalText}</span>
+            <span class="progress-speed"></span>
         </div>
     `;
+    progressItem.querySelector('.progress-filename').textContent = title;
     progressContainer.appendChild(progressItem);
+    return progressItem;
+}
+
+// 移除上传进度条
+function removeProgressItem(progressItem, delay) {
+    const progressContainer = document.getElementById('uploadProgress');
+    setTimeout(() => {
+        progressItem.remove();
+        if (progressContainer.children.length === 0) {
+            progressContainer.style.display = 'none';
+        }
+    }, delay);
+}
+
+// 创建速度计算器，返回根据已传输字节数计算速度的函数
+function createSpeedMeter(speedElement) {
+    let lastLoaded = 0;
+    let lastTime = Date.now();
+    return (loaded) => {
+        const now = Date.now();
+        if (loaded < lastLoaded) { // 暂停或重试后重新开始
+            lastLoaded = loaded;
+            lastTime = now;
+            return;
+        }
+        const timeDelta = (now - lastTime) / 1000; // 秒
+        if (timeDelta > 0.1) { // 至少间隔100ms
+            const speed = (loaded - lastLoaded) / timeDelta; // 字节/秒
+            speedElement.textContent = formatSpeed(speed);
+            lastLoaded = loaded;
+            lastTime = now;
+        }
+    };
+}
 
-    const xhr = new XMLHttpRequest();
+// 上传单个文件
+function uploadFile(file, path = currentPath) {
+    const progressItem = createProgressItem(file.name, formatFileSize(file.size));
     const progressBar = progressItem.querySelector('.progress-bar-fill');
     const progressPercent = progressItem.querySelector('.progress-percent');
     const progressSize = progressItem.querySelector('.progress-size');
     const progressSpeed = progressItem.querySelector('.progress-speed');
-    
-    // 速度计算相关变量
-    let lastLoaded = 0;
-    let lastTime = Date.now();
+    const updateSpeed = createSpeedMeter(progressSpeed);
+    let updateControls = () => {};
 
-    xhr.upload.addEventListener('progress', (e) => {
-        if (e.lengthComputable) {
-            const percent = Math.round((e.loaded / e.total) * 100);
-            progressBar.style.width = percent + '%';
-            progressPercent.textContent = percent + '%';
-            progressSize.textContent = `${formatFileSize(e.loaded)} / ${formatFileSize(e.total)}`;
-            
-            // 计算速度
-            const now = Date.now();
-            const timeDelta = (now - lastTime) / 1000; // 秒
-            if (timeDelta > 0.1) { // 至少间隔100ms
-                const bytesDelta = e.loaded - lastLoaded;
-                const speed = bytesDelta / timeDelta; // 字节/秒
-                progressSpeed.textContent = formatSpeed(speed);
-                
-                lastLoaded = e.loaded;
-                lastTime = now;
-            }
+    const task = enqueueUpload(file, path, (loaded, total) => {
+        const percent = Math.round((loaded / total) * 100);
+        progressBar.style.width = percent + '%';
+        progressPercent.textContent = percent + '%';
+        progressSize.textContent = `${formatFileSize(loaded)} / ${formatFileSize(total)}`;
+        updateSpeed(loaded);
+    }, (task) => {
+        const text = uploadStatusText(task);
+        if (text) {
+            progressPercent.textContent = text;
+            progressSpeed.textContent = task.status === 'retrying' ? task.lastError.message : '';
+        } else if (task.status === 'uploading') {
+            progressBar.style.width = '0%';
+            progressPercent.textContent = '0%';
+            progressSpeed.textContent = task.attempts > 1 ? `第 ${task.attempts - 1} 次重试` : '计算中...';
         }
+        updateControls();
     });
+    updateControls = bindUploadControls(progressItem, [task]);
+    updateControls();
 
-    xhr.addEventListener('load', () => {
-        if (xhr.status === 200) {
-            try {
-                const data = JSON.parse(xhr.responseText);
-                if (data.success) {
-                    progressItem.classList.add('success');
-                    progressPercent.textContent = '完成';
-                    
-                    // 显示后端返回的速度信息
-                    if (data.speed && data.speed.speedText) {
-                        progressSpeed.textContent = `平均速度: ${data.speed.speedText}`;
-                        progressSpeed.style.color = '#27ae60';
-                        progressSpeed.style.fontWeight = '600';
-                    }
-                    
-                    setTimeout(() => {
-                        progressItem.remove();
-                        if (progressContainer.children.length === 0) {
-                            progressContainer.style.display = 'none';
-                        }
-                    }, 3000); // 延长显示时间以便查看速度信息
-                    // 延迟刷新文件列表，避免多个文件同时上传时频繁刷新
-                    setTimeout(() => {
-                        loadFiles(currentPath);
-                    }, 500);
-                } else {
-                    progressItem.classList.add('error');
-                    progressPercent.textContent = '失败';
-                    progressSpeed.textContent = '上传失败';
-                    showToast(data.message || '上传失败', 'error');
-                }
-            } catch (e) {
-                progressItem.classList.add('error');
-                progressPercent.textContent = '失败';
-                progressSpeed.textContent = '上传失败';
-                showToast('上传失败', 'error');
-            }
-        } else {
-            progressItem.classList.add('error');
-            progressPercent.textContent = '失败';
-            progressSpeed.textContent = '上传失败';
-            showToast('上传失败: HTTP ' + xhr.status, 'error');
+    task.promise.then(data => {
+        progressItem.classList.add('success');
+        progressPercent.textContent = '完成';
+
+        // 显示后端返回的速度信息
+        if (data.speed && data.speed.speedText) {
+            progressSpeed.textContent = `平均速度: ${data.speed.speedText}`;
+            progressSpeed.style.color = '#27ae60';
+            progressSpeed.style.fontWeight = '600';
         }
-    });
 
-    xhr.addEventListener('error', () => {
+        removeProgressItem(progressItem, 3000); // 延长显示时间以便查看速度信息
+        // 延迟刷新文件列表，避免多个文件同时上传时频繁刷新
+        setTimeout(() => {
+            loadFiles(currentPath);
+        }, 500);
+    }).catch(error => {
+        if (error.cancelled) {
+            progressPercent.textContent = '已取消';
+            removeProgressItem(progressItem, 1000);
+            return;
+        }
         progressItem.classList.add('error');
         progressPercent.textContent = '失败';
-        progressSpeed.textContent = '网络错误';
-        showToast('上传失败: 网络错误', 'error');
+        progressSpeed.textContent = '上传失败';
+        showToast(error.message, 'error');
     });
+}
 
-    xhr.addEventListener('abort', () => {
-        progressItem.remove();
-        if (progressContainer.children.length === 0) {
-            progressContainer.style.display = 'none';
+// 上传整个文件夹，保留目录结构，显示汇总进度
+async function uploadFolder(name, items, emptyDirs, basePath) {
+    const totalBytes = items.reduce((sum, item) => sum + item.file.size, 0);
+    const progressItem = createProgressItem(`📁 ${name}（${items.length} 个文件）`, formatFileSize(totalBytes));
+    const progressBar = progressItem.querySelector('.progress-bar-fill');
+    const progressPercent = progressItem.querySelector('.progress-percent');
+    const progressSize = progressItem.querySelector('.progress-size');
+    const progressSpeed = progressItem.querySelector('.progress-speed');
+    const updateSpeed = createSpeedMeter(progressSpeed);
+    const startTime = Date.now();
+
+    const loadedBytes = new Map(); // 每个任务已上传的字节数
+    let tasks = [];
+    let done = 0;
+    let updateControls = () => {};
+    const update = () => {
+        let loaded = 0;
+        loadedBytes.forEach(n => loaded += n);
+        const percent = totalBytes > 0 ? Math.floor((loaded / totalBytes) * 100) : 100;
+        progressBar.style.width = percent + '%';
+        progressSize.textContent = `${done} / ${items.length} 个文件，${formatFileSize(loaded)} / ${formatFileSize(totalBytes)}`;
+        const pending = tasks.filter(t => !['done', 'failed', 'cancelled'].includes(t.status));
+        if (pending.length > 0 && pending.every(t => t.status === 'paused')) {
+            progressPercent.textContent = '已暂停';
+        } else if (pending.length > 0 && pending.every(t => t.status === 'queued')) {
+            progressPercent.textContent = '等待中';
+        } else {
+            progressPercent.textContent = percent + '%';
+            updateSpeed(loaded);
         }
+    };
+
+    // 目标目录为 当前目录/文件在文件夹中的相对目录
+    tasks = items.map(item => {
+        const relativeDir = item.relativePath.split('/').slice(0, -1).join('/');
+        const task = enqueueUpload(item.file, joinPath(basePath, relativeDir), (loaded) => {
+            loadedBytes.set(task, loaded);
+            update();
+        }, (task) => {
+            if (task.status === 'done') {
+                loadedBytes.set(task, task.file.size);
+                done++;
+            } else if (task.status !== 'uploading') {
+                loadedBytes.set(task, 0);
+            }
+            update();
+            updateControls();
+        });
+        return task;
     });
+    updateControls = bindUploadControls(progressItem, tasks);
+    update();
+    updateControls();
 
-    // 构建上传URL，包含当前路径
-    let uploadUrl = `${API_BASE}/upload`;
-    if (currentPath) {
-        uploadUrl += `?path=${encodeURIComponent(currentPath)}`;
+    const failed = [];
+    const results = await Promise.allSettled(tasks.map(t => t.promise));
+    let cancelled = 0;
+    results.forEach((result, i) => {
+        if (result.status === 'fulfilled') return;
+        if (result.reason.cancelled) {
+            cancelled++;
+        } else {
+            failed.push(`${items[i].relativePath}: ${result.reason.message}`);
+        }
+    });
+    if (items.length > 0 && cancelled === items.length) {
+        progressPercent.textContent = '已取消';
+        removeProgressItem(progressItem, 1000);
+        loadFiles(currentPath);
+        return;
     }
-    
-    xhr.open('POST', uploadUrl);
-    xhr.send(formData);
+
+    // 空文件夹没有文件可上传，单独创建
+    for (const dir of emptyDirs) {
+        try {
+            const response = await fetch(`${API_BASE}/mkdir`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ path: joinPath(basePath, dir) })
+            });
+            const data = await response.json();
+            if (!data.success) {
+                failed.push(`${dir}: ${data.message || '创建文件夹失败'}`);
+            }
+        } catch (error) {
+            failed.push(`${dir}: ${error.message}`);
+        }
+    }
+
+    const seconds = (Date.now() - startTime) / 1000;
+    if (failed.length === 0) {
+        progressItem.classList.add('success');
+        progressPercent.textContent = cancelled > 0 ? `完成（${cancelled} 个已取消）` : '完成';
+        progressSpeed.textContent = `平均速度: ${formatSpeed(seconds > 0 ? totalBytes / seconds : 0)}`;
+        progressSpeed.style.color = '#27ae60';
+        progressSpeed.style.fontWeight = '600';
+        removeProgressItem(progressItem, 3000);
+    } else {
+        progressItem.classList.add('error');
+        progressPercent.textContent = `${failed.length} 个失败`;
+        progressSpeed.textContent = '部分文件上传失败';
+        console.error('文件夹上传失败的文件:', failed);
+        showToast(`文件夹 ${name} 中有 ${failed.length} 项上传失败: ${failed[0]}`, 'error');
+    }
+    loadFiles(currentPath);
 }
 
 // 加载文件列表
 async function loadFiles(path = '') {
+    const seq = ++listSeq;
+    // 进入其他目录时清空过滤条件
+    if (path !== currentPath) {
+        fileFilter.value = '';
+    }
     try {
         currentPath = path;
+        nextCursor = null;
+        loadingMore = false;
         filesContainer.innerHTML = '<tr><td colspan="5" class="loading">加载中...</td></tr>';
         
-        const url = path ? `${API_BASE}/files?path=${encodeURIComponent(path)}` : `${API_BASE}/files`;
-        const response = await fetch(url);
+        const search = searchParams(path);
+        const response = await fetch(search ? `${API_BASE}/search?${search}` : fileListURL(path, null));
         const data = await response.json();
+        if (seq !== listSeq) return;
 
         if (data.success) {
-            files = data.data || [];
-            sortFiles();
+            if (search) {
+                files = searchResults(data.data || [], path);
+                totalFiles = files.length;
+            } else {
+                files = data.data.files || [];
+                totalFiles = data.data.total;
+                nextCursor = data.data.nextCursor || null;
+            }
             renderFiles();
             updateSortIcons();
             updateBreadcrumb(path);
+            watchDirStats(seq, 0);
         } else {
             showToast('加载文件列表失败', 'error');
             filesContainer.innerHTML = `
@@ -241,14 +797,17 @@ async function loadFiles(path = '') {
                     <td colspan="5" class="empty-state">加载失败</td>
                 </tr>
             `;
+            filesGrid.innerHTML = '<div class="empty-state">加载失败</div>';
         }
     } catch (error) {
+        if (seq !== listSeq) return;
         showToast('加载文件列表失败: ' + error.message, 'error');
         filesContainer.innerHTML = `
             <tr>
                 <td colspan="5" class="empty-state">加载失败</td>
             </tr>
         `;
+        filesGrid.innerHTML = '<div class="empty-state">加载失败</div>';
     }
 }
 
@@ -280,37 +839,228 @@ function updateBreadcrumb(path) {
 }
 
 // 进入目录
+// 文件列表请求地址，排序、过滤和分页由服务器完成
+function fileListURL(path, cursor) {
+    const params = new URLSearchParams({ limit: PAGE_SIZE, sort: sortField, order: sortOrder, dirStats: 'true' });
+    if (path) {
+        params.set('path', path);
+    }
+    // 以点开头时按扩展名过滤（可用逗号分隔多个），否则按文件名过滤
+    const filter = fileFilter.value.trim();
+    if (filter.startsWith('.')) {
+        params.set('ext', filter);
+    } else if (filter) {
+        params.set('name', filter);
+    }
+    if (cursor) {
+        params.set('cursor', cursor);
+    }
+    return `${API_BASE}/files?${params}`;
+}
+
+// 过滤框中含有 #标签 或 键=值 时改为在当前目录及其子目录中搜索，返回搜索参数，否则返回 null
+function searchParams(path) {
+    const params = new URLSearchParams();
+    const text = [];
+    let search = false;
+    fileFilter.value.trim().split(/\s+/).forEach(token => {
+        if (token.startsWith('#') && token.length > 1) {
+            params.append('tag', token.slice(1));
+            search = true;
+        } else if (token.indexOf('=') > 0) {
+            params.append('meta', token);
+            search = true;
+        } else if (token) {
+            text.push(token);
+        }
+    });
+    if (!search) return null;
+    if (text.length > 0) {
+        params.set('q', text.join(' '));
+    }
+    if (path) {
+        params.set('path', path);
+    }
+    return params;
+}
+
+// 搜索结果显示为相对当前目录的路径
+function searchResults(results, path) {
+    const prefix = path ? path + '/' : '';
+    return results.map(file => ({
+        ...file,
+        name: file.path.startsWith(prefix) ? file.path.slice(prefix.length) : file.path
+    }));
+}
+
+// 加载下一页并追加到列表末尾
+async function loadMoreFiles() {
+    if (!nextCursor || loadingMore) return;
+    const seq = listSeq;
+    loadingMore = true;
+    updateFilesMore();
+    try {
+        const response = await fetch(fileListURL(currentPath, nextCursor));
+        const data = await response.json();
+        if (seq !== listSeq) return;
+        if (!data.success) {
+            throw new Error(data.message || '加载失败');
+        }
+        const page = data.data.files || [];
+        files = files.concat(page);
+        totalFiles = data.data.total;
+        nextCursor = data.data.nextCursor || null;
+        appendFiles(page);
+        watchDirStats(seq, 0);
+    } catch (error) {
+        if (seq !== listSeq) return;
+        showToast('加载更多文件失败: ' + error.message, 'error');
+    } finally {
+        if (seq === listSeq) {
+            loadingMore = false;
+            updateFilesMore();
+            // 加载后底部仍在可视范围内时继续加载
+            if (filesMoreVisible && nextCursor) loadMoreFiles();
+        }
+    }
+}
+
+// 目录大小由服务器在后台统计，有未完成的目录时定时重新获取（最多约 1 分钟）
+function watchDirStats(seq, attempt) {
+    clearTimeout(dirStatsTimer);
+    if (attempt >= 30 || !files.some(f => f.statsPending)) return;
+    dirStatsTimer = setTimeout(() => refreshDirStats(seq, attempt + 1), 2000);
+}
+
+// 重新获取当前目录下子目录的统计结果并更新列表
+async function refreshDirStats(seq, attempt) {
+    if (seq !== listSeq) return;
+    try {
+        const params = new URLSearchParams({ type: 'dir', limit: 1000, dirStats: 'true' });
+        if (currentPath) {
+            params.set('path', currentPath);
+        }
+        const response = await fetch(`${API_BASE}/files?${params}`);
+        const data = await response.json();
+        if (seq !== listSeq || !data.success) return;
+
+        const stats = new Map((data.data.files || []).map(f => [f.path || f.name, f]));
+        let updated = false;
+        files = files.map(file => {
+            const fresh = stats.get(file.path || file.name);
+            if (file.statsPending && fresh && !fresh.statsPending) {
+                updated = true;
+                return fresh;
+            }
+            return file;
+        });
+        if (updated) {
+            // 按大小排序时需要服务器重新排序
+            if (sortField === 'size') {
+                loadFiles(currentPath);
+                return;
+            }
+            renderFiles();
+        }
+    } catch (error) {
+        console.error('获取目录统计失败:', error);
+    }
+    watchDirStats(seq, attempt);
+}
+
+// 目录大小的显示文字：统计完成时显示递归总大小，否则显示“计算中”
+function dirSizeText(file) {
+    if (file.stats) {
+        return formatFileSize(file.size);
+    }
+    return file.statsPending ? '计算中...' : '-';
+}
+
+// 目录统计的提示文字
+function dirStatsTitle(file) {
+    if (!file.stats) return '';
+    return `${file.stats.files} 个文件，${file.stats.dirs} 个子目录，最近修改于 ${formatDate(file.stats.modTime)}`;
+}
+
+// 更新列表底部的计数和“加载更多”按钮
+function updateFilesMore() {
+    filesMore.style.display = files.length > 0 ? '' : 'none';
+    document.getElementById('filesCount').textContent = nextCursor
+        ? `已显示 ${files.length} / ${totalFiles} 项`
+        : `共 ${totalFiles} 项`;
+    const loadMoreBtn = document.getElementById('loadMoreBtn');
+    loadMoreBtn.style.display = nextCursor ? '' : 'none';
+    loadMoreBtn.disabled = loadingMore;
+    loadMoreBtn.textContent = loadingMore ? '加载中...' : '加载更多';
+}
+
 function enterDirectory(path) {
     loadFiles(path);
 }
 
 // 渲染文件列表
 function renderFiles() {
+    const grid = viewMode === 'grid';
+    filesTableContainer.style.display = grid ? 'none' : '';
+    filesGrid.style.display = grid ? '' : 'none';
+    filesContainer.innerHTML = '';
+    filesGrid.innerHTML = '';
+    updateFilesMore();
+
     if (files.length === 0) {
-        filesContainer.innerHTML = `
-            <tr>
-                <td colspan="5" class="empty-state">
-                    <div class="empty-state-icon">📂</div>
-                    <p>暂无文件</p>
-                    <p style="margin-top: 10px; font-size: 0.9em;">上传您的第一个文件开始使用</p>
-                </td>
-            </tr>
+        const emptyState = fileFilter.value.trim() ? '<p>没有符合条件的文件</p>' : `
+            <div class="empty-state-icon">📂</div>
+            <p>暂无文件</p>
+            <p style="margin-top: 10px; font-size: 0.9em;">上传您的第一个文件开始使用</p>
         `;
+        if (grid) {
+            filesGrid.innerHTML = `<div class="empty-state">${emptyState}</div>`;
+        } else {
+            filesContainer.innerHTML = `<tr><td colspan="5" class="empty-state">${emptyState}</td></tr>`;
+        }
         return;
     }
 
-    filesContainer.innerHTML = files.map(file => createFileRow(file)).join('');
+    appendFiles(files);
+}
+
+// 将文件追加到当前视图末尾，只为新增的元素绑定事件
+function appendFiles(items) {
+    const grid = viewMode === 'grid';
+    const holder = document.createElement(grid ? 'div' : 'tbody');
+    holder.innerHTML = items.map(file => grid ? createFileCard(file) : createFileRow(file)).join('');
+
+    // 缩略图加载失败时显示图标
+    holder.querySelectorAll('.file-thumb img').forEach(img => {
+        img.addEventListener('error', () => {
+            img.parentElement.textContent = img.dataset.icon;
+        });
+    });
     
     // 添加目录点击事件
-    document.querySelectorAll('.file-dir').forEach(item => {
+    holder.querySelectorAll('.file-dir').forEach(item => {
         item.addEventListener('click', (e) => {
             const path = e.currentTarget.dataset.path;
             enterDirectory(path);
         });
     });
     
+    // 点击可预览的文件打开预览
+    holder.querySelectorAll('.file-previewable').forEach(item => {
+        item.addEventListener('click', (e) => {
+            openPreview(e.currentTarget.dataset.path);
+        });
+    });
+
     // 添加事件监听器
-    document.querySelectorAll('.btn-download').forEach(btn => {
+    holder.querySelectorAll('.btn-preview').forEach(btn => {
+        btn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            openPreview(e.target.dataset.path);
+        });
+    });
+
+    holder.querySelectorAll('.btn-download').forEach(btn => {
         btn.addEventListener('click', (e) => {
             e.stopPropagation();
             const path = e.target.dataset.path;
@@ -318,39 +1068,122 @@ function renderFiles() {
         });
     });
 
-    document.querySelectorAll('.btn-danger').forEach(btn => {
+    holder.querySelectorAll('.btn-share').forEach(btn => {
+        btn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            openShareModal(e.target.dataset.path);
+        });
+    });
+
+    holder.querySelectorAll('.btn-drop').forEach(btn => {
+        btn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            openDropModal(e.target.dataset.path);
+        });
+    });
+
+    holder.querySelectorAll('.btn-details').forEach(btn => {
+        btn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            openDetails(e.target.dataset.path);
+        });
+    });
+
+    holder.querySelectorAll('.btn-tags').forEach(btn => {
+        btn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            openTagsModal(e.target.dataset.path);
+        });
+    });
+
+    holder.querySelectorAll('.btn-danger').forEach(btn => {
         btn.addEventListener('click', (e) => {
             e.stopPropagation();
             const path = e.target.dataset.path;
             deleteFile(path);
         });
     });
+
+    (grid ? filesGrid : filesContainer).append(...holder.children);
 }
 
 // 创建文件表格行
 function createFileRow(file) {
-    const icon = file.isDir ? '📁' : getFileIcon(file.extension);
-    const size = file.isDir ? '-' : formatFileSize(file.size);
+    const icon = file.mount ? '💽' : (file.isDir ? '📁' : getFileIcon(file.extension));
+    let size = file.isDir ? dirSizeText(file) : formatFileSize(file.size);
+    if (file.mount && file.quota) {
+        size = file.stats ? `${size} / 配额 ${formatFileSize(file.quota)}` : '配额 ' + formatFileSize(file.quota);
+    }
     const date = formatDate(file.modTime);
-    const rowClass = file.isDir ? 'file-dir' : '';
+    const rowClass = file.isDir ? 'file-dir' : (previewType(file) ? 'file-previewable' : '');
     const path = file.path || file.name;
 
     return `
         <tr class="${rowClass}" data-path="${path}">
             <td>${icon}</td>
-            <td title="${file.name}" class="${file.isDir ? 'dir-name' : ''}">${file.name}${file.isDir ? ' /' : ''}</td>
-            <td>${size}</td>
+            <td title="${file.name}" class="${file.isDir ? 'dir-name' : ''}">${file.name}${file.isDir ? ' /' : ''}${file.mount && file.readOnly ? ' <span class="badge-readonly">只读</span>' : ''}${tagBadges(file)}</td>
+            <td title="${dirStatsTitle(file)}">${size}</td>
             <td>${date}</td>
             <td>
                 <div class="file-actions">
+                    ${!file.isDir && previewType(file) ? `<button class="btn btn-preview" data-path="${path}">预览</button>` : ''}
                     ${file.isDir ? '' : `<button class="btn btn-download" data-path="${path}">下载</button>`}
-                    <button class="btn btn-danger" data-path="${path}">删除</button>
+                    ${file.isDir ? '' : `<button class="btn btn-details" data-path="${path}">详情</button>`}
+                    <button class="btn btn-share" data-path="${path}">分享</button>
+                    ${file.isDir && !file.readOnly ? `<button class="btn btn-drop" data-path="${path}">上传链接</button>` : ''}
+                    ${file.mount || file.readOnly ? '' : `<button class="btn btn-tags" data-path="${path}">标签</button>`}
+                    ${file.mount || file.readOnly ? '' : `<button class="btn btn-danger" data-path="${path}">删除</button>`}
                 </div>
             </td>
         </tr>
     `;
 }
 
+// 创建网格视图中的文件卡片，图片显示缩略图
+function createFileCard(file) {
+    const icon = file.mount ? '💽' : (file.isDir ? '📁' : getFileIcon(file.extension));
+    let size = file.isDir ? (file.stats ? `${formatFileSize(file.size)} · ${file.stats.files} 个文件` : '') : formatFileSize(file.size);
+    if (file.mount && file.quota) {
+        size = file.stats ? `${formatFileSize(file.size)} / 配额 ${formatFileSize(file.quota)}` : '配额 ' + formatFileSize(file.quota);
+    }
+    const path = file.path || file.name;
+    const thumb = !file.isDir && THUMBNAIL_EXTENSIONS.includes(file.extension?.toLowerCase())
+        ? `<img src="${API_BASE}/thumbnail/${encodeURIComponent(path)}" alt="" loading="lazy" data-icon="${icon}">`
+        : icon;
+
+    return `
+        <div class="file-card ${file.isDir ? 'file-dir' : (previewType(file) ? 'file-previewable' : '')}" data-path="${path}">
+            <div class="file-thumb">${thumb}</div>
+            <div class="file-card-name" title="${file.name}">${file.name}${file.mount && file.readOnly ? ' <span class="badge-readonly">只读</span>' : ''}${tagBadges(file)}</div>
+            <div class="file-card-meta" title="${dirStatsTitle(file)}">${size}</div>
+            <div class="file-actions">
+                ${!file.isDir && previewType(file) ? `<button class="btn btn-preview" data-path="${path}">预览</button>` : ''}
+                ${file.isDir ? '' : `<button class="btn btn-download" data-path="${path}">下载</button>`}
+                ${file.isDir ? '' : `<button class="btn btn-details" data-path="${path}">详情</button>`}
+                <button class="btn btn-share" data-path="${path}">分享</button>
+                ${file.mount || file.readOnly ? '' : `<button class="btn btn-tags" data-path="${path}">标签</button>`}
+                ${file.mount || file.readOnly ? '' : `<button class="btn btn-danger" data-path="${path}">删除</button>`}
+            </div>
+        </div>
+    `;
+}
+
+// 文件名后显示的标签，元数据合并为一个标记，悬停时显示内容
+function tagBadges(file) {
+    let html = (file.tags || []).map(tag => ` <span class="badge-tag">${escapeHtml(tag)}</span>`).join('');
+    const metadata = Object.entries(file.metadata || {});
+    if (metadata.length > 0) {
+        const title = metadata.map(([key, value]) => `${key}=${value}`).join('\n');
+        html += ` <span class="badge-tag" title="${escapeHtml(title)}">${metadata.length} 项元数据</span>`;
+    }
+    return html;
+}
+
+// 更新视图切换按钮的文字
+function updateViewToggle() {
+    viewToggleBtn.textContent = viewMode === 'grid' ? '列表视图' : '网格视图';
+}
+
 // 获取文件图标
 function getFileIcon(extension) {
     const icons = {
@@ -358,7 +1191,7 @@ function getFileIcon(extension) {
         'doc': '📝', 'docx': '📝',
         'xls': '📊', 'xlsx': '📊',
         'ppt': '📽️', 'pptx': '📽️',
-        'jpg': '🖼️', 'jpeg': '🖼️', 'png': '🖼️', 'gif': '🖼️', 'svg': '🖼️',
+        'jpg': '🖼️', 'jpeg': '🖼️', 'png': '🖼️', 'gif': '🖼️', 'webp': '🖼️', 'svg': '🖼️',
         'mp4': '🎬', 'avi': '🎬', 'mov': '🎬',
         'mp3': '🎵', 'wav': '🎵',
         'zip': '📦', 'rar': '📦', '7z': '📦',
@@ -409,6 +1242,404 @@ function formatDate(dateString) {
     }
 }
 
+// 预览：文本与代码（语法高亮）、图片、PDF、音视频
+const PREVIEW_TEXT_LIMIT = 1024 * 1024; // 文本预览最多读取 1 MB
+const PREVIEW_TYPES = {
+    image: ['jpg', 'jpeg', 'png', 'gif', 'webp', 'svg', 'bmp', 'ico', 'avif'],
+    pdf: ['pdf'],
+    audio: ['mp3', 'wav', 'ogg', 'oga', 'm4a', 'aac', 'flac', 'opus'],
+    video: ['mp4', 'webm', 'ogv', 'mov', 'm4v'],
+    text: ['txt', 'md', 'log', 'csv', 'tsv', 'json', 'xml', 'yaml', 'yml', 'toml', 'ini', 'conf', 'cfg', 'env', 'properties',
+        'go', 'js', 'mjs', 'ts', 'tsx', 'jsx', 'py', 'rb', 'java', 'kt', 'swift', 'c', 'h', 'cpp', 'hpp', 'cc', 'cs', 'rs',
+        'php', 'pl', 'r', 'lua', 'dart', 'scala', 'sh', 'bash', 'zsh', 'bat', 'ps1', 'sql', 'html', 'htm', 'css', 'scss',
+        'less', 'vue', 'gradle', 'mod', 'sum']
+};
+
+// 代码高亮的语言分类：决定注释的写法
+const HIGHLIGHT_LANGUAGES = {};
+['go', 'js', 'mjs', 'ts', 'tsx', 'jsx', 'java', 'kt', 'swift', 'c', 'h', 'cpp', 'hpp', 'cc', 'cs', 'rs', 'php', 'dart',
+    'scala', 'css', 'scss', 'less', 'json', 'gradle'].forEach(ext => HIGHLIGHT_LANGUAGES[ext] = 'c');
+['py', 'rb', 'pl', 'r', 'sh', 'bash', 'zsh', 'ps1', 'yaml', 'yml', 'toml', 'conf', 'env', 'properties']
+    .forEach(ext => HIGHLIGHT_LANGUAGES[ext] = 'hash');
+['sql', 'lua'].forEach(ext => HIGHLIGHT_LANGUAGES[ext] = 'dash');
+['html', 'htm', 'xml', 'vue', 'svg'].forEach(ext => HIGHLIGHT_LANGUAGES[ext] = 'markup');
+
+const HIGHLIGHT_COMMENTS = {
+    c: '\\/\\/[^\\n]*|\\/\\*[\\s\\S]*?\\*\\/',
+    hash: '#[^\\n]*',
+    dash: '--[^\\n]*',
+    markup: '<!--[\\s\\S]*?-->'
+};
+
+const HIGHLIGHT_KEYWORDS = new Set((
+    'abstract and as async await break case catch chan class const continue def default defer del delete do elif else ' +
+    'end enum export extends false False final finally fn for from func function go goto if impl import in instanceof ' +
+    'interface is let loop map match mut new nil None not null or package pass private protected pub public raise ' +
+    'range return select self static struct super switch then this throw throws true True try type typeof use var ' +
+    'void where while with yield SELECT FROM WHERE INSERT INTO UPDATE DELETE CREATE TABLE AND OR NOT NULL JOIN ON ' +
+    'GROUP BY ORDER LIMIT VALUES SET select from where insert into update create table join on group by order limit values'
+).split(' '));
+
+let previewSeq = 0; // 每次打开预览递增，丢弃过期的加载结果
+let previewPath = null; // 当前预览的文件路径
+let maxEditSize = 1024 * 1024; // 可在线编辑的最大文件大小，以服务器设置为准
+let editor = null; // 正在编辑的文件: { path, etag, original }
+
+// 根据扩展名判断预览方式，扩展名未知时参考服务器识别的 MIME 类型，不支持时返回 null
+function previewType(file) {
+    const ext = (file.extension || '').toLowerCase();
+    for (const type in PREVIEW_TYPES) {
+        if (PREVIEW_TYPES[type].includes(ext)) return type;
+    }
+    const mimeType = file.mimeType || '';
+    if (mimeType === 'application/pdf') return 'pdf';
+    if (mimeType.startsWith('text/') || mimeType === 'application/json') return 'text';
+    for (const type of ['image', 'audio', 'video']) {
+        if (mimeType.startsWith(type + '/')) return type;
+    }
+    return null;
+}
+
+// 打开预览面板
+async function openPreview(path) {
+    const file = files.find(f => (f.path || f.name) === path);
+    if (!file) return;
+    const type = previewType(file);
+    const seq = ++previewSeq;
+    const url = `${API_BASE}/view/${encodeURIComponent(path)}`;
+    const previewBody = document.getElementById('previewBody');
+
+    editor = null;
+    previewPath = path;
+    document.getElementById('previewTitle').textContent = file.name;
+    document.getElementById('previewDownloadBtn').onclick = () => downloadFile(path);
+    updateEditorButtons(type === 'text' && canEdit(file));
+    previewModal.style.display = 'flex';
+
+    switch (type) {
+        case 'image':
+            previewBody.innerHTML = `<img class="preview-image" src="${url}" alt="">`;
+            return;
+        case 'pdf':
+            previewBody.innerHTML = `<iframe class="preview-frame" src="${url}"></iframe>`;
+            return;
+        case 'audio':
+            previewBody.innerHTML = `<audio class="preview-audio" controls autoplay src="${url}"></audio>`;
+            return;
+        case 'video':
+            previewBody.innerHTML = `<video class="preview-video" controls autoplay src="${url}"></video>`;
+            return;
+        case 'text':
+            break;
+        default:
+            previewBody.innerHTML = '<div class="empty-state">此类型的文件不支持预览，请下载后查看</div>';
+            return;
+    }
+
+    previewBody.innerHTML = '<div class="loading">加载中...</div>';
+    try {
+        // 大文件只读取开头部分
+        const truncated = file.size > PREVIEW_TEXT_LIMIT;
+        const headers = truncated ? { Range: `bytes=0-${PREVIEW_TEXT_LIMIT - 1}` } : {};
+        const response = await fetch(url, { headers });
+        if (!response.ok) {
+            throw new Error('HTTP ' + response.status);
+        }
+        const text = await response.text();
+        if (seq !== previewSeq) return;
+
+        previewBody.innerHTML = `<pre class="code-view"><code>${highlightCode(text, file.extension.toLowerCase())}</code></pre>` +
+            (truncated ? `<div class="preview-note">文件较大，仅显示前 ${formatFileSize(PREVIEW_TEXT_LIMIT)}，完整内容请下载查看</div>` : '');
+    } catch (error) {
+        if (seq !== previewSeq) return;
+        previewBody.innerHTML = '<div class="empty-state">加载失败</div>';
+        showToast('预览失败: ' + error.message, 'error');
+    }
+}
+
+// 关闭预览面板，清空内容以停止音视频播放
+function closePreview() {
+    if (editorDirty() && !confirm('修改尚未保存，确定要关闭吗？')) return;
+    editor = null;
+    previewPath = null;
+    previewSeq++;
+    previewModal.style.display = 'none';
+    document.getElementById('previewBody').innerHTML = '';
+}
+
+// 判断文件是否可以在线编辑：不超过大小限制且不在只读挂载点中
+function canEdit(file) {
+    return !file.isDir && !file.readOnly && file.size <= maxEditSize;
+}
+
+// 切换预览面板头部的按钮：预览时显示“编辑”，编辑时显示“保存”“取消”
+function updateEditorButtons(editable) {
+    const editing = editor !== null;
+    document.getElementById('previewEditBtn').style.display = !editing && editable ? '' : 'none';
+    document.getElementById('editorSaveBtn').style.display = editing ? '' : 'none';
+    document.getElementById('editorCancelBtn').style.display = editing ? '' : 'none';
+    document.getElementById('previewDownloadBtn').style.display = editing ? 'none' : '';
+}
+
+// 编辑内容是否有未保存的修改
+function editorDirty() {
+    const textarea = document.getElementById('editorTextarea');
+    return editor !== null && textarea !== null && textarea.value !== editor.original;
+}
+
+// 进入编辑模式：重新读取完整内容并记录 ETag，保存时据此判断文件是否已被他人修改
+async function openEditor() {
+    const path = previewPath;
+    if (!path) return;
+    const seq = ++previewSeq;
+    const previewBody = document.getElementById('previewBody');
+    previewBody.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await fetch(`${API_BASE}/view/${encodeURIComponent(path)}`, { cache: 'no-store' });
+        if (!response.ok) {
+            throw new Error('HTTP ' + response.status);
+        }
+        const text = await response.text();
+        if (seq !== previewSeq) return;
+
+        editor = { path, etag: response.headers.get('ETag'), original: text };
+        previewBody.innerHTML = '<textarea class="editor-textarea" id="editorTextarea" spellcheck="false"></textarea>';
+        const textarea = document.getElementById('editorTextarea');
+        textarea.value = text;
+        textarea.addEventListener('keydown', handleEditorKey);
+        textarea.focus();
+        updateEditorButtons(true);
+    } catch (error) {
+        if (seq !== previewSeq) return;
+        showToast('无法加载文件内容: ' + error.message, 'error');
+        openPreview(path);
+    }
+}
+
+// 编辑器快捷键：Ctrl+S 保存，Tab 插入制表符
+function handleEditorKey(e) {
+    if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 's') {
+        e.preventDefault();
+        saveEditor();
+    } else if (e.key === 'Tab' && !e.shiftKey) {
+        e.preventDefault();
+        const textarea = e.target;
+        const start = textarea.selectionStart;
+        textarea.value = textarea.value.slice(0, start) + '\t' + textarea.value.slice(textarea.selectionEnd);
+        textarea.selectionStart = textarea.selectionEnd = start + 1;
+    }
+}
+
+// 保存编辑内容，文件已被他人修改时服务器返回 409
+async function saveEditor() {
+    const textarea = document.getElementById('editorTextarea');
+    const saveBtn = document.getElementById('editorSaveBtn');
+    if (!editor || !textarea || saveBtn.disabled) return;
+
+    const current = editor;
+    const content = textarea.value;
+    saveBtn.disabled = true;
+    try {
+        const headers = { 'Content-Type': 'text/plain; charset=utf-8' };
+        if (current.etag) {
+            headers['If-Match'] = current.etag;
+        }
+        const response = await fetch(`${API_BASE}/content/${encodeURIComponent(current.path)}`, {
+            method: 'PUT',
+            headers,
+            body: content
+        });
+        const data = await response.json().catch(() => ({}));
+
+        if (response.status === 409) {
+            if (confirm('文件已被其他人修改。点击“确定”加载最新内容（将丢失你的修改），点击“取消”继续编辑。')) {
+                editor = null;
+                await openEditor();
+            }
+            return;
+        }
+        if (!response.ok || !data.success) {
+            throw new Error(data.message || 'HTTP ' + response.status);
+        }
+
+        current.etag = response.headers.get('ETag') || current.etag;
+        current.original = content;
+        showToast('已保存', 'success');
+        loadFiles(currentPath);
+    } catch (error) {
+        showToast('保存失败: ' + error.message, 'error');
+    } finally {
+        saveBtn.disabled = false;
+    }
+}
+
+// 退出编辑模式，回到预览
+function cancelEditor() {
+    if (editorDirty() && !confirm('修改尚未保存，确定要放弃吗？')) return;
+    const path = editor ? editor.path : previewPath;
+    editor = null;
+    openPreview(path);
+}
+
+let usagePath = ''; // 空间分析当前查看的目录
+let usageSeq = 0;   // 每次分析递增，丢弃过期的结果
+
+// 打开空间分析面板并统计目录
+async function openUsage(path) {
+    const seq = ++usageSeq;
+    const treemap = document.getElementById('usageTreemap');
+    usagePath = path;
+    usageModal.style.display = 'flex';
+    document.getElementById('usagePath').textContent = '/' + path;
+    document.getElementById('usageTotal').textContent = '';
+    document.getElementById('usageUpBtn').disabled = !path;
+    treemap.innerHTML = '<div class="loading">统计中...</div>';
+
+    try {
+        const params = new URLSearchParams({ path, depth: 2, top: 12 });
+        const response = await fetch(`${API_BASE}/usage?${params}`);
+        const data = await response.json();
+        if (seq !== usageSeq) return;
+        if (!data.success) {
+            throw new Error(data.message || 'HTTP ' + response.status);
+        }
+        renderTreemap(data.data);
+    } catch (error) {
+        if (seq !== usageSeq) return;
+        treemap.innerHTML = '<div class="empty-state">统计失败</div>';
+        showToast('空间分析失败: ' + error.message, 'error');
+    }
+}
+
+// 绘制矩形树图
+function renderTreemap(root) {
+    const treemap = document.getElementById('usageTreemap');
+    document.getElementById('usageTotal').textContent = `共 ${root.sizeText}，${root.files || 0} 个文件`;
+    const nodes = (root.children || []).filter(n => n.size > 0);
+    if (nodes.length === 0) {
+        treemap.innerHTML = '<div class="empty-state">目录为空</div>';
+        return;
+    }
+    treemap.innerHTML = treemapCells(nodes, 0, 0, treemap.clientWidth, treemap.clientHeight, 0, null);
+}
+
+// 生成一组区块的 HTML，目录区块足够大时在其中嵌套绘制子项
+function treemapCells(nodes, x, y, width, height, depth, hue) {
+    return squarify(nodes.filter(n => n.size > 0), x, y, width, height).map((rect, i) => {
+        const node = rect.node;
+        const nodeHue = hue === null ? (i * 47 + 200) % 360 : hue;
+        const color = node.isDir
+            ? `hsl(${nodeHue}, 55%, ${Math.max(55, 78 - depth * 10)}%)`
+            : `hsl(${nodeHue}, 35%, 88%)`;
+        const classes = ['usage-cell'];
+        if (node.isDir) classes.push('usage-dir');
+        if (node.other) classes.push('usage-other');
+        const title = `${node.path || node.name}\n${node.sizeText}${node.isDir ? `，${node.files || 0} 个文件` : ''}`;
+
+        let inner = '';
+        if (rect.w > 50 && rect.h > 18) {
+            inner += `<span class="usage-label">${escapeHtml(node.name)} · ${node.sizeText}</span>`;
+        }
+        if (node.children && rect.w > 40 && rect.h > 40) {
+            inner += treemapCells(node.children, 2, 18, rect.w - 6, rect.h - 22, depth + 1, nodeHue);
+        }
+        return `<div class="${classes.join(' ')}" style="left:${rect.x}px;top:${rect.y}px;width:${rect.w}px;height:${rect.h}px;background:${color}" ` +
+            `title="${escapeHtml(title)}"${node.isDir ? ` data-usage-path="${escapeHtml(node.path)}"` : ''}>${inner}</div>`;
+    }).join('');
+}
+
+// 按 squarified 算法把节点排布到矩形中，面积与大小成正比，尽量接近正方形
+function squarify(nodes, x, y, width, height) {
+    const total = nodes.reduce((sum, n) => sum + n.size, 0);
+    if (total <= 0 || width <= 0 || height <= 0) return [];
+    const scale = width * height / total;
+    let items = nodes.map(node => ({ node, area: node.size * scale }));
+    const rects = [];
+
+    // 一行中最差的长宽比
+    const worst = (row, side) => {
+        const sum = row.reduce((s, r) => s + r.area, 0);
+        const max = Math.max(...row.map(r => r.area));
+        const min = Math.min(...row.map(r => r.area));
+        return Math.max(side * side * max / (sum * sum), (sum * sum) / (side * side * min));
+    };
+
+    while (items.length > 0) {
+        const side = Math.min(width, height);
+        let count = 1;
+        while (count < items.length && worst(items.slice(0, count + 1), side) <= worst(items.slice(0, count), side)) {
+            count++;
+        }
+        const row = items.slice(0, count);
+        items = items.slice(count);
+        const rowArea = row.reduce((s, r) => s + r.area, 0);
+
+        if (width >= height) {
+            // 沿左侧排成一列
+            const colWidth = rowArea / height;
+            let cy = y;
+            row.forEach(r => {
+                const h = r.area / colWidth;
+                rects.push({ node: r.node, x, y: cy, w: colWidth, h });
+                cy += h;
+            });
+            x += colWidth;
+            width -= colWidth;
+        } else {
+            // 沿顶部排成一行
+            const rowHeight = rowArea / width;
+            let cx = x;
+            row.forEach(r => {
+                const w = r.area / rowHeight;
+                rects.push({ node: r.node, x: cx, y, w, h: rowHeight });
+                cx += w;
+            });
+            y += rowHeight;
+            height -= rowHeight;
+        }
+    }
+    return rects;
+}
+
+// 转义 HTML 特殊字符
+function escapeHtml(text) {
+    return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+// 简单的语法高亮：标出注释、字符串、数字和关键字，返回 HTML
+function highlightCode(text, extension) {
+    const lang = HIGHLIGHT_LANGUAGES[extension];
+    if (!lang) {
+        return escapeHtml(text);
+    }
+
+    const rules = lang === 'markup'
+        ? [['comment', HIGHLIGHT_COMMENTS.markup], ['string', '"[^"\\n]*"|\'[^\'\\n]*\''], ['keyword', '<\\/?[\\w:-]+|\\/?>']]
+        : [
+            ['comment', HIGHLIGHT_COMMENTS[lang]],
+            ['string', '"(?:[^"\\\\\\n]|\\\\.)*"|\'(?:[^\'\\\\\\n]|\\\\.)*\'|`[^`]*`'],
+            ['number', '\\b\\d+(?:\\.\\d+)?\\b'],
+            ['word', '\\b[A-Za-z_]\\w*\\b']
+        ];
+    const pattern = new RegExp(rules.map(rule => `(${rule[1]})`).join('|'), 'g');
+
+    let html = '';
+    let last = 0;
+    let match;
+    while ((match = pattern.exec(text)) !== null) {
+        html += escapeHtml(text.slice(last, match.index));
+        const index = match.slice(1).findIndex(group => group !== undefined);
+        let type = rules[index][0];
+        if (type === 'word') {
+            type = HIGHLIGHT_KEYWORDS.has(match[0]) ? 'keyword' : null;
+        }
+        html += type ? `<span class="hl-${type}">${escapeHtml(match[0])}</span>` : escapeHtml(match[0]);
+        last = pattern.lastIndex;
+    }
+    return html + escapeHtml(text.slice(last));
+}
+
 // 下载文件
 function downloadFile(path) {
     const pathParts = path.split(/[/\\]/);
@@ -551,40 +1782,338 @@ async function deleteFile(path) {
     }
 }
 
-// 排序文件
-function sortFiles() {
-    files.sort((a, b) => {
-        // 目录始终排在前面
-        if (a.isDir && !b.isDir) return -1;
-        if (!a.isDir && b.isDir) return 1;
-        
-        let compareA, compareB;
-        
-        switch (sortField) {
-            case 'name':
-                compareA = a.name.toLowerCase();
-                compareB = b.name.toLowerCase();
-                break;
-            case 'size':
-                compareA = a.size;
-                compareB = b.size;
-                break;
-            case 'time':
-                compareA = new Date(a.modTime).getTime();
-                compareB = new Date(b.modTime).getTime();
-                break;
-            default:
-                return 0;
+// 上传方式的显示名称
+const UPLOAD_VIA = {
+    web: '网页/API', instant: '秒传', edit: '在线编辑', drop: '上传链接', webdav: 'WebDAV', sftp: 'SFTP', s3: 'S3'
+};
+
+// 打开文件详情面板，显示上传者和上传历史
+async function openDetails(path) {
+    const info = document.getElementById('detailsInfo');
+    const uploads = document.getElementById('detailsUploads');
+    info.innerHTML = '<div class="loading">加载中...</div>';
+    uploads.innerHTML = '';
+    detailsModal.style.display = 'flex';
+
+    try {
+        const response = await fetch(`${API_BASE}/details/${encodeURIComponent(path)}`);
+        const data = await response.json();
+        if (!data.success) {
+            throw new Error(data.message || 'HTTP ' + response.status);
         }
-        
-        if (compareA < compareB) {
-            return sortOrder === 'asc' ? -1 : 1;
+        const d = data.data;
+        const rows = [
+            ['路径', d.path],
+            ['大小', formatFileSize(d.size)],
+            ['类型', d.mimeType],
+            ['修改时间', new Date(d.modTime).toLocaleString('zh-CN')],
+            ['SHA-256', d.sha256 || '未知'],
+            ['上传者', d.uploader || '未知（在服务器外放入）'],
+            ['上传时间', d.created ? new Date(d.created).toLocaleString('zh-CN') : '未知']
+        ];
+        info.innerHTML = rows.map(([label, value]) => `
+            <div class="form-row">
+                <label>${label}</label>
+                <span class="details-value">${escapeHtml(String(value))}</span>
+            </div>
+        `).join('');
+
+        if (d.uploads.length === 0) {
+            uploads.innerHTML = '<tr><td colspan="5" class="empty-state">暂无上传记录</td></tr>';
+            return;
         }
-        if (compareA > compareB) {
-            return sortOrder === 'asc' ? 1 : -1;
+        uploads.innerHTML = d.uploads.map(up => `
+            <tr>
+                <td title="${escapeHtml(up.userAgent || '')}">${escapeHtml(up.uploader)}${up.ip && up.ip !== up.uploader ? ` (${escapeHtml(up.ip)})` : ''}</td>
+                <td>${UPLOAD_VIA[up.via] || escapeHtml(up.via)}</td>
+                <td>${new Date(up.time).toLocaleString('zh-CN')}</td>
+                <td>${formatFileSize(up.size)}</td>
+                <td>${up.duration} / ${up.speedText}</td>
+            </tr>
+        `).join('');
+    } catch (error) {
+        info.innerHTML = '<div class="empty-state">加载失败</div>';
+        showToast('加载文件详情失败: ' + error.message, 'error');
+    }
+}
+
+// 打开标签编辑对话框，填入当前的标签和元数据
+function openTagsModal(path) {
+    const file = files.find(f => (f.path || f.name) === path) || {};
+    tagsPath = path;
+    document.getElementById('tagsTargetName').textContent = path;
+    document.getElementById('tagsInput').value = (file.tags || []).join(', ');
+    document.getElementById('tagsMetadata').value = Object.entries(file.metadata || {})
+        .map(([key, value]) => `${key}=${value}`).join('\n');
+    document.getElementById('tagsSubmit').disabled = false;
+    tagsModal.style.display = 'flex';
+}
+
+// 保存标签和元数据，两者都为空时清除
+async function saveTags() {
+    const metadata = {};
+    for (const line of document.getElementById('tagsMetadata').value.split('\n')) {
+        if (!line.trim()) continue;
+        const index = line.indexOf('=');
+        if (index <= 0) {
+            showToast(`元数据格式应为 键=值: ${line}`, 'error');
+            return;
         }
-        return 0;
-    });
+        metadata[line.slice(0, index).trim()] = line.slice(index + 1).trim();
+    }
+    const body = {
+        tags: document.getElementById('tagsInput').value.split(',').map(tag => tag.trim()).filter(tag => tag),
+        metadata
+    };
+
+    const submit = document.getElementById('tagsSubmit');
+    submit.disabled = true;
+    try {
+        const response = await fetch(`${API_BASE}/tags/${encodeURIComponent(tagsPath)}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        const data = await response.json();
+        if (!data.success) {
+            showToast(data.message || '保存标签失败', 'error');
+            return;
+        }
+        tagsModal.style.display = 'none';
+        showToast('标签已保存', 'success');
+        loadFiles(currentPath);
+    } catch (error) {
+        showToast('保存标签失败: ' + error.message, 'error');
+    } finally {
+        submit.disabled = false;
+    }
+}
+
+// 打开创建分享对话框
+function openShareModal(path) {
+    sharePath = path;
+    shareForm.reset();
+    document.getElementById('shareTargetName').textContent = path;
+    document.getElementById('shareResult').style.display = 'none';
+    document.getElementById('shareSubmit').disabled = false;
+    shareModal.style.display = 'flex';
+}
+
+// 创建分享链接
+async function createShare() {
+    const body = {
+        path: sharePath,
+        expiresIn: parseInt(document.getElementById('shareExpires').value, 10) || 0,
+        maxDownloads: parseInt(document.getElementById('shareMaxDownloads').value, 10) || 0,
+        password: document.getElementById('sharePassword').value
+    };
+
+    try {
+        const response = await fetch(`${API_BASE}/shares`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        const data = await response.json();
+        if (!data.success) {
+            showToast(data.message || '创建分享失败', 'error');
+            return;
+        }
+
+        const shareUrl = document.getElementById('shareUrl');
+        shareUrl.value = window.location.origin + data.data.url;
+        document.getElementById('shareResult').style.display = 'flex';
+        document.getElementById('shareSubmit').disabled = true;
+        shareUrl.select();
+        showToast('分享链接已创建', 'success');
+    } catch (error) {
+        showToast('创建分享失败: ' + error.message, 'error');
+    }
+}
+
+// 打开分享管理对话框
+async function openSharesModal() {
+    sharesModal.style.display = 'flex';
+    loadDrops();
+    sharesContainer.innerHTML = '<tr><td colspan="5" class="loading">加载中...</td></tr>';
+
+    try {
+        const response = await fetch(`${API_BASE}/shares`);
+        const data = await response.json();
+        if (!data.success) {
+            showToast(data.message || '加载分享列表失败', 'error');
+            return;
+        }
+
+        const shares = data.data || [];
+        if (shares.length === 0) {
+            sharesContainer.innerHTML = '<tr><td colspan="5" class="empty-state">暂无分享链接</td></tr>';
+            return;
+        }
+
+        sharesContainer.innerHTML = shares.map(share => `
+            <tr>
+                <td title="${window.location.origin + share.url}">${share.isDir ? '📁' : '📄'} ${share.path}${share.hasPassword ? ' 🔒' : ''}</td>
+                <td>${share.expiresAt ? formatDate(share.expiresAt) : '永久'}</td>
+                <td>${share.downloads}${share.maxDownloads ? ' / ' + share.maxDownloads : ''}</td>
+                <td class="${share.status ? 'status-invalid' : ''}">${share.status || '有效'}</td>
+                <td>
+                    <div class="file-actions">
+                        <button class="btn btn-secondary btn-copy-share" data-url="${window.location.origin + share.url}">复制</button>
+                        <button class="btn btn-danger btn-revoke-share" data-token="${share.token}">撤销</button>
+                    </div>
+                </td>
+            </tr>
+        `).join('');
+
+        sharesContainer.querySelectorAll('.btn-copy-share').forEach(btn => {
+            btn.addEventListener('click', () => {
+                navigator.clipboard.writeText(btn.dataset.url)
+                    .then(() => showToast('链接已复制', 'success'))
+                    .catch(() => showToast(btn.dataset.url, 'info'));
+            });
+        });
+        sharesContainer.querySelectorAll('.btn-revoke-share').forEach(btn => {
+            btn.addEventListener('click', () => revokeShare(btn.dataset.token));
+        });
+    } catch (error) {
+        showToast('加载分享列表失败: ' + error.message, 'error');
+    }
+}
+
+// 打开创建上传链接对话框
+function openDropModal(path) {
+    dropPath = path;
+    dropForm.reset();
+    document.getElementById('dropTargetName').textContent = path;
+    document.getElementById('dropResult').style.display = 'none';
+    document.getElementById('dropSubmit').disabled = false;
+    dropModal.style.display = 'flex';
+}
+
+// 创建上传链接
+async function createDrop() {
+    const mb = 1024 * 1024;
+    const body = {
+        path: dropPath,
+        expiresIn: parseInt(document.getElementById('dropExpires').value, 10) || 0,
+        maxFileSize: (parseInt(document.getElementById('dropMaxFileSize').value, 10) || 0) * mb,
+        maxTotalSize: (parseInt(document.getElementById('dropMaxTotalSize').value, 10) || 0) * mb
+    };
+
+    try {
+        const response = await fetch(`${API_BASE}/drops`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        const data = await response.json();
+        if (!data.success) {
+            showToast(data.message || '创建上传链接失败', 'error');
+            return;
+        }
+
+        const dropUrl = document.getElementById('dropUrl');
+        dropUrl.value = window.location.origin + data.data.url;
+        document.getElementById('dropResult').style.display = 'flex';
+        document.getElementById('dropSubmit').disabled = true;
+        dropUrl.select();
+        showToast('上传链接已创建', 'success');
+    } catch (error) {
+        showToast('创建上传链接失败: ' + error.message, 'error');
+    }
+}
+
+// 加载上传链接列表
+async function loadDrops() {
+    dropsContainer.innerHTML = '<tr><td colspan="5" class="loading">加载中...</td></tr>';
+
+    try {
+        const response = await fetch(`${API_BASE}/drops`);
+        const data = await response.json();
+        if (!data.success) {
+            showToast(data.message || '加载上传链接失败', 'error');
+            return;
+        }
+
+        const drops = data.data || [];
+        if (drops.length === 0) {
+            dropsContainer.innerHTML = '<tr><td colspan="5" class="empty-state">暂无上传链接</td></tr>';
+            return;
+        }
+
+        dropsContainer.innerHTML = drops.map(drop => `
+            <tr>
+                <td title="${window.location.origin + drop.url}">📥 ${drop.path || '/'}</td>
+                <td>${drop.expiresAt ? formatDate(drop.expiresAt) : '永久'}</td>
+                <td>${drop.files} 个 / ${formatFileSize(drop.uploaded)}${drop.maxTotalSize ? ' / ' + formatFileSize(drop.maxTotalSize) : ''}</td>
+                <td class="${drop.status ? 'status-invalid' : ''}">${drop.status || '有效'}</td>
+                <td>
+                    <div class="file-actions">
+                        <button class="btn btn-secondary btn-copy-share" data-url="${window.location.origin + drop.url}">复制</button>
+                        <button class="btn btn-danger btn-revoke-drop" data-token="${drop.token}">撤销</button>
+                    </div>
+                </td>
+            </tr>
+        `).join('');
+
+        dropsContainer.querySelectorAll('.btn-copy-share').forEach(btn => {
+            btn.addEventListener('click', () => {
+                navigator.clipboard.writeText(btn.dataset.url)
+                    .then(() => showToast('链接已复制', 'success'))
+                    .catch(() => showToast(btn.dataset.url, 'info'));
+            });
+        });
+        dropsContainer.querySelectorAll('.btn-revoke-drop').forEach(btn => {
+            btn.addEventListener('click', () => revokeDrop(btn.dataset.token));
+        });
+    } catch (error) {
+        showToast('加载上传链接失败: ' + error.message, 'error');
+    }
+}
+
+// 撤销上传链接
+async function revokeDrop(token) {
+    if (!confirm('确定要撤销该上传链接吗？')) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`${API_BASE}/drops/${encodeURIComponent(token)}`, {
+            method: 'DELETE'
+        });
+        const data = await response.json();
+        if (data.success) {
+            showToast(data.message || '已撤销', 'success');
+            loadDrops();
+        } else {
+            showToast(data.message || '撤销失败', 'error');
+        }
+    } catch (error) {
+        showToast('撤销失败: ' + error.message, 'error');
+    }
+}
+
+// 撤销分享链接
+async function revokeShare(token) {
+    if (!confirm('确定要撤销该分享链接吗？')) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`${API_BASE}/shares/${encodeURIComponent(token)}`, {
+            method: 'DELETE'
+        });
+        const data = await response.json();
+        if (data.success) {
+            showToast(data.message || '已撤销', 'success');
+            openSharesModal();
+        } else {
+            showToast(data.message || '撤销失败', 'error');
+        }
+    } catch (error) {
+        showToast('撤销失败: ' + error.message, 'error');
+    }
 }
 
 // 更新排序图标
@@ -0,0 +1,197 @@
+// 从全局配置获取根路径，如果没有则默认为 "/"
+const ROOT_PATH = (typeof window !== 'undefined' && window.ROOT_PATH) || '/';
+const SHARE_TOKEN = window.location.pathname.split('/').filter(p => p).pop();
+const SHARE_BASE = (ROOT_PATH === '/' ? '' : ROOT_PATH) + '/s/' + SHARE_TOKEN;
+let sharePassword = '';
+let currentPath = '';
+
+// DOM 元素
+const shareSubtitle = document.getElementById('shareSubtitle');
+const passwordSection = document.getElementById('passwordSection');
+const passwordForm = document.getElementById('passwordForm');
+const passwordInput = document.getElementById('passwordInput');
+const shareSection = document.getElementById('shareSection');
+const filesContainer = document.getElementById('filesContainer');
+const breadcrumb = document.getElementById('breadcrumb');
+const toast = document.getElementById('toast');
+
+// 初始化
+document.addEventListener('DOMContentLoaded', () => {
+    passwordForm.addEventListener('submit', (e) => {
+        e.preventDefault();
+        sharePassword = passwordInput.value;
+        loadShare('');
+    });
+    loadShare('');
+});
+
+// 构建请求头
+function shareHeaders() {
+    return sharePassword ? { 'X-Share-Password': sharePassword } : {};
+}
+
+// 加载分享内容
+async function loadShare(path) {
+    try {
+        const url = `${SHARE_BASE}/info` + (path ? `?path=${encodeURIComponent(path)}` : '');
+        const response = await fetch(url, { headers: shareHeaders() });
+        const data = await response.json();
+
+        if (response.status === 401) {
+            passwordSection.style.display = 'block';
+            shareSection.style.display = 'none';
+            shareSubtitle.textContent = '请输入访问密码';
+            if (sharePassword) {
+                showToast(data.message || '密码错误', 'error');
+            }
+            return;
+        }
+        if (!data.success) {
+            shareSubtitle.textContent = data.message || '分享链接不可用';
+            passwordSection.style.display = 'none';
+            shareSection.style.display = 'none';
+            return;
+        }
+
+        currentPath = path;
+        passwordSection.style.display = 'none';
+        shareSection.style.display = 'block';
+        renderShare(data.data);
+    } catch (error) {
+        shareSubtitle.textContent = '加载失败';
+        showToast('加载失败: ' + error.message, 'error');
+    }
+}
+
+// 渲染分享内容
+function renderShare(info) {
+    let subtitle = info.name;
+    if (info.expiresAt) {
+        subtitle += ` · 有效期至 ${new Date(info.expiresAt).toLocaleString('zh-CN')}`;
+    }
+    if (info.maxDownloads) {
+        subtitle += ` · 剩余下载次数 ${Math.max(info.maxDownloads - info.downloads, 0)}`;
+    }
+    shareSubtitle.textContent = subtitle;
+
+    updateBreadcrumb(info);
+
+    const items = info.isDir ? (info.files || []) : [{
+        name: info.name,
+        size: info.size,
+        modTime: info.createdAt,
+        isDir: false,
+        extension: info.name.split('.').pop(),
+        path: ''
+    }];
+
+    if (items.length === 0) {
+        filesContainer.innerHTML = '<tr><td colspan="5" class="empty-state">目录为空</td></tr>';
+        return;
+    }
+
+    items.sort((a, b) => {
+        if (a.isDir !== b.isDir) return a.isDir ? -1 : 1;
+        return a.name.localeCompare(b.name);
+    });
+
+    filesContainer.innerHTML = items.map(file => `
+        <tr class="${file.isDir ? 'file-dir' : ''}" data-path="${escapeHtml(file.path)}">
+            <td>${file.isDir ? '📁' : '📄'}</td>
+            <td title="${escapeHtml(file.name)}" class="${file.isDir ? 'dir-name' : ''}">${escapeHtml(file.name)}${file.isDir ? ' /' : ''}</td>
+            <td>${file.isDir ? '-' : formatFileSize(file.size)}</td>
+            <td>${formatDate(file.modTime)}</td>
+            <td>
+                <div class="file-actions">
+                    ${file.isDir ? '' : `<button class="btn btn-download" data-path="${escapeHtml(file.path)}" data-name="${escapeHtml(file.name)}">下载</button>`}
+                </div>
+            </td>
+        </tr>
+    `).join('');
+
+    filesContainer.querySelectorAll('.file-dir').forEach(row => {
+        row.addEventListener('click', () => loadShare(row.dataset.path));
+    });
+    filesContainer.querySelectorAll('.btn-download').forEach(btn => {
+        btn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            downloadSharedFile(btn.dataset.path, btn.dataset.name);
+        });
+    });
+}
+
+// 更新面包屑导航
+function updateBreadcrumb(info) {
+    if (!info.isDir) {
+        breadcrumb.style.display = 'none';
+        return;
+    }
+    breadcrumb.style.display = 'block';
+
+    const parts = currentPath.split('/').filter(p => p);
+    let html = '<span class="breadcrumb-item" data-path="">分享根目录</span>';
+    let current = '';
+    parts.forEach(part => {
+        current = current ? current + '/' + part : part;
+        html += ` <span class="breadcrumb-separator">/</span> <span class="breadcrumb-item" data-path="${escapeHtml(current)}">${escapeHtml(part)}</span>`;
+    });
+    breadcrumb.innerHTML = html;
+    breadcrumb.querySelectorAll('.breadcrumb-item').forEach(item => {
+        item.addEventListener('click', () => loadShare(item.dataset.path || ''));
+    });
+}
+
+// 下载文件：由浏览器处理下载，大文件不占用页面内存，中断后可以续传。
+// 有密码时先用密码换取短期下载凭证，再打开带凭证的下载地址
+async function downloadSharedFile(path, filename) {
+    const query = path ? `?path=${encodeURIComponent(path)}` : '';
+    let url = `${SHARE_BASE}/download` + query;
+    if (sharePassword) {
+        try {
+            const response = await fetch(`${SHARE_BASE}/ticket` + query, { method: 'POST', headers: shareHeaders() });
+            const data = await response.json();
+            if (!data.success) {
+                throw new Error(data.message || 'HTTP ' + response.status);
+            }
+            url = data.data.url;
+        } catch (error) {
+            showToast('下载失败: ' + error.message, 'error');
+            return;
+        }
+    }
+    showToast(`开始下载 ${filename}...`, 'info');
+    window.location.href = url;
+    setTimeout(() => loadShare(currentPath), 1000);
+}
+
+// 转义 HTML
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text == null ? '' : String(text);
+    return div.innerHTML.replace(/"/g, '&quot;');
+}
+
+// 格式化文件大小
+function formatFileSize(bytes) {
+    if (!bytes) return '0 B';
+    const k = 1024;
+    const sizes = ['B', 'KB', 'MB', 'GB'];
+    const i = Math.floor(Math.log(bytes) / Math.log(k));
+    return Math.round(bytes / Math.pow(k, i) * 100) / 100 + ' ' + sizes[i];
+}
+
+// 格式化日期
+function formatDate(dateString) {
+    const date = new Date(dateString);
+    return date.toLocaleDateString('zh-CN') + ' ' + date.toLocaleTimeString('zh-CN', { hour: '2-digit', minute: '2-digit' });
+}
+
+// 显示提示消息
+function showToast(message, type = 'info') {
+    toast.textContent = message;
+    toast.className = `toast ${type} show`;
+
+    setTimeout(() => {
+        toast.classList.remove('show');
+    }, 3000);
+}